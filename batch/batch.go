@@ -0,0 +1,129 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/michimani/aws-lambda-api-go/events"
+)
+
+// ErrAllRecordsFailed is returned when all records in the batch failed
+// and WithFailBatchOnAllFailures is set.
+var ErrAllRecordsFailed = errors.New("all records in the batch failed")
+
+// Response is the partial batch response, that makes Lambda retry only the failed records.
+// The event source mapping must be configured with ReportBatchItemFailures.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/services-sqs-errorhandling.html#services-sqs-batchfailurereporting
+type Response struct {
+	BatchItemFailures []ItemFailure `json:"batchItemFailures"`
+}
+
+// ItemFailure is the failed record in Response.
+// ItemIdentifier is the message ID for SQS, and the sequence number for Kinesis and DynamoDB Streams.
+type ItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// Option is the option of the batch processing.
+type Option func(*options)
+
+type options struct {
+	failBatchOnAllFailures bool
+	stopOnFirstFailure     bool
+	onFailure              func(ctx context.Context, itemIdentifier string, err error)
+}
+
+// WithFailBatchOnAllFailures makes the handler return the error that wraps ErrAllRecordsFailed
+// and the errors of the records instead of the response, when all records in the batch failed,
+// so that the whole batch is retried as a failed invocation.
+func WithFailBatchOnAllFailures() Option {
+	return func(o *options) {
+		o.failBatchOnAllFailures = true
+	}
+}
+
+// WithStopOnFirstFailure makes the handler stop processing at the first failed record,
+// and report it and the following records as failed.
+// It is required to keep the order of the records, e.g. SQS FIFO queues.
+func WithStopOnFirstFailure() Option {
+	return func(o *options) {
+		o.stopOnFirstFailure = true
+	}
+}
+
+// WithFailureHandler sets the function that is called with the error of each failed record, e.g. for logging.
+func WithFailureHandler(fn func(ctx context.Context, itemIdentifier string, err error)) Option {
+	return func(o *options) {
+		o.onFailure = fn
+	}
+}
+
+// SQS returns the handler of SQSEvent, that calls handler for each message
+// and returns the message IDs of the failed messages.
+func SQS(handler func(context.Context, events.SQSMessage) error, opts ...Option) func(context.Context, events.SQSEvent) (*Response, error) {
+	return func(ctx context.Context, e events.SQSEvent) (*Response, error) {
+		return Process(ctx, e.Records, func(m events.SQSMessage) string { return m.MessageID }, handler, opts...)
+	}
+}
+
+// Kinesis returns the handler of KinesisEvent, that calls handler for each record
+// and returns the sequence numbers of the failed records.
+func Kinesis(handler func(context.Context, events.KinesisEventRecord) error, opts ...Option) func(context.Context, events.KinesisEvent) (*Response, error) {
+	return func(ctx context.Context, e events.KinesisEvent) (*Response, error) {
+		return Process(ctx, e.Records, func(r events.KinesisEventRecord) string { return r.Kinesis.SequenceNumber }, handler, opts...)
+	}
+}
+
+// DynamoDB returns the handler of DynamoDBEvent, that calls handler for each record
+// and returns the sequence numbers of the failed records.
+func DynamoDB(handler func(context.Context, events.DynamoDBEventRecord) error, opts ...Option) func(context.Context, events.DynamoDBEvent) (*Response, error) {
+	return func(ctx context.Context, e events.DynamoDBEvent) (*Response, error) {
+		return Process(ctx, e.Records, func(r events.DynamoDBEventRecord) string { return r.Change.SequenceNumber }, handler, opts...)
+	}
+}
+
+// Process calls handler for each record in order, and returns the response
+// that has the identifiers (given by id) of the records for which handler returned an error.
+// When ctx is done, the remaining records are not processed and reported as failed.
+func Process[T any](ctx context.Context, records []T, id func(T) string, handler func(context.Context, T) error, opts ...Option) (*Response, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	res := &Response{BatchItemFailures: []ItemFailure{}}
+	errs := []error{}
+	fail := func(itemIdentifier string, err error) {
+		res.BatchItemFailures = append(res.BatchItemFailures, ItemFailure{ItemIdentifier: itemIdentifier})
+		errs = append(errs, fmt.Errorf("%s: %w", itemIdentifier, err))
+		if o.onFailure != nil {
+			o.onFailure(ctx, itemIdentifier, err)
+		}
+	}
+
+	var stopErr error
+	for _, r := range records {
+		if stopErr == nil {
+			stopErr = ctx.Err()
+		}
+		if stopErr != nil {
+			fail(id(r), stopErr)
+			continue
+		}
+
+		if err := handler(ctx, r); err != nil {
+			fail(id(r), err)
+			if o.stopOnFirstFailure {
+				stopErr = fmt.Errorf("preceding record failed: %w", err)
+			}
+		}
+	}
+
+	if o.failBatchOnAllFailures && len(records) > 0 && len(res.BatchItemFailures) == len(records) {
+		return nil, fmt.Errorf("%w: %w", ErrAllRecordsFailed, errors.Join(errs...))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,156 @@
+package batch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/batch"
+	"github.com/michimani/aws-lambda-api-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTest = errors.New("test-error")
+
+func sqsEvent(bodies ...string) events.SQSEvent {
+	e := events.SQSEvent{}
+	for i, b := range bodies {
+		e.Records = append(e.Records, events.SQSMessage{MessageID: "msg-" + string(rune('1'+i)), Body: b})
+	}
+	return e
+}
+
+func Test_SQS(t *testing.T) {
+	handler := func(ctx context.Context, m events.SQSMessage) error {
+		if m.Body == "ng" {
+			return errTest
+		}
+		return nil
+	}
+
+	cases := []struct {
+		name    string
+		event   events.SQSEvent
+		opts    []batch.Option
+		ctx     func() context.Context
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:   "ok: all records succeeded",
+			event:  sqsEvent("ok", "ok"),
+			expect: `{"batchItemFailures":[]}`,
+		},
+		{
+			name:   "ok: some records failed",
+			event:  sqsEvent("ok", "ng", "ok", "ng"),
+			expect: `{"batchItemFailures":[{"itemIdentifier":"msg-2"},{"itemIdentifier":"msg-4"}]}`,
+		},
+		{
+			name:   "ok: all records failed",
+			event:  sqsEvent("ng", "ng"),
+			expect: `{"batchItemFailures":[{"itemIdentifier":"msg-1"},{"itemIdentifier":"msg-2"}]}`,
+		},
+		{
+			name:   "ok: empty batch",
+			event:  sqsEvent(),
+			opts:   []batch.Option{batch.WithFailBatchOnAllFailures()},
+			expect: `{"batchItemFailures":[]}`,
+		},
+		{
+			name:   "ok: stop on first failure",
+			event:  sqsEvent("ok", "ng", "ok"),
+			opts:   []batch.Option{batch.WithStopOnFirstFailure()},
+			expect: `{"batchItemFailures":[{"itemIdentifier":"msg-2"},{"itemIdentifier":"msg-3"}]}`,
+		},
+		{
+			name:   "ok: fail batch on all failures, some records failed",
+			event:  sqsEvent("ok", "ng"),
+			opts:   []batch.Option{batch.WithFailBatchOnAllFailures()},
+			expect: `{"batchItemFailures":[{"itemIdentifier":"msg-2"}]}`,
+		},
+		{
+			name:  "ok: context is done",
+			event: sqsEvent("ok", "ok"),
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			expect: `{"batchItemFailures":[{"itemIdentifier":"msg-1"},{"itemIdentifier":"msg-2"}]}`,
+		},
+		{
+			name:    "ng: fail batch on all failures",
+			event:   sqsEvent("ng", "ng"),
+			opts:    []batch.Option{batch.WithFailBatchOnAllFailures()},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			ctx := context.Background()
+			if c.ctx != nil {
+				ctx = c.ctx()
+			}
+
+			res, err := batch.SQS(handler, c.opts...)(ctx, c.event)
+			if c.wantErr {
+				asst.ErrorIs(err, batch.ErrAllRecordsFailed)
+				asst.ErrorIs(err, errTest)
+				asst.Nil(res)
+				return
+			}
+
+			asst.NoError(err)
+			b, err := json.Marshal(res)
+			asst.NoError(err)
+			asst.JSONEq(c.expect, string(b))
+		})
+	}
+}
+
+func Test_Kinesis(t *testing.T) {
+	asst := assert.New(t)
+
+	e := events.KinesisEvent{Records: []events.KinesisEventRecord{
+		{Kinesis: events.KinesisRecord{SequenceNumber: "1", Data: []byte("ok")}},
+		{Kinesis: events.KinesisRecord{SequenceNumber: "2", Data: []byte("ng")}},
+	}}
+
+	res, err := batch.Kinesis(func(ctx context.Context, r events.KinesisEventRecord) error {
+		if string(r.Kinesis.Data) == "ng" {
+			return errTest
+		}
+		return nil
+	})(context.Background(), e)
+
+	asst.NoError(err)
+	asst.Equal(&batch.Response{BatchItemFailures: []batch.ItemFailure{{ItemIdentifier: "2"}}}, res)
+}
+
+func Test_DynamoDB(t *testing.T) {
+	asst := assert.New(t)
+
+	e := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		{EventName: "INSERT", Change: events.DynamoDBStreamRecord{SequenceNumber: "100"}},
+		{EventName: "REMOVE", Change: events.DynamoDBStreamRecord{SequenceNumber: "200"}},
+	}}
+
+	failed := map[string]error{}
+	res, err := batch.DynamoDB(func(ctx context.Context, r events.DynamoDBEventRecord) error {
+		if r.EventName == "INSERT" {
+			return errTest
+		}
+		return nil
+	}, batch.WithFailureHandler(func(ctx context.Context, itemIdentifier string, err error) {
+		failed[itemIdentifier] = err
+	}))(context.Background(), e)
+
+	asst.NoError(err)
+	asst.Equal(&batch.Response{BatchItemFailures: []batch.ItemFailure{{ItemIdentifier: "100"}}}, res)
+	asst.Equal(map[string]error{"100": errTest}, failed)
+}
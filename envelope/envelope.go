@@ -0,0 +1,193 @@
+package envelope
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/michimani/aws-lambda-api-go/internal/jmespath"
+	"github.com/michimani/aws-lambda-api-go/internal/jsonutil"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+)
+
+// The envelopes of the common event sources.
+var (
+	// APIGatewayREST extracts the JSON body of API Gateway REST API (v1) events.
+	APIGatewayREST = MustNew("powertools_json(body)")
+
+	// APIGatewayHTTP extracts the JSON body of API Gateway HTTP API (v2) events.
+	APIGatewayHTTP = MustNew("powertools_json(body)")
+
+	// FunctionURL extracts the JSON body of Lambda function URL events.
+	FunctionURL = MustNew("powertools_json(body)")
+
+	// SQS extracts the JSON bodies of the messages in SQS events as an array.
+	SQS = MustNew("Records[*].powertools_json(body)")
+
+	// SNS extracts the JSON message of the first record in SNS events.
+	SNS = MustNew("Records[0].Sns.Message | powertools_json(@)")
+
+	// SNSInSQS extracts the JSON messages of SNS notifications delivered to SQS as an array.
+	SNSInSQS = MustNew("Records[*].powertools_json(body).powertools_json(Message)")
+
+	// EventBridge extracts the detail of EventBridge events.
+	EventBridge = MustNew("detail")
+
+	// Kinesis extracts the base64-encoded JSON data of the records in Kinesis events as an array.
+	Kinesis = MustNew("Records[*].kinesis.powertools_json(powertools_base64(data))")
+
+	// CloudWatchLogs extracts the log events of CloudWatch Logs subscription events as an array.
+	CloudWatchLogs = MustNew("awslogs.powertools_base64_gzip(data) | powertools_json(@).logEvents[*]")
+)
+
+// functions are the custom functions of the envelope expressions, that are compatible with Powertools for AWS Lambda.
+//
+// document: https://docs.powertools.aws.dev/lambda/python/latest/utilities/jmespath_functions/
+var functions = map[string]jmespath.Function{
+	// powertools_json decodes the JSON string.
+	"powertools_json": {Arity: 1, Call: func(args []any) (any, error) {
+		s, err := stringArg(args)
+		if err != nil {
+			return nil, err
+		}
+		return jsonutil.Decode([]byte(s))
+	}},
+	// powertools_base64 decodes the base64-encoded string.
+	"powertools_base64": {Arity: 1, Call: func(args []any) (any, error) {
+		s, err := stringArg(args)
+		if err != nil {
+			return nil, err
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}},
+	// powertools_base64_gzip decodes the base64-encoded string and decompresses it with gzip.
+	"powertools_base64_gzip": {Arity: 1, Call: func(args []any) (any, error) {
+		s, err := stringArg(args)
+		if err != nil {
+			return nil, err
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		out, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return string(out), nil
+	}},
+}
+
+func stringArg(args []any) (string, error) {
+	s, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("%w: argument must be a string, but got %T", jmespath.ErrInvalidType, args[0])
+	}
+	return s, nil
+}
+
+// Envelope extracts the payload from the event with the JMESPath expression.
+// In addition to the built-in functions of JMESPath, the expression can call
+// powertools_json, powertools_base64 and powertools_base64_gzip.
+//
+// document: https://jmespath.org/specification.html
+type Envelope struct {
+	expr     string
+	compiled *jmespath.Expression
+}
+
+// New compiles the expression of the envelope.
+func New(expr string) (*Envelope, error) {
+	compiled, err := jmespath.Compile(expr, functions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid envelope expression %q: %w", expr, err)
+	}
+
+	return &Envelope{expr: expr, compiled: compiled}, nil
+}
+
+// MustNew is like New but panics if the expression is invalid.
+func MustNew(expr string) *Envelope {
+	e, err := New(expr)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// String returns the expression of the envelope.
+func (e *Envelope) String() string {
+	return e.expr
+}
+
+// Extract returns the payload extracted from the event (JSON).
+// The numbers in the payload are json.Number or float64.
+func (e *Envelope) Extract(event []byte) (any, error) {
+	if e == nil {
+		return nil, errors.New("Receiver is nil.")
+	}
+
+	v, err := jsonutil.Decode(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.compiled.Search(v)
+}
+
+// Unmarshal extracts the payload from the event (JSON) and unmarshals it into target.
+// If the expression results in null, target is not changed.
+func (e *Envelope) Unmarshal(event []byte, target any) error {
+	payload, err := e.Extract(event)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, target)
+}
+
+// UnmarshalEventResponse is like NextOutput.UnmarshalEventResponse,
+// but unmarshals the payload extracted from the event.
+func (e *Envelope) UnmarshalEventResponse(o *runtime.NextOutput, target any) error {
+	if o == nil {
+		return fmt.Errorf("NextOutput is nil")
+	}
+
+	return e.Unmarshal(o.RawEventResponse, target)
+}
+
+// Handler converts the typed handler into runtime.Handler, that is called with the payload extracted from the event.
+// The errors of extracting the payload are returned as *runtime.FunctionError with the error type Runtime.UnmarshalError.
+func Handler[TIn, TOut any](e *Envelope, handler func(context.Context, TIn) (TOut, error)) runtime.Handler {
+	h := runtime.NewHandler(handler)
+	return func(ctx context.Context, event []byte) ([]byte, error) {
+		payload, err := e.Extract(event)
+		if err == nil {
+			event, err = json.Marshal(payload)
+		}
+		if err != nil {
+			return nil, &runtime.FunctionError{ErrorMessage: err.Error(), ErrorType: runtime.ErrorTypeUnmarshalError}
+		}
+
+		return h(ctx, event)
+	}
+}
@@ -0,0 +1,255 @@
+package envelope_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/envelope"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipBase64(t *testing.T, s string) string {
+	buf := bytes.Buffer{}
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func Test_envelopes(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString
+
+	cases := []struct {
+		name     string
+		envelope *envelope.Envelope
+		event    func(t *testing.T) string
+		expect   string
+	}{
+		{
+			name:     "ok: API Gateway REST API",
+			envelope: envelope.APIGatewayREST,
+			event:    func(t *testing.T) string { return `{"resource":"/","body":"{\"name\":\"alice\"}"}` },
+			expect:   `{"name":"alice"}`,
+		},
+		{
+			name:     "ok: API Gateway HTTP API",
+			envelope: envelope.APIGatewayHTTP,
+			event:    func(t *testing.T) string { return `{"version":"2.0","body":"[1,2]"}` },
+			expect:   `[1,2]`,
+		},
+		{
+			name:     "ok: function URL",
+			envelope: envelope.FunctionURL,
+			event:    func(t *testing.T) string { return `{"version":"2.0","body":"{\"id\":12345678901234567890}"}` },
+			expect:   `{"id":12345678901234567890}`,
+		},
+		{
+			name:     "ok: SQS",
+			envelope: envelope.SQS,
+			event: func(t *testing.T) string {
+				b, err := os.ReadFile(filepath.Join("..", "events", "testdata", "sqs.json"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return string(b)
+			},
+			expect: `[{"name":"alice"}]`,
+		},
+		{
+			name:     "ok: SNS",
+			envelope: envelope.SNS,
+			event:    func(t *testing.T) string { return `{"Records":[{"Sns":{"Message":"{\"name\":\"alice\"}"}}]}` },
+			expect:   `{"name":"alice"}`,
+		},
+		{
+			name:     "ok: SNS in SQS",
+			envelope: envelope.SNSInSQS,
+			event: func(t *testing.T) string {
+				return `{"Records":[{"body":"{\"Type\":\"Notification\",\"Message\":\"{\\\"name\\\":\\\"alice\\\"}\"}"},{"body":"{\"Message\":\"{\\\"name\\\":\\\"bob\\\"}\"}"}]}`
+			},
+			expect: `[{"name":"alice"},{"name":"bob"}]`,
+		},
+		{
+			name:     "ok: EventBridge",
+			envelope: envelope.EventBridge,
+			event:    func(t *testing.T) string { return `{"detail-type":"test","detail":{"name":"alice"}}` },
+			expect:   `{"name":"alice"}`,
+		},
+		{
+			name:     "ok: Kinesis",
+			envelope: envelope.Kinesis,
+			event: func(t *testing.T) string {
+				return `{"Records":[{"kinesis":{"data":"` + b64([]byte(`{"name":"alice"}`)) + `"}},{"kinesis":{"data":"` + b64([]byte(`{"name":"bob"}`)) + `"}}]}`
+			},
+			expect: `[{"name":"alice"},{"name":"bob"}]`,
+		},
+		{
+			name:     "ok: CloudWatch Logs",
+			envelope: envelope.CloudWatchLogs,
+			event: func(t *testing.T) string {
+				data := gzipBase64(t, `{"messageType":"DATA_MESSAGE","logEvents":[{"id":"1","timestamp":1,"message":"a"},{"id":"2","timestamp":2,"message":"b"}]}`)
+				return `{"awslogs":{"data":"` + data + `"}}`
+			},
+			expect: `[{"id":"1","timestamp":1,"message":"a"},{"id":"2","timestamp":2,"message":"b"}]`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := c.envelope.Extract([]byte(c.event(tt)))
+			asst.NoError(err)
+
+			b, err := json.Marshal(out)
+			asst.NoError(err)
+			asst.JSONEq(c.expect, string(b))
+		})
+	}
+}
+
+func Test_New(t *testing.T) {
+	cases := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "ok", expr: "Records[*].powertools_json(body)"},
+		{name: "ng: syntax error", expr: "Records[*", wantErr: true},
+		{name: "ng: unknown function", expr: "powertools_yaml(body)", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			e, err := envelope.New(c.expr)
+			if c.wantErr {
+				asst.Error(err)
+				asst.Nil(e)
+				asst.Panics(func() { envelope.MustNew(c.expr) })
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expr, e.String())
+		})
+	}
+}
+
+func Test_Envelope_Extract_error(t *testing.T) {
+	cases := []struct {
+		name     string
+		envelope *envelope.Envelope
+		event    string
+	}{
+		{name: "ng: event is not JSON", envelope: envelope.EventBridge, event: `{`},
+		{name: "ng: body is not JSON", envelope: envelope.APIGatewayREST, event: `{"body":"Hello"}`},
+		{name: "ng: body is null", envelope: envelope.APIGatewayREST, event: `{"body":null}`},
+		{name: "ng: data is not base64", envelope: envelope.Kinesis, event: `{"Records":[{"kinesis":{"data":"!"}}]}`},
+		{name: "ng: data is not gzip", envelope: envelope.CloudWatchLogs, event: `{"awslogs":{"data":"aGVsbG8="}}`},
+		{name: "ng: nil", envelope: nil, event: `{}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := c.envelope.Extract([]byte(c.event))
+			asst.Error(err)
+			asst.Nil(out)
+		})
+	}
+}
+
+type order struct {
+	ID    string `json:"id"`
+	Items int    `json:"items"`
+}
+
+func Test_Envelope_UnmarshalEventResponse(t *testing.T) {
+	cases := []struct {
+		name    string
+		o       *runtime.NextOutput
+		expect  []order
+		wantErr bool
+	}{
+		{
+			name:   "ok",
+			o:      &runtime.NextOutput{RawEventResponse: []byte(`{"Records":[{"body":"{\"id\":\"o-1\",\"items\":2}"},{"body":"{\"id\":\"o-2\",\"items\":1}"}]}`)},
+			expect: []order{{ID: "o-1", Items: 2}, {ID: "o-2", Items: 1}},
+		},
+		{
+			name:    "ng: type mismatch",
+			o:       &runtime.NextOutput{RawEventResponse: []byte(`{"Records":[{"body":"{\"id\":1}"}]}`)},
+			wantErr: true,
+		},
+		{
+			name:    "ng: nil",
+			o:       nil,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			orders := []order{}
+			err := envelope.SQS.UnmarshalEventResponse(c.o, &orders)
+			if c.wantErr {
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, orders)
+		})
+	}
+}
+
+func Test_Handler(t *testing.T) {
+	h := envelope.Handler(envelope.EventBridge, func(ctx context.Context, o order) (string, error) {
+		return o.ID, nil
+	})
+
+	cases := []struct {
+		name          string
+		event         string
+		expect        string
+		wantErrorType string
+	}{
+		{name: "ok", event: `{"detail":{"id":"o-1","items":1}}`, expect: `"o-1"`},
+		{name: "ng: event is not JSON", event: `{`, wantErrorType: runtime.ErrorTypeUnmarshalError},
+		{name: "ng: type mismatch", event: `{"detail":{"id":1}}`, wantErrorType: runtime.ErrorTypeUnmarshalError},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := h(context.Background(), []byte(c.event))
+			if c.wantErrorType != "" {
+				var fe *runtime.FunctionError
+				asst.ErrorAs(err, &fe)
+				asst.Equal(c.wantErrorType, fe.ErrorType)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, string(out))
+		})
+	}
+}
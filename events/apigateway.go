@@ -0,0 +1,176 @@
+package events
+
+import "encoding/base64"
+
+// APIGatewayProxyRequest is the event of API Gateway REST API with the Lambda proxy integration
+// (payload format version 1.0).
+//
+// document: https://docs.aws.amazon.com/apigateway/latest/developerguide/set-up-lambda-proxy-integrations.html
+type APIGatewayProxyRequest struct {
+	Resource                        string                        `json:"resource"`
+	Path                            string                        `json:"path"`
+	HTTPMethod                      string                        `json:"httpMethod"`
+	Headers                         map[string]string             `json:"headers"`
+	MultiValueHeaders               map[string][]string           `json:"multiValueHeaders"`
+	QueryStringParameters           map[string]string             `json:"queryStringParameters"`
+	MultiValueQueryStringParameters map[string][]string           `json:"multiValueQueryStringParameters"`
+	PathParameters                  map[string]string             `json:"pathParameters"`
+	StageVariables                  map[string]string             `json:"stageVariables"`
+	RequestContext                  APIGatewayProxyRequestContext `json:"requestContext"`
+	Body                            string                        `json:"body"`
+	IsBase64Encoded                 bool                          `json:"isBase64Encoded"`
+}
+
+// APIGatewayProxyRequestContext is the request context of APIGatewayProxyRequest.
+type APIGatewayProxyRequestContext struct {
+	AccountID        string                    `json:"accountId"`
+	ResourceID       string                    `json:"resourceId"`
+	Stage            string                    `json:"stage"`
+	RequestID        string                    `json:"requestId"`
+	Identity         APIGatewayRequestIdentity `json:"identity"`
+	ResourcePath     string                    `json:"resourcePath"`
+	Authorizer       map[string]any            `json:"authorizer,omitempty"`
+	HTTPMethod       string                    `json:"httpMethod"`
+	APIID            string                    `json:"apiId"`
+	DomainName       string                    `json:"domainName,omitempty"`
+	Protocol         string                    `json:"protocol,omitempty"`
+	RequestTimeEpoch int64                     `json:"requestTimeEpoch"`
+}
+
+// APIGatewayRequestIdentity is the identity of the caller of APIGatewayProxyRequest.
+type APIGatewayRequestIdentity struct {
+	AccountID string `json:"accountId,omitempty"`
+	APIKey    string `json:"apiKey,omitempty"`
+	Caller    string `json:"caller,omitempty"`
+	SourceIP  string `json:"sourceIp"`
+	User      string `json:"user,omitempty"`
+	UserAgent string `json:"userAgent"`
+	UserArn   string `json:"userArn,omitempty"`
+}
+
+// DecodedBody returns Body decoded from base64 if IsBase64Encoded is true.
+func (r *APIGatewayProxyRequest) DecodedBody() ([]byte, error) {
+	return decodeBody(r.Body, r.IsBase64Encoded)
+}
+
+// APIGatewayProxyResponse is the response for APIGatewayProxyRequest.
+type APIGatewayProxyResponse struct {
+	StatusCode        int                 `json:"statusCode"`
+	Headers           map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
+
+// APIGatewayV2HTTPRequest is the event of API Gateway HTTP API (payload format version 2.0).
+//
+// document: https://docs.aws.amazon.com/apigateway/latest/developerguide/http-api-develop-integrations-lambda.html
+type APIGatewayV2HTTPRequest struct {
+	Version               string                         `json:"version"`
+	RouteKey              string                         `json:"routeKey"`
+	RawPath               string                         `json:"rawPath"`
+	RawQueryString        string                         `json:"rawQueryString"`
+	Cookies               []string                       `json:"cookies,omitempty"`
+	Headers               map[string]string              `json:"headers"`
+	QueryStringParameters map[string]string              `json:"queryStringParameters,omitempty"`
+	PathParameters        map[string]string              `json:"pathParameters,omitempty"`
+	RequestContext        APIGatewayV2HTTPRequestContext `json:"requestContext"`
+	StageVariables        map[string]string              `json:"stageVariables,omitempty"`
+	Body                  string                         `json:"body,omitempty"`
+	IsBase64Encoded       bool                           `json:"isBase64Encoded"`
+}
+
+// APIGatewayV2HTTPRequestContext is the request context of APIGatewayV2HTTPRequest.
+type APIGatewayV2HTTPRequestContext struct {
+	AccountID    string                                   `json:"accountId"`
+	APIID        string                                   `json:"apiId"`
+	Authorizer   map[string]any                           `json:"authorizer,omitempty"`
+	DomainName   string                                   `json:"domainName"`
+	DomainPrefix string                                   `json:"domainPrefix"`
+	HTTP         APIGatewayV2HTTPRequestContextHTTPDetail `json:"http"`
+	RequestID    string                                   `json:"requestId"`
+	RouteKey     string                                   `json:"routeKey"`
+	Stage        string                                   `json:"stage"`
+	Time         string                                   `json:"time"`
+	TimeEpoch    int64                                    `json:"timeEpoch"`
+}
+
+// APIGatewayV2HTTPRequestContextHTTPDetail is the HTTP request in APIGatewayV2HTTPRequestContext.
+type APIGatewayV2HTTPRequestContextHTTPDetail struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Protocol  string `json:"protocol"`
+	SourceIP  string `json:"sourceIp"`
+	UserAgent string `json:"userAgent"`
+}
+
+// DecodedBody returns Body decoded from base64 if IsBase64Encoded is true.
+func (r *APIGatewayV2HTTPRequest) DecodedBody() ([]byte, error) {
+	return decodeBody(r.Body, r.IsBase64Encoded)
+}
+
+// APIGatewayV2HTTPResponse is the response for APIGatewayV2HTTPRequest.
+type APIGatewayV2HTTPResponse struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Cookies         []string          `json:"cookies,omitempty"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// LambdaFunctionURLRequest is the event of Lambda function URL,
+// that has the same format as API Gateway HTTP API.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/urls-invocation.html
+type LambdaFunctionURLRequest = APIGatewayV2HTTPRequest
+
+// LambdaFunctionURLResponse is the response for LambdaFunctionURLRequest.
+type LambdaFunctionURLResponse = APIGatewayV2HTTPResponse
+
+// ALBTargetGroupRequest is the event of Application Load Balancer.
+// The query string parameters are not decoded.
+//
+// document: https://docs.aws.amazon.com/elasticloadbalancing/latest/application/lambda-functions.html
+type ALBTargetGroupRequest struct {
+	HTTPMethod                      string                       `json:"httpMethod"`
+	Path                            string                       `json:"path"`
+	QueryStringParameters           map[string]string            `json:"queryStringParameters,omitempty"`
+	MultiValueQueryStringParameters map[string][]string          `json:"multiValueQueryStringParameters,omitempty"`
+	Headers                         map[string]string            `json:"headers,omitempty"`
+	MultiValueHeaders               map[string][]string          `json:"multiValueHeaders,omitempty"`
+	RequestContext                  ALBTargetGroupRequestContext `json:"requestContext"`
+	IsBase64Encoded                 bool                         `json:"isBase64Encoded"`
+	Body                            string                       `json:"body"`
+}
+
+// ALBTargetGroupRequestContext is the request context of ALBTargetGroupRequest.
+type ALBTargetGroupRequestContext struct {
+	ELB ELBContext `json:"elb"`
+}
+
+// ELBContext is the target group that invokes the function.
+type ELBContext struct {
+	TargetGroupArn string `json:"targetGroupArn"`
+}
+
+// DecodedBody returns Body decoded from base64 if IsBase64Encoded is true.
+func (r *ALBTargetGroupRequest) DecodedBody() ([]byte, error) {
+	return decodeBody(r.Body, r.IsBase64Encoded)
+}
+
+// ALBTargetGroupResponse is the response for ALBTargetGroupRequest.
+type ALBTargetGroupResponse struct {
+	StatusCode        int                 `json:"statusCode"`
+	StatusDescription string              `json:"statusDescription"`
+	Headers           map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
+
+func decodeBody(body string, isBase64Encoded bool) ([]byte, error) {
+	if !isBase64Encoded {
+		return []byte(body), nil
+	}
+	return base64.StdEncoding.DecodeString(body)
+}
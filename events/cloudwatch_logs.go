@@ -0,0 +1,59 @@
+package events
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// CloudWatchLogsEvent is the event of Amazon CloudWatch Logs subscription filter.
+// The log data is gzip compressed and base64 encoded, and can be decoded by Parse.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/services-cloudwatchlogs.html
+type CloudWatchLogsEvent struct {
+	AWSLogs CloudWatchLogsRawData `json:"awslogs"`
+}
+
+// CloudWatchLogsRawData is the compressed log data of CloudWatchLogsEvent.
+type CloudWatchLogsRawData struct {
+	Data string `json:"data"`
+}
+
+// CloudWatchLogsData is the log data decoded from CloudWatchLogsRawData.
+type CloudWatchLogsData struct {
+	Owner               string                   `json:"owner"`
+	LogGroup            string                   `json:"logGroup"`
+	LogStream           string                   `json:"logStream"`
+	SubscriptionFilters []string                 `json:"subscriptionFilters"`
+	MessageType         string                   `json:"messageType"`
+	LogEvents           []CloudWatchLogsLogEvent `json:"logEvents"`
+}
+
+// CloudWatchLogsLogEvent is a log event in CloudWatchLogsData.
+type CloudWatchLogsLogEvent struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// Parse decodes the base64 encoded and gzip compressed log data.
+func (r CloudWatchLogsRawData) Parse() (*CloudWatchLogsData, error) {
+	b, err := base64.StdEncoding.DecodeString(r.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	d := CloudWatchLogsData{}
+	if err := json.NewDecoder(zr).Decode(&d); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
@@ -0,0 +1,91 @@
+package events
+
+// CognitoEventUserPoolsHeader is the common parameters of the events of Amazon Cognito user pool triggers.
+//
+// document: https://docs.aws.amazon.com/cognito/latest/developerguide/cognito-user-pools-working-with-lambda-triggers.html
+type CognitoEventUserPoolsHeader struct {
+	Version       string                             `json:"version"`
+	TriggerSource string                             `json:"triggerSource"`
+	Region        string                             `json:"region"`
+	UserPoolID    string                             `json:"userPoolId"`
+	CallerContext CognitoEventUserPoolsCallerContext `json:"callerContext"`
+	UserName      string                             `json:"userName"`
+}
+
+// CognitoEventUserPoolsCallerContext is the caller of the event.
+type CognitoEventUserPoolsCallerContext struct {
+	AWSSDKVersion string `json:"awsSdkVersion"`
+	ClientID      string `json:"clientId"`
+}
+
+// CognitoEventUserPoolsPreSignup is the event of pre sign-up trigger.
+// The function returns the event with Response.
+type CognitoEventUserPoolsPreSignup struct {
+	CognitoEventUserPoolsHeader
+	Request  CognitoEventUserPoolsPreSignupRequest  `json:"request"`
+	Response CognitoEventUserPoolsPreSignupResponse `json:"response"`
+}
+
+// CognitoEventUserPoolsPreSignupRequest is the request of CognitoEventUserPoolsPreSignup.
+type CognitoEventUserPoolsPreSignupRequest struct {
+	UserAttributes map[string]string `json:"userAttributes"`
+	ValidationData map[string]string `json:"validationData,omitempty"`
+	ClientMetadata map[string]string `json:"clientMetadata,omitempty"`
+}
+
+// CognitoEventUserPoolsPreSignupResponse is the response of CognitoEventUserPoolsPreSignup.
+type CognitoEventUserPoolsPreSignupResponse struct {
+	AutoConfirmUser bool `json:"autoConfirmUser"`
+	AutoVerifyEmail bool `json:"autoVerifyEmail"`
+	AutoVerifyPhone bool `json:"autoVerifyPhone"`
+}
+
+// CognitoEventUserPoolsPostConfirmation is the event of post confirmation trigger.
+type CognitoEventUserPoolsPostConfirmation struct {
+	CognitoEventUserPoolsHeader
+	Request  CognitoEventUserPoolsPostConfirmationRequest  `json:"request"`
+	Response CognitoEventUserPoolsPostConfirmationResponse `json:"response"`
+}
+
+// CognitoEventUserPoolsPostConfirmationRequest is the request of CognitoEventUserPoolsPostConfirmation.
+type CognitoEventUserPoolsPostConfirmationRequest struct {
+	UserAttributes map[string]string `json:"userAttributes"`
+	ClientMetadata map[string]string `json:"clientMetadata,omitempty"`
+}
+
+// CognitoEventUserPoolsPostConfirmationResponse is the response of CognitoEventUserPoolsPostConfirmation,
+// that has no parameters.
+type CognitoEventUserPoolsPostConfirmationResponse struct{}
+
+// CognitoEventUserPoolsPreTokenGen is the event of pre token generation trigger.
+type CognitoEventUserPoolsPreTokenGen struct {
+	CognitoEventUserPoolsHeader
+	Request  CognitoEventUserPoolsPreTokenGenRequest  `json:"request"`
+	Response CognitoEventUserPoolsPreTokenGenResponse `json:"response"`
+}
+
+// CognitoEventUserPoolsPreTokenGenRequest is the request of CognitoEventUserPoolsPreTokenGen.
+type CognitoEventUserPoolsPreTokenGenRequest struct {
+	UserAttributes     map[string]string  `json:"userAttributes"`
+	GroupConfiguration GroupConfiguration `json:"groupConfiguration"`
+	ClientMetadata     map[string]string  `json:"clientMetadata,omitempty"`
+}
+
+// CognitoEventUserPoolsPreTokenGenResponse is the response of CognitoEventUserPoolsPreTokenGen.
+type CognitoEventUserPoolsPreTokenGenResponse struct {
+	ClaimsOverrideDetails *ClaimsOverrideDetails `json:"claimsOverrideDetails"`
+}
+
+// GroupConfiguration is the groups and the IAM roles of the user.
+type GroupConfiguration struct {
+	GroupsToOverride   []string `json:"groupsToOverride"`
+	IAMRolesToOverride []string `json:"iamRolesToOverride"`
+	PreferredRole      *string  `json:"preferredRole"`
+}
+
+// ClaimsOverrideDetails is the claims to add, override or suppress in the token.
+type ClaimsOverrideDetails struct {
+	ClaimsToAddOrOverride map[string]string   `json:"claimsToAddOrOverride,omitempty"`
+	ClaimsToSuppress      []string            `json:"claimsToSuppress,omitempty"`
+	GroupOverrideDetails  *GroupConfiguration `json:"groupOverrideDetails,omitempty"`
+}
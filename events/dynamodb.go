@@ -0,0 +1,93 @@
+package events
+
+import "encoding/json"
+
+// DynamoDBEvent is the event of Amazon DynamoDB Streams.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/with-ddb.html
+type DynamoDBEvent struct {
+	Records []DynamoDBEventRecord `json:"Records"`
+}
+
+// DynamoDBEventRecord is a record in DynamoDBEvent.
+type DynamoDBEventRecord struct {
+	EventID        string                `json:"eventID"`
+	EventName      string                `json:"eventName"`
+	EventVersion   string                `json:"eventVersion"`
+	EventSource    string                `json:"eventSource"`
+	AWSRegion      string                `json:"awsRegion"`
+	Change         DynamoDBStreamRecord  `json:"dynamodb"`
+	EventSourceArn string                `json:"eventSourceARN"`
+	UserIdentity   *DynamoDBUserIdentity `json:"userIdentity,omitempty"`
+}
+
+// DynamoDBStreamRecord is the change of the item in DynamoDBEventRecord.
+type DynamoDBStreamRecord struct {
+	// The approximate date and time when the record was created, in seconds since the Unix epoch.
+	ApproximateCreationDateTime float64                   `json:"ApproximateCreationDateTime,omitempty"`
+	Keys                        map[string]AttributeValue `json:"Keys,omitempty"`
+	NewImage                    map[string]AttributeValue `json:"NewImage,omitempty"`
+	OldImage                    map[string]AttributeValue `json:"OldImage,omitempty"`
+	SequenceNumber              string                    `json:"SequenceNumber"`
+	SizeBytes                   int64                     `json:"SizeBytes"`
+	StreamViewType              string                    `json:"StreamViewType"`
+}
+
+// DynamoDBUserIdentity is the identity that made the change, e.g. TTL.
+type DynamoDBUserIdentity struct {
+	Type        string `json:"type"`
+	PrincipalID string `json:"principalId"`
+}
+
+// AttributeValue is the attribute value of DynamoDB. Exactly one of the fields is set.
+// The binary values are decoded from base64. An empty list or map, e.g. {"L":[]},
+// is decoded as the non-nil empty value, so that it is marshaled as it is.
+type AttributeValue struct {
+	B    []byte                    `json:"B,omitempty"`
+	BOOL *bool                     `json:"BOOL,omitempty"`
+	BS   [][]byte                  `json:"BS,omitempty"`
+	L    []AttributeValue          `json:"L,omitempty"`
+	M    map[string]AttributeValue `json:"M,omitempty"`
+	N    *string                   `json:"N,omitempty"`
+	NS   []string                  `json:"NS,omitempty"`
+	NULL *bool                     `json:"NULL,omitempty"`
+	S    *string                   `json:"S,omitempty"`
+	SS   []string                  `json:"SS,omitempty"`
+}
+
+// MarshalJSON marshals the fields that are not nil, even if they are empty.
+func (v AttributeValue) MarshalJSON() ([]byte, error) {
+	m := map[string]any{}
+	if v.B != nil {
+		m["B"] = v.B
+	}
+	if v.BOOL != nil {
+		m["BOOL"] = *v.BOOL
+	}
+	if v.BS != nil {
+		m["BS"] = v.BS
+	}
+	if v.L != nil {
+		m["L"] = v.L
+	}
+	if v.M != nil {
+		m["M"] = v.M
+	}
+	if v.N != nil {
+		m["N"] = *v.N
+	}
+	if v.NS != nil {
+		m["NS"] = v.NS
+	}
+	if v.NULL != nil {
+		m["NULL"] = *v.NULL
+	}
+	if v.S != nil {
+		m["S"] = *v.S
+	}
+	if v.SS != nil {
+		m["SS"] = v.SS
+	}
+
+	return json.Marshal(m)
+}
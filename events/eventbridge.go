@@ -0,0 +1,27 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventBridgeEvent is the event of Amazon EventBridge.
+// Detail is kept as raw JSON, since its structure depends on Source and DetailType.
+//
+// document: https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-events-structure.html
+type EventBridgeEvent struct {
+	Version    string          `json:"version"`
+	ID         string          `json:"id"`
+	DetailType string          `json:"detail-type"`
+	Source     string          `json:"source"`
+	Account    string          `json:"account"`
+	Time       time.Time       `json:"time"`
+	Region     string          `json:"region"`
+	Resources  []string        `json:"resources"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+// UnmarshalDetail converts Detail into the structure received as an argument.
+func (e *EventBridgeEvent) UnmarshalDetail(target any) error {
+	return json.Unmarshal(e.Detail, target)
+}
@@ -0,0 +1,230 @@
+package events_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_roundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		fixture string
+		target  func() any
+	}{
+		{name: "ok: SQS", fixture: "sqs.json", target: func() any { return &events.SQSEvent{} }},
+		{name: "ok: SNS", fixture: "sns.json", target: func() any { return &events.SNSEvent{} }},
+		{name: "ok: S3", fixture: "s3.json", target: func() any { return &events.S3Event{} }},
+		{name: "ok: DynamoDB Streams", fixture: "dynamodb.json", target: func() any { return &events.DynamoDBEvent{} }},
+		{name: "ok: Kinesis", fixture: "kinesis.json", target: func() any { return &events.KinesisEvent{} }},
+		{name: "ok: EventBridge", fixture: "eventbridge.json", target: func() any { return &events.EventBridgeEvent{} }},
+		{name: "ok: CloudWatch Logs", fixture: "cloudwatch_logs.json", target: func() any { return &events.CloudWatchLogsEvent{} }},
+		{name: "ok: API Gateway REST API", fixture: "apigateway_v1.json", target: func() any { return &events.APIGatewayProxyRequest{} }},
+		{name: "ok: API Gateway HTTP API", fixture: "apigateway_v2.json", target: func() any { return &events.APIGatewayV2HTTPRequest{} }},
+		{name: "ok: function URL", fixture: "function_url.json", target: func() any { return &events.LambdaFunctionURLRequest{} }},
+		{name: "ok: ALB", fixture: "alb.json", target: func() any { return &events.ALBTargetGroupRequest{} }},
+		{name: "ok: Cognito pre sign-up", fixture: "cognito_pre_signup.json", target: func() any { return &events.CognitoEventUserPoolsPreSignup{} }},
+		{name: "ok: Cognito post confirmation", fixture: "cognito_post_confirmation.json", target: func() any { return &events.CognitoEventUserPoolsPostConfirmation{} }},
+		{name: "ok: Cognito pre token generation", fixture: "cognito_pre_token_gen.json", target: func() any { return &events.CognitoEventUserPoolsPreTokenGen{} }},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			b, err := os.ReadFile(filepath.Join("testdata", c.fixture))
+			asst.NoError(err)
+
+			// all fields of the fixture must be in the model
+			target := c.target()
+			dec := json.NewDecoder(bytes.NewReader(b))
+			dec.DisallowUnknownFields()
+			asst.NoError(dec.Decode(target))
+
+			out, err := json.Marshal(target)
+			asst.NoError(err)
+			asst.JSONEq(string(b), string(out))
+		})
+	}
+}
+
+func readFixture(t *testing.T, name string, target any) {
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, target); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_decodedFields(t *testing.T) {
+	asst := assert.New(t)
+
+	sqs := events.SQSEvent{}
+	readFixture(t, "sqs.json", &sqs)
+	asst.Equal([]byte("hello"), sqs.Records[0].MessageAttributes["Attr2"].BinaryValue)
+
+	kinesis := events.KinesisEvent{}
+	readFixture(t, "kinesis.json", &kinesis)
+	asst.Equal("Hello, this is a test.", string(kinesis.Records[0].Kinesis.Data))
+
+	ddb := events.DynamoDBEvent{}
+	readFixture(t, "dynamodb.json", &ddb)
+	asst.Equal([]byte("hello"), ddb.Records[0].Change.NewImage["Data"].B)
+	asst.Equal("New item!", *ddb.Records[0].Change.NewImage["Message"].S)
+	asst.True(*ddb.Records[0].Change.NewImage["Nested"].M["List"].L[1].NULL)
+	asst.Equal([]events.AttributeValue{}, ddb.Records[0].Change.NewImage["EmptyList"].L)
+	asst.Equal(map[string]events.AttributeValue{}, ddb.Records[0].Change.NewImage["EmptyMap"].M)
+}
+
+func Test_AttributeValue_MarshalJSON(t *testing.T) {
+	s := "a"
+	n := "1"
+	b := true
+
+	cases := []struct {
+		name   string
+		v      events.AttributeValue
+		expect string
+	}{
+		{name: "ok: S", v: events.AttributeValue{S: &s}, expect: `{"S":"a"}`},
+		{name: "ok: N", v: events.AttributeValue{N: &n}, expect: `{"N":"1"}`},
+		{name: "ok: BOOL", v: events.AttributeValue{BOOL: &b}, expect: `{"BOOL":true}`},
+		{name: "ok: NULL", v: events.AttributeValue{NULL: &b}, expect: `{"NULL":true}`},
+		{name: "ok: B", v: events.AttributeValue{B: []byte("hello")}, expect: `{"B":"aGVsbG8="}`},
+		{name: "ok: BS", v: events.AttributeValue{BS: [][]byte{[]byte("hello")}}, expect: `{"BS":["aGVsbG8="]}`},
+		{name: "ok: NS", v: events.AttributeValue{NS: []string{"1"}}, expect: `{"NS":["1"]}`},
+		{name: "ok: SS", v: events.AttributeValue{SS: []string{"a"}}, expect: `{"SS":["a"]}`},
+		{name: "ok: nested", v: events.AttributeValue{M: map[string]events.AttributeValue{"k": {L: []events.AttributeValue{{S: &s}}}}}, expect: `{"M":{"k":{"L":[{"S":"a"}]}}}`},
+		{name: "ok: empty list", v: events.AttributeValue{L: []events.AttributeValue{}}, expect: `{"L":[]}`},
+		{name: "ok: empty map", v: events.AttributeValue{M: map[string]events.AttributeValue{}}, expect: `{"M":{}}`},
+		{name: "ok: empty binary", v: events.AttributeValue{B: []byte{}}, expect: `{"B":""}`},
+		{name: "ok: no fields", v: events.AttributeValue{}, expect: `{}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := json.Marshal(c.v)
+			asst.NoError(err)
+			asst.JSONEq(c.expect, string(out))
+		})
+	}
+}
+
+func Test_S3Object_DecodedKey(t *testing.T) {
+	cases := []struct {
+		name    string
+		key     string
+		expect  string
+		wantErr bool
+	}{
+		{name: "ok", key: "photos/my+photo.jpg", expect: "photos/my photo.jpg"},
+		{name: "ok: escaped", key: "a%2Bb%3D.txt", expect: "a+b=.txt"},
+		{name: "ng: invalid escape", key: "%zz", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			key, err := events.S3Object{Key: c.key}.DecodedKey()
+			if c.wantErr {
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, key)
+		})
+	}
+}
+
+func Test_EventBridgeEvent_UnmarshalDetail(t *testing.T) {
+	asst := assert.New(t)
+
+	e := events.EventBridgeEvent{}
+	readFixture(t, "eventbridge.json", &e)
+
+	detail := struct {
+		InstanceID string `json:"instance-id"`
+		State      string `json:"state"`
+	}{}
+	asst.NoError(e.UnmarshalDetail(&detail))
+	asst.Equal("i-1234567890abcdef0", detail.InstanceID)
+	asst.Equal("terminated", detail.State)
+}
+
+func Test_CloudWatchLogsRawData_Parse(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    func(t *testing.T) string
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			data: func(t *testing.T) string {
+				e := events.CloudWatchLogsEvent{}
+				readFixture(t, "cloudwatch_logs.json", &e)
+				return e.AWSLogs.Data
+			},
+		},
+		{
+			name:    "ng: invalid base64",
+			data:    func(t *testing.T) string { return "///" },
+			wantErr: true,
+		},
+		{
+			name:    "ng: not gzip",
+			data:    func(t *testing.T) string { return "aGVsbG8=" },
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			d, err := events.CloudWatchLogsRawData{Data: c.data(tt)}.Parse()
+			if c.wantErr {
+				asst.Error(err)
+				asst.Nil(d)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal("/aws/lambda/test", d.LogGroup)
+			asst.Equal([]string{"test-filter"}, d.SubscriptionFilters)
+			asst.Equal("DATA_MESSAGE", d.MessageType)
+			asst.Len(d.LogEvents, 1)
+			asst.Equal(int64(1552518348220), d.LogEvents[0].Timestamp)
+		})
+	}
+}
+
+func Test_DecodedBody(t *testing.T) {
+	asst := assert.New(t)
+
+	v1 := events.APIGatewayProxyRequest{}
+	readFixture(t, "apigateway_v1.json", &v1)
+	b, err := v1.DecodedBody()
+	asst.NoError(err)
+	asst.Equal(`{"test":"body"}`, string(b))
+
+	v2 := events.APIGatewayV2HTTPRequest{}
+	readFixture(t, "apigateway_v2.json", &v2)
+	b, err = v2.DecodedBody()
+	asst.NoError(err)
+	asst.Equal("Hello from Lambda", string(b))
+
+	alb := events.ALBTargetGroupRequest{Body: "///", IsBase64Encoded: true}
+	_, err = alb.DecodedBody()
+	asst.Error(err)
+}
@@ -0,0 +1,34 @@
+package events
+
+// KinesisEvent is the event of Amazon Kinesis Data Streams.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/with-kinesis.html
+type KinesisEvent struct {
+	Records []KinesisEventRecord `json:"Records"`
+}
+
+// KinesisEventRecord is a record in KinesisEvent.
+type KinesisEventRecord struct {
+	AWSRegion         string        `json:"awsRegion"`
+	EventID           string        `json:"eventID"`
+	EventName         string        `json:"eventName"`
+	EventSource       string        `json:"eventSource"`
+	EventSourceArn    string        `json:"eventSourceARN"`
+	EventVersion      string        `json:"eventVersion"`
+	InvokeIdentityArn string        `json:"invokeIdentityArn"`
+	Kinesis           KinesisRecord `json:"kinesis"`
+}
+
+// KinesisRecord is the data record in KinesisEventRecord.
+type KinesisRecord struct {
+	// The approximate time that the record was inserted into the stream, in seconds since the Unix epoch.
+	ApproximateArrivalTimestamp float64 `json:"approximateArrivalTimestamp"`
+
+	// The data blob, that is decoded from base64.
+	Data []byte `json:"data"`
+
+	EncryptionType       string `json:"encryptionType,omitempty"`
+	PartitionKey         string `json:"partitionKey"`
+	SequenceNumber       string `json:"sequenceNumber"`
+	KinesisSchemaVersion string `json:"kinesisSchemaVersion"`
+}
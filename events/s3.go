@@ -0,0 +1,67 @@
+package events
+
+import (
+	"net/url"
+	"time"
+)
+
+// S3Event is the event notification of Amazon S3.
+//
+// document: https://docs.aws.amazon.com/AmazonS3/latest/userguide/notification-content-structure.html
+type S3Event struct {
+	Records []S3EventRecord `json:"Records"`
+}
+
+// S3EventRecord is a record in S3Event.
+type S3EventRecord struct {
+	EventVersion      string              `json:"eventVersion"`
+	EventSource       string              `json:"eventSource"`
+	AWSRegion         string              `json:"awsRegion"`
+	EventTime         time.Time           `json:"eventTime"`
+	EventName         string              `json:"eventName"`
+	UserIdentity      S3UserIdentity      `json:"userIdentity"`
+	RequestParameters S3RequestParameters `json:"requestParameters"`
+	ResponseElements  map[string]string   `json:"responseElements"`
+	S3                S3Entity            `json:"s3"`
+}
+
+// S3UserIdentity is the identity of the user or the bucket owner.
+type S3UserIdentity struct {
+	PrincipalID string `json:"principalId"`
+}
+
+// S3RequestParameters is the parameters of the request that caused the event.
+type S3RequestParameters struct {
+	SourceIPAddress string `json:"sourceIPAddress"`
+}
+
+// S3Entity is the bucket and the object of S3EventRecord.
+type S3Entity struct {
+	SchemaVersion   string   `json:"s3SchemaVersion"`
+	ConfigurationID string   `json:"configurationId"`
+	Bucket          S3Bucket `json:"bucket"`
+	Object          S3Object `json:"object"`
+}
+
+// S3Bucket is the bucket of S3Entity.
+type S3Bucket struct {
+	Name          string         `json:"name"`
+	OwnerIdentity S3UserIdentity `json:"ownerIdentity"`
+	Arn           string         `json:"arn"`
+}
+
+// S3Object is the object of S3Entity.
+// Size and ETag are not included in the events of deletion.
+type S3Object struct {
+	Key       string `json:"key"`
+	Size      int64  `json:"size,omitempty"`
+	ETag      string `json:"eTag,omitempty"`
+	VersionID string `json:"versionId,omitempty"`
+	Sequencer string `json:"sequencer"`
+}
+
+// DecodedKey returns the object key. The key in the event is URL encoded,
+// e.g. "my+photo.jpg" for "my photo.jpg".
+func (o S3Object) DecodedKey() (string, error) {
+	return url.QueryUnescape(o.Key)
+}
@@ -0,0 +1,39 @@
+package events
+
+import "time"
+
+// SNSEvent is the event of Amazon SNS.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/with-sns.html
+type SNSEvent struct {
+	Records []SNSEventRecord `json:"Records"`
+}
+
+// SNSEventRecord is a record in SNSEvent.
+type SNSEventRecord struct {
+	EventVersion         string    `json:"EventVersion"`
+	EventSubscriptionArn string    `json:"EventSubscriptionArn"`
+	EventSource          string    `json:"EventSource"`
+	SNS                  SNSEntity `json:"Sns"`
+}
+
+// SNSEntity is the notification in SNSEventRecord.
+type SNSEntity struct {
+	SignatureVersion  string                         `json:"SignatureVersion"`
+	Timestamp         time.Time                      `json:"Timestamp"`
+	Signature         string                         `json:"Signature"`
+	SigningCertURL    string                         `json:"SigningCertUrl"`
+	MessageID         string                         `json:"MessageId"`
+	Message           string                         `json:"Message"`
+	MessageAttributes map[string]SNSMessageAttribute `json:"MessageAttributes"`
+	Type              string                         `json:"Type"`
+	UnsubscribeURL    string                         `json:"UnsubscribeUrl"`
+	TopicArn          string                         `json:"TopicArn"`
+	Subject           string                         `json:"Subject"`
+}
+
+// SNSMessageAttribute is a message attribute of SNSEntity.
+type SNSMessageAttribute struct {
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+}
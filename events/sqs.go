@@ -0,0 +1,32 @@
+package events
+
+// SQSEvent is the event of Amazon SQS.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/with-sqs.html
+type SQSEvent struct {
+	Records []SQSMessage `json:"Records"`
+}
+
+// SQSMessage is a message in SQSEvent.
+type SQSMessage struct {
+	MessageID              string                         `json:"messageId"`
+	ReceiptHandle          string                         `json:"receiptHandle"`
+	Body                   string                         `json:"body"`
+	Attributes             map[string]string              `json:"attributes"`
+	MessageAttributes      map[string]SQSMessageAttribute `json:"messageAttributes"`
+	MD5OfMessageAttributes string                         `json:"md5OfMessageAttributes,omitempty"`
+	MD5OfBody              string                         `json:"md5OfBody"`
+	EventSource            string                         `json:"eventSource"`
+	EventSourceARN         string                         `json:"eventSourceARN"`
+	AWSRegion              string                         `json:"awsRegion"`
+}
+
+// SQSMessageAttribute is a message attribute of SQSMessage.
+// The binary values are decoded from base64.
+type SQSMessageAttribute struct {
+	StringValue      *string  `json:"stringValue,omitempty"`
+	BinaryValue      []byte   `json:"binaryValue,omitempty"`
+	StringListValues []string `json:"stringListValues"`
+	BinaryListValues [][]byte `json:"binaryListValues"`
+	DataType         string   `json:"dataType"`
+}
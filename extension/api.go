@@ -49,7 +49,7 @@ func Register(ctx context.Context, client alago.AlagoClient, in *RegisterInput)
 	}
 
 	url := fmt.Sprintf(registerEndpointFmt, client.Host())
-	sc, h, b, err := internal.CallAPI(context.Background(), client, http.MethodPost, url, reqBody, hs...)
+	sc, h, b, err := internal.CallAPI(ctx, client, http.MethodPost, url, reqBody, hs...)
 	if err != nil {
 		return nil, err
 	}
@@ -102,7 +102,7 @@ func EventNext(ctx context.Context, client alago.AlagoClient, in *EventNextInput
 	}
 
 	url := fmt.Sprintf(eventNextEndpointFmt, client.Host())
-	sc, h, b, err := internal.CallAPI(context.Background(), client, http.MethodGet, url, nil, hs...)
+	sc, h, b, err := internal.CallAPI(ctx, client, http.MethodGet, url, nil, hs...)
 	if err != nil {
 		return nil, err
 	}
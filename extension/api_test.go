@@ -503,3 +503,40 @@ func Test_generateEventEventNextOutput(t *testing.T) {
 		})
 	}
 }
+
+func Test_canceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", "127.0.0.1:1")
+	ac, err := alago.NewClient(&alago.NewClientInput{HttpClient: &http.Client{}})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name string
+		call func() (any, error)
+	}{
+		{
+			name: "Register",
+			call: func() (any, error) {
+				return extension.Register(ctx, ac, &extension.RegisterInput{LambdaExtensionName: "test"})
+			},
+		},
+		{
+			name: "EventNext",
+			call: func() (any, error) {
+				return extension.EventNext(ctx, ac, &extension.EventNextInput{LambdaExtensionIdentifier: "test"})
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := c.call()
+			asst.ErrorIs(err, context.Canceled)
+			asst.Nil(out)
+		})
+	}
+}
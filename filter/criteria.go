@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/michimani/aws-lambda-api-go/events"
+	"github.com/michimani/aws-lambda-api-go/internal/jsonutil"
+)
+
+// Criteria is the filter criteria of the event source mapping.
+// The event matches Criteria when it matches any of the patterns. Empty Criteria matches any event.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/invocation-eventfiltering.html
+type Criteria []*Pattern
+
+// ParseCriteria parses FilterCriteria of the event source mapping,
+// e.g. {"Filters": [{"Pattern": "{\"body\": {\"type\": [\"order\"]}}"}]}.
+// The patterns that use the operators not supported by the event source mapping,
+// that is, wildcard and cidr, are rejected with ErrInvalidPattern as Lambda does.
+func ParseCriteria(b []byte) (Criteria, error) {
+	fc := struct {
+		Filters []struct {
+			Pattern string `json:"Pattern"`
+		} `json:"Filters"`
+	}{}
+	if err := json.Unmarshal(b, &fc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPattern, err)
+	}
+
+	c := make(Criteria, 0, len(fc.Filters))
+	for i, f := range fc.Filters {
+		p, err := compile([]byte(f.Pattern), syntaxEventSourceMapping)
+		if err != nil {
+			return nil, fmt.Errorf("Filters[%d]: %w", i, err)
+		}
+		c = append(c, p)
+	}
+
+	return c, nil
+}
+
+// Match reports whether the event (JSON) matches the criteria.
+func (c Criteria) Match(event []byte) (bool, error) {
+	v, err := jsonutil.Decode(event)
+	if err != nil {
+		return false, err
+	}
+	return c.MatchValue(v), nil
+}
+
+// MatchValue reports whether the event decoded from JSON matches the criteria.
+func (c Criteria) MatchValue(event any) bool {
+	if len(c) == 0 {
+		return true
+	}
+	for _, p := range c {
+		if p.MatchValue(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// SQSDocument returns the SQS message as the value that the event source mapping filters,
+// in which body is the parsed object if the body is a JSON object, otherwise the string as is.
+func SQSDocument(m events.SQSMessage) (map[string]any, error) {
+	doc, err := toDocument(m)
+	if err != nil {
+		return nil, err
+	}
+
+	if body, ok := parseObject([]byte(m.Body)); ok {
+		doc["body"] = body
+	}
+
+	return doc, nil
+}
+
+// KinesisDocument returns the Kinesis record as the value that the event source mapping filters,
+// that is the kinesis field of the record, in which data is the parsed object
+// if the data is a JSON object, otherwise the base64-encoded string.
+func KinesisDocument(r events.KinesisEventRecord) (map[string]any, error) {
+	doc, err := toDocument(r.Kinesis)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, ok := parseObject(r.Kinesis.Data); ok {
+		doc["data"] = data
+	}
+
+	return doc, nil
+}
+
+// DynamoDBDocument returns the DynamoDB Streams record as the value that the event source mapping filters.
+func DynamoDBDocument(r events.DynamoDBEventRecord) (map[string]any, error) {
+	return toDocument(r)
+}
+
+func toDocument(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, ok := parseObject(b)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a JSON object", v)
+	}
+	return doc, nil
+}
+
+func parseObject(b []byte) (map[string]any, bool) {
+	v, err := jsonutil.Decode(b)
+	if err != nil {
+		return nil, false
+	}
+	m, ok := v.(map[string]any)
+	return m, ok
+}
@@ -0,0 +1,246 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/michimani/aws-lambda-api-go/internal/jsonutil"
+)
+
+// ErrInvalidPattern is returned when the filter pattern is invalid.
+var ErrInvalidPattern = errors.New("invalid filter pattern")
+
+// Pattern is the compiled pattern of EventBridge rules and Lambda event filtering.
+// It supports the exact values, prefix, suffix, equals-ignore-case, anything-but,
+// numeric, exists, cidr, wildcard and $or.
+//
+// document: https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-patterns.html
+type Pattern struct {
+	root *objectPattern
+}
+
+// objectPattern matches a JSON object. All fields and at least one of each $or group must match.
+type objectPattern struct {
+	fields []*fieldPattern
+	or     [][]*objectPattern
+}
+
+// fieldPattern matches a field of a JSON object, with the nested pattern or the matchers of the values.
+type fieldPattern struct {
+	key      string
+	object   *objectPattern
+	matchers []matcher
+}
+
+// syntax is the set of the operators that are allowed in the pattern.
+type syntax int
+
+const (
+	// syntaxEventBridge allows all operators of EventBridge rules.
+	syntaxEventBridge syntax = iota
+
+	// syntaxEventSourceMapping is the syntax of the filter criteria of the event source mapping,
+	// that does not support wildcard and cidr.
+	syntaxEventSourceMapping
+)
+
+// Compile compiles the pattern of EventBridge rules, that must be a JSON object.
+// Use ParseCriteria for the filter criteria of the event source mapping.
+func Compile(pattern []byte) (*Pattern, error) {
+	return compile(pattern, syntaxEventBridge)
+}
+
+func compile(pattern []byte, sx syntax) (*Pattern, error) {
+	v, err := jsonutil.Decode(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPattern, err)
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: pattern must be a JSON object", ErrInvalidPattern)
+	}
+
+	root, err := compileObject(m, "", sx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pattern{root: root}, nil
+}
+
+// MustCompile is like Compile but panics if the pattern is invalid.
+func MustCompile(pattern string) *Pattern {
+	p, err := Compile([]byte(pattern))
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Match reports whether the event (JSON) matches the pattern.
+func (p *Pattern) Match(event []byte) (bool, error) {
+	v, err := jsonutil.Decode(event)
+	if err != nil {
+		return false, err
+	}
+	return p.MatchValue(v), nil
+}
+
+// MatchValue reports whether the event matches the pattern.
+// The event is the value decoded from JSON, and numbers are float64 or json.Number.
+// The event that is not map[string]any never matches.
+func (p *Pattern) MatchValue(event any) bool {
+	m, ok := event.(map[string]any)
+	if !ok {
+		return false
+	}
+	return p.root.match(m)
+}
+
+func (op *objectPattern) match(m map[string]any) bool {
+	for _, f := range op.fields {
+		if !f.match(m) {
+			return false
+		}
+	}
+
+	for _, group := range op.or {
+		if !slices.ContainsFunc(group, func(alt *objectPattern) bool { return alt.match(m) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f *fieldPattern) match(m map[string]any) bool {
+	v, present := m[f.key]
+
+	if f.object != nil {
+		objects := collectObjects(v, nil)
+		if len(objects) == 0 {
+			// the fields of the nested pattern do not exist
+			return f.object.match(map[string]any{})
+		}
+		return slices.ContainsFunc(objects, f.object.match)
+	}
+
+	var values []any
+	if present {
+		values = collectValues(v, nil)
+	}
+	for _, mt := range f.matchers {
+		if mt(values) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectObjects returns the objects in v, flattening the arrays.
+func collectObjects(v any, dst []map[string]any) []map[string]any {
+	switch vv := v.(type) {
+	case map[string]any:
+		dst = append(dst, vv)
+	case []any:
+		for _, e := range vv {
+			dst = collectObjects(e, dst)
+		}
+	}
+	return dst
+}
+
+// collectValues returns the leaf values (not objects nor arrays) in v, flattening the arrays.
+func collectValues(v any, dst []any) []any {
+	switch vv := v.(type) {
+	case map[string]any:
+	case []any:
+		for _, e := range vv {
+			dst = collectValues(e, dst)
+		}
+	default:
+		dst = append(dst, vv)
+	}
+	return dst
+}
+
+func compileObject(m map[string]any, path string, sx syntax) (*objectPattern, error) {
+	if len(m) == 0 {
+		return nil, invalid(path, "empty object")
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	op := &objectPattern{}
+	for _, k := range keys {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+
+		if k == "$or" {
+			group, err := compileOr(m[k], p, sx)
+			if err != nil {
+				return nil, err
+			}
+			op.or = append(op.or, group)
+			continue
+		}
+
+		switch v := m[k].(type) {
+		case map[string]any:
+			nested, err := compileObject(v, p, sx)
+			if err != nil {
+				return nil, err
+			}
+			op.fields = append(op.fields, &fieldPattern{key: k, object: nested})
+		case []any:
+			matchers, err := compileMatchers(v, p, sx)
+			if err != nil {
+				return nil, err
+			}
+			op.fields = append(op.fields, &fieldPattern{key: k, matchers: matchers})
+		default:
+			return nil, invalid(p, "must be an object or an array")
+		}
+	}
+
+	return op, nil
+}
+
+func compileOr(v any, path string, sx syntax) ([]*objectPattern, error) {
+	alts, ok := v.([]any)
+	if !ok || len(alts) == 0 {
+		return nil, invalid(path, "must be a non-empty array of objects")
+	}
+
+	group := make([]*objectPattern, 0, len(alts))
+	for i, a := range alts {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		m, ok := a.(map[string]any)
+		if !ok {
+			return nil, invalid(p, "must be an object")
+		}
+		alt, err := compileObject(m, p, sx)
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, alt)
+	}
+
+	return group, nil
+}
+
+func invalid(path, format string, args ...any) error {
+	if path == "" {
+		path = "(root)"
+	}
+	return fmt.Errorf("%w: %s: %s", ErrInvalidPattern, path, fmt.Sprintf(format, args...))
+}
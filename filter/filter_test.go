@@ -0,0 +1,303 @@
+package filter_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/events"
+	"github.com/michimani/aws-lambda-api-go/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_conformance runs the suite of the patterns and the events in testdata/conformance.json.
+func Test_conformance(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "conformance.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suite := []struct {
+		Name    string          `json:"name"`
+		Pattern json.RawMessage `json:"pattern"`
+		Events  []struct {
+			Event json.RawMessage `json:"event"`
+			Match bool            `json:"match"`
+		} `json:"events"`
+	}{}
+	if err := json.Unmarshal(b, &suite); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range suite {
+		t.Run(s.Name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			p, err := filter.Compile(s.Pattern)
+			if !asst.NoError(err) {
+				return
+			}
+
+			for _, e := range s.Events {
+				match, err := p.Match(e.Event)
+				asst.NoError(err)
+				asst.Equal(e.Match, match, "event: %s", e.Event)
+			}
+		})
+	}
+}
+
+func Test_Compile(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern string
+		wantErr bool
+	}{
+		{name: "ok", pattern: `{"source":["aws.ec2"],"detail":{"state":[{"anything-but":"stopped"}]}}`},
+		{name: "ng: not JSON", pattern: `{`, wantErr: true},
+		{name: "ng: trailing data", pattern: `{"a":[1]} {}`, wantErr: true},
+		{name: "ng: not object", pattern: `["aws.ec2"]`, wantErr: true},
+		{name: "ng: empty object", pattern: `{}`, wantErr: true},
+		{name: "ng: empty nested object", pattern: `{"detail":{}}`, wantErr: true},
+		{name: "ng: scalar field", pattern: `{"source":"aws.ec2"}`, wantErr: true},
+		{name: "ng: empty array", pattern: `{"source":[]}`, wantErr: true},
+		{name: "ng: nested array", pattern: `{"source":[["aws.ec2"]]}`, wantErr: true},
+		{name: "ng: unknown operator", pattern: `{"source":[{"contains":"ec2"}]}`, wantErr: true},
+		{name: "ng: operator object with two keys", pattern: `{"source":[{"prefix":"a","suffix":"b"}]}`, wantErr: true},
+		{name: "ng: prefix of number", pattern: `{"source":[{"prefix":1}]}`, wantErr: true},
+		{name: "ng: prefix with unknown option", pattern: `{"source":[{"prefix":{"ignore-case":"a"}}]}`, wantErr: true},
+		{name: "ng: equals-ignore-case of number", pattern: `{"source":[{"equals-ignore-case":1}]}`, wantErr: true},
+		{name: "ng: anything-but of mixed types", pattern: `{"source":[{"anything-but":["a",1]}]}`, wantErr: true},
+		{name: "ng: anything-but of empty array", pattern: `{"source":[{"anything-but":[]}]}`, wantErr: true},
+		{name: "ng: anything-but of boolean", pattern: `{"source":[{"anything-but":true}]}`, wantErr: true},
+		{name: "ng: anything-but of numeric", pattern: `{"source":[{"anything-but":{"numeric":[">",1]}}]}`, wantErr: true},
+		{name: "ng: numeric of odd length", pattern: `{"price":[{"numeric":[">",1,"<"]}]}`, wantErr: true},
+		{name: "ng: numeric of unknown operator", pattern: `{"price":[{"numeric":["!=",1]}]}`, wantErr: true},
+		{name: "ng: numeric of string", pattern: `{"price":[{"numeric":[">","1"]}]}`, wantErr: true},
+		{name: "ng: numeric of two lower bounds", pattern: `{"price":[{"numeric":[">",1,">=",2]}]}`, wantErr: true},
+		{name: "ng: numeric of equal and bound", pattern: `{"price":[{"numeric":["=",1,"<",2]}]}`, wantErr: true},
+		{name: "ng: exists of string", pattern: `{"source":[{"exists":"true"}]}`, wantErr: true},
+		{name: "ng: invalid cidr", pattern: `{"ip":[{"cidr":"10.0.0.0"}]}`, wantErr: true},
+		{name: "ng: consecutive wildcards", pattern: `{"key":[{"wildcard":"a**b"}]}`, wantErr: true},
+		{name: "ng: invalid escape of wildcard", pattern: `{"key":[{"wildcard":"a\\b"}]}`, wantErr: true},
+		{name: "ng: $or of object", pattern: `{"$or":{"a":[1]}}`, wantErr: true},
+		{name: "ng: $or of empty array", pattern: `{"$or":[]}`, wantErr: true},
+		{name: "ng: $or of invalid pattern", pattern: `{"$or":[{"a":[1]},{"b":1}]}`, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			p, err := filter.Compile([]byte(c.pattern))
+			if c.wantErr {
+				asst.ErrorIs(err, filter.ErrInvalidPattern)
+				asst.Nil(p)
+				return
+			}
+
+			asst.NoError(err)
+			asst.NotNil(p)
+		})
+	}
+}
+
+func Test_MustCompile(t *testing.T) {
+	asst := assert.New(t)
+
+	asst.NotPanics(func() { filter.MustCompile(`{"a":[1]}`) })
+	asst.Panics(func() { filter.MustCompile(`{"a":1}`) })
+}
+
+func Test_Pattern_Match(t *testing.T) {
+	p := filter.MustCompile(`{"a":[1]}`)
+
+	cases := []struct {
+		name    string
+		event   string
+		expect  bool
+		wantErr bool
+	}{
+		{name: "ok: match", event: `{"a":1}`, expect: true},
+		{name: "ok: not object", event: `[{"a":1}]`, expect: false},
+		{name: "ng: not JSON", event: `{"a":`, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			match, err := p.Match([]byte(c.event))
+			if c.wantErr {
+				asst.Error(err)
+				asst.False(match)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, match)
+		})
+	}
+}
+
+func Test_Pattern_MatchValue(t *testing.T) {
+	asst := assert.New(t)
+
+	p := filter.MustCompile(`{"price":[{"numeric":[">",1]}],"tags":["a"]}`)
+	asst.True(p.MatchValue(map[string]any{"price": 1.5, "tags": []any{"b", "a"}}))
+	asst.True(p.MatchValue(map[string]any{"price": json.Number("2"), "tags": "a"}))
+	asst.False(p.MatchValue(map[string]any{"price": 1, "tags": "a"})) // int is not a JSON number
+	asst.False(p.MatchValue(nil))
+}
+
+func Test_ParseCriteria(t *testing.T) {
+	cases := []struct {
+		name     string
+		criteria string
+		event    string
+		expect   bool
+		wantErr  bool
+	}{
+		{
+			name:     "ok: match any of the filters",
+			criteria: `{"Filters":[{"Pattern":"{\"a\":[1]}"},{"Pattern":"{\"b\":[2]}"}]}`,
+			event:    `{"b":2}`,
+			expect:   true,
+		},
+		{
+			name:     "ok: match none of the filters",
+			criteria: `{"Filters":[{"Pattern":"{\"a\":[1]}"},{"Pattern":"{\"b\":[2]}"}]}`,
+			event:    `{"c":3}`,
+			expect:   false,
+		},
+		{
+			name:     "ok: no filters",
+			criteria: `{"Filters":[]}`,
+			event:    `{"c":3}`,
+			expect:   true,
+		},
+		{
+			name:     "ok: supported operators",
+			criteria: `{"Filters":[{"Pattern":"{\"a\":[{\"prefix\":\"x\"},{\"anything-but\":{\"suffix\":\"z\"}}],\"cidr\":[{\"exists\":true}]}"}]}`,
+			event:    `{"a":"xyz","cidr":"10.0.0.1"}`,
+			expect:   true,
+		},
+		{
+			name:     "ng: invalid pattern",
+			criteria: `{"Filters":[{"Pattern":"{\"a\":[1]}"},{"Pattern":"{\"b\":2}"}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "ng: wildcard is not supported",
+			criteria: `{"Filters":[{"Pattern":"{\"a\":[{\"wildcard\":\"x*\"}]}"}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "ng: cidr is not supported",
+			criteria: `{"Filters":[{"Pattern":"{\"ip\":[{\"cidr\":\"10.0.0.0/24\"}]}"}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "ng: wildcard in anything-but is not supported",
+			criteria: `{"Filters":[{"Pattern":"{\"a\":[{\"anything-but\":{\"wildcard\":\"x*\"}}]}"}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "ng: cidr in nested pattern is not supported",
+			criteria: `{"Filters":[{"Pattern":"{\"body\":{\"$or\":[{\"ip\":[{\"cidr\":\"10.0.0.0/24\"}]},{\"b\":[1]}]}}"}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "ng: not JSON",
+			criteria: `{"Filters":`,
+			wantErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			criteria, err := filter.ParseCriteria([]byte(c.criteria))
+			if c.wantErr {
+				asst.ErrorIs(err, filter.ErrInvalidPattern)
+				asst.Nil(criteria)
+				return
+			}
+
+			asst.NoError(err)
+			match, err := criteria.Match([]byte(c.event))
+			asst.NoError(err)
+			asst.Equal(c.expect, match)
+		})
+	}
+}
+
+func Test_SQSDocument(t *testing.T) {
+	p := filter.MustCompile(`{"body":{"type":["order"]},"attributes":{"ApproximateReceiveCount":["1"]}}`)
+
+	cases := []struct {
+		name   string
+		body   string
+		expect bool
+	}{
+		{name: "ok: JSON body", body: `{"type":"order"}`, expect: true},
+		{name: "ok: JSON body not matched", body: `{"type":"refund"}`, expect: false},
+		{name: "ok: plain text body", body: `type=order`, expect: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			doc, err := filter.SQSDocument(events.SQSMessage{
+				MessageID:  "msg-1",
+				Body:       c.body,
+				Attributes: map[string]string{"ApproximateReceiveCount": "1"},
+			})
+			asst.NoError(err)
+			asst.Equal(c.expect, p.MatchValue(doc))
+		})
+	}
+
+	asst := assert.New(t)
+	doc, err := filter.SQSDocument(events.SQSMessage{Body: "plain"})
+	asst.NoError(err)
+	asst.Equal("plain", doc["body"])
+}
+
+func Test_KinesisDocument(t *testing.T) {
+	asst := assert.New(t)
+
+	p := filter.MustCompile(`{"partitionKey":["pk-1"],"data":{"temperature":[{"numeric":[">",30]}]}}`)
+
+	doc, err := filter.KinesisDocument(events.KinesisEventRecord{
+		Kinesis: events.KinesisRecord{PartitionKey: "pk-1", Data: []byte(`{"temperature":35}`)},
+	})
+	asst.NoError(err)
+	asst.True(p.MatchValue(doc))
+
+	doc, err = filter.KinesisDocument(events.KinesisEventRecord{
+		Kinesis: events.KinesisRecord{PartitionKey: "pk-1", Data: []byte("hello")},
+	})
+	asst.NoError(err)
+	asst.False(p.MatchValue(doc))
+	asst.Equal("aGVsbG8=", doc["data"])
+}
+
+func Test_DynamoDBDocument(t *testing.T) {
+	asst := assert.New(t)
+
+	p := filter.MustCompile(`{"eventName":["INSERT"],"dynamodb":{"NewImage":{"status":{"S":["active"]}}}}`)
+
+	status := "active"
+	doc, err := filter.DynamoDBDocument(events.DynamoDBEventRecord{
+		EventName: "INSERT",
+		Change: events.DynamoDBStreamRecord{
+			NewImage: map[string]events.AttributeValue{"status": {S: &status}},
+		},
+	})
+	asst.NoError(err)
+	asst.True(p.MatchValue(doc))
+}
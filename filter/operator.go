@@ -0,0 +1,422 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// matcher reports whether the values of the field match. values is empty when the field does not exist.
+type matcher func(values []any) bool
+
+// anyValue returns the matcher that matches when any of the values satisfies fn.
+func anyValue(fn func(v any) bool) matcher {
+	return func(values []any) bool {
+		return slices.ContainsFunc(values, fn)
+	}
+}
+
+// anyString returns the matcher that matches when any of the string values satisfies fn.
+func anyString(fn func(s string) bool) matcher {
+	return anyValue(func(v any) bool {
+		s, ok := v.(string)
+		return ok && fn(s)
+	})
+}
+
+// anyNumber returns the matcher that matches when any of the number values satisfies fn.
+func anyNumber(fn func(n float64) bool) matcher {
+	return anyValue(func(v any) bool {
+		n, ok := toNumber(v)
+		return ok && fn(n)
+	})
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func compileMatchers(values []any, path string, sx syntax) ([]matcher, error) {
+	if len(values) == 0 {
+		return nil, invalid(path, "empty array")
+	}
+
+	matchers := make([]matcher, 0, len(values))
+	for i, v := range values {
+		p := fmt.Sprintf("%s[%d]", path, i)
+
+		switch vv := v.(type) {
+		case map[string]any:
+			m, err := compileOperator(vv, p, sx)
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, m)
+		case []any:
+			return nil, invalid(p, "nested array")
+		default:
+			eq, err := equals(vv, p)
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, anyValue(eq))
+		}
+	}
+
+	return matchers, nil
+}
+
+// equals returns the function that reports whether the value is equal to the literal.
+// Numbers are compared by their values, e.g. 300 equals 3e2.
+func equals(literal any, path string) (func(v any) bool, error) {
+	switch l := literal.(type) {
+	case string:
+		return func(v any) bool { s, ok := v.(string); return ok && s == l }, nil
+	case json.Number:
+		n, ok := toNumber(l)
+		if !ok {
+			return nil, invalid(path, "invalid number %s", l)
+		}
+		return func(v any) bool { f, ok := toNumber(v); return ok && f == n }, nil
+	case bool:
+		return func(v any) bool { b, ok := v.(bool); return ok && b == l }, nil
+	case nil:
+		return func(v any) bool { return v == nil }, nil
+	}
+	return nil, invalid(path, "unsupported value %v", literal)
+}
+
+func compileOperator(m map[string]any, path string, sx syntax) (matcher, error) {
+	if len(m) != 1 {
+		return nil, invalid(path, "operator object must have exactly one key")
+	}
+
+	for op, arg := range m {
+		p := path + "." + op
+		if err := sx.check(op, p); err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case "prefix":
+			fn, err := compilePrefix(arg, p, strings.HasPrefix, hasPrefixFold)
+			if err != nil {
+				return nil, err
+			}
+			return anyString(fn), nil
+		case "suffix":
+			fn, err := compilePrefix(arg, p, strings.HasSuffix, hasSuffixFold)
+			if err != nil {
+				return nil, err
+			}
+			return anyString(fn), nil
+		case "equals-ignore-case":
+			fn, err := compileStringOperator(op, arg, p)
+			if err != nil {
+				return nil, err
+			}
+			return anyString(fn), nil
+		case "wildcard":
+			fn, err := compileStringOperator(op, arg, p)
+			if err != nil {
+				return nil, err
+			}
+			return anyString(fn), nil
+		case "anything-but":
+			return compileAnythingBut(arg, p, sx)
+		case "numeric":
+			fn, err := compileNumeric(arg, p)
+			if err != nil {
+				return nil, err
+			}
+			return anyNumber(fn), nil
+		case "exists":
+			exists, ok := arg.(bool)
+			if !ok {
+				return nil, invalid(p, "must be a boolean")
+			}
+			return func(values []any) bool { return (len(values) > 0) == exists }, nil
+		case "cidr":
+			fn, err := compileCIDR(arg, p)
+			if err != nil {
+				return nil, err
+			}
+			return anyString(fn), nil
+		default:
+			return nil, invalid(path, "unknown operator %q", op)
+		}
+	}
+
+	return nil, nil // unreachable
+}
+
+// check returns the error if the operator is not supported by the syntax.
+func (sx syntax) check(op, path string) error {
+	if sx == syntaxEventSourceMapping && (op == "wildcard" || op == "cidr") {
+		return invalid(path, "%s is not supported by the event source mapping", op)
+	}
+	return nil
+}
+
+// compilePrefix compiles the argument of prefix and suffix,
+// that is a string or {"equals-ignore-case": string}.
+func compilePrefix(arg any, path string, match, matchFold func(s, affix string) bool) (func(s string) bool, error) {
+	switch a := arg.(type) {
+	case string:
+		return func(s string) bool { return match(s, a) }, nil
+	case map[string]any:
+		fold, ok := a["equals-ignore-case"].(string)
+		if len(a) != 1 || !ok {
+			return nil, invalid(path, `must be a string or {"equals-ignore-case": string}`)
+		}
+		return func(s string) bool { return matchFold(s, fold) }, nil
+	}
+	return nil, invalid(path, `must be a string or {"equals-ignore-case": string}`)
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func hasSuffixFold(s, suffix string) bool {
+	return len(s) >= len(suffix) && strings.EqualFold(s[len(s)-len(suffix):], suffix)
+}
+
+// compileStringOperator compiles prefix, suffix, equals-ignore-case and wildcard with the string argument.
+func compileStringOperator(op string, arg any, path string) (func(s string) bool, error) {
+	a, ok := arg.(string)
+	if !ok {
+		return nil, invalid(path, "must be a string")
+	}
+
+	switch op {
+	case "prefix":
+		return func(s string) bool { return strings.HasPrefix(s, a) }, nil
+	case "suffix":
+		return func(s string) bool { return strings.HasSuffix(s, a) }, nil
+	case "equals-ignore-case":
+		return func(s string) bool { return strings.EqualFold(s, a) }, nil
+	case "wildcard":
+		return compileWildcard(a, path)
+	}
+	return nil, invalid(path, "unknown operator %q", op)
+}
+
+// compileAnythingBut compiles anything-but, whose argument is a string, a number,
+// an array of strings or numbers, or {"prefix" | "suffix" | "equals-ignore-case" | "wildcard": ...}.
+// It matches the values of the same type that are not matched with the argument,
+// e.g. anything-but of strings does not match numbers.
+func compileAnythingBut(arg any, path string, sx syntax) (matcher, error) {
+	switch a := arg.(type) {
+	case string, json.Number:
+		return compileAnythingBut([]any{a}, path, sx)
+	case []any:
+		if len(a) == 0 {
+			return nil, invalid(path, "empty array")
+		}
+
+		eqs := make([]func(v any) bool, 0, len(a))
+		_, isString := a[0].(string)
+		for i, e := range a {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			_, s := e.(string)
+			_, n := e.(json.Number)
+			if !(s || n) || s != isString {
+				return nil, invalid(p, "must be all strings or all numbers")
+			}
+			eq, err := equals(e, p)
+			if err != nil {
+				return nil, err
+			}
+			eqs = append(eqs, eq)
+		}
+
+		notAny := func(v any) bool {
+			return !slices.ContainsFunc(eqs, func(eq func(any) bool) bool { return eq(v) })
+		}
+		if isString {
+			return anyString(func(s string) bool { return notAny(s) }), nil
+		}
+		return anyNumber(func(n float64) bool { return notAny(n) }), nil
+	case map[string]any:
+		if len(a) != 1 {
+			return nil, invalid(path, "operator object must have exactly one key")
+		}
+		for op, opArg := range a {
+			p := path + "." + op
+			switch op {
+			case "prefix", "suffix", "equals-ignore-case", "wildcard":
+			default:
+				return nil, invalid(p, "unsupported operator in anything-but")
+			}
+			if err := sx.check(op, p); err != nil {
+				return nil, err
+			}
+
+			// equals-ignore-case and wildcard accept the array of strings
+			opArgs, isArray := opArg.([]any)
+			if !isArray || op == "prefix" || op == "suffix" {
+				opArgs = []any{opArg}
+			} else if len(opArgs) == 0 {
+				return nil, invalid(p, "empty array")
+			}
+
+			fns := make([]func(s string) bool, 0, len(opArgs))
+			for _, oa := range opArgs {
+				fn, err := compileStringOperator(op, oa, p)
+				if err != nil {
+					return nil, err
+				}
+				fns = append(fns, fn)
+			}
+
+			return anyString(func(s string) bool {
+				return !slices.ContainsFunc(fns, func(fn func(string) bool) bool { return fn(s) })
+			}), nil
+		}
+	}
+
+	return nil, invalid(path, "must be a string, a number, an array or an operator object")
+}
+
+// compileNumeric compiles numeric, whose argument is ["=", n], or one or two comparisons
+// of a lower bound (">", ">=") and an upper bound ("<", "<="), e.g. [">", 0, "<=", 5].
+func compileNumeric(arg any, path string) (func(n float64) bool, error) {
+	a, ok := arg.([]any)
+	if !ok || (len(a) != 2 && len(a) != 4) {
+		return nil, invalid(path, "must be an array of one or two pairs of an operator and a number")
+	}
+
+	conds := make([]func(n float64) bool, 0, 2)
+	var lower, upper, eq bool
+	for i := 0; i < len(a); i += 2 {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		op, ok := a[i].(string)
+		if !ok {
+			return nil, invalid(p, "operator must be a string")
+		}
+		l, ok := a[i+1].(json.Number)
+		if !ok {
+			return nil, invalid(fmt.Sprintf("%s[%d]", path, i+1), "must be a number")
+		}
+		v, ok := toNumber(l)
+		if !ok {
+			return nil, invalid(fmt.Sprintf("%s[%d]", path, i+1), "invalid number %s", l)
+		}
+
+		var dup bool
+		switch op {
+		case "=":
+			dup, eq = eq, true
+			conds = append(conds, func(n float64) bool { return n == v })
+		case ">":
+			dup, lower = lower, true
+			conds = append(conds, func(n float64) bool { return n > v })
+		case ">=":
+			dup, lower = lower, true
+			conds = append(conds, func(n float64) bool { return n >= v })
+		case "<":
+			dup, upper = upper, true
+			conds = append(conds, func(n float64) bool { return n < v })
+		case "<=":
+			dup, upper = upper, true
+			conds = append(conds, func(n float64) bool { return n <= v })
+		default:
+			return nil, invalid(p, "unknown operator %q", op)
+		}
+		if dup {
+			return nil, invalid(p, "duplicated bound %q", op)
+		}
+	}
+	if eq && len(a) != 2 {
+		return nil, invalid(path, `"=" cannot be combined with other operators`)
+	}
+
+	return func(n float64) bool {
+		for _, c := range conds {
+			if !c(n) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// compileCIDR compiles cidr, that matches the IPv4 or IPv6 addresses in the range.
+func compileCIDR(arg any, path string) (func(s string) bool, error) {
+	a, ok := arg.(string)
+	if !ok {
+		return nil, invalid(path, "must be a string")
+	}
+	prefix, err := netip.ParsePrefix(a)
+	if err != nil {
+		return nil, invalid(path, "%v", err)
+	}
+	prefix = prefix.Masked()
+
+	return func(s string) bool {
+		addr, err := netip.ParseAddr(s)
+		return err == nil && prefix.Contains(addr.Unmap())
+	}, nil
+}
+
+// compileWildcard compiles wildcard, in which "*" matches any characters.
+// "\*" and "\\" are the literal "*" and "\". Consecutive "*" are not allowed.
+func compileWildcard(pattern, path string) (func(s string) bool, error) {
+	segments := []string{}
+	cur := strings.Builder{}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '\\':
+			if i+1 >= len(pattern) || (pattern[i+1] != '*' && pattern[i+1] != '\\') {
+				return nil, invalid(path, "invalid escape at %d", i)
+			}
+			i++
+			cur.WriteByte(pattern[i])
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				return nil, invalid(path, "consecutive wildcards at %d", i)
+			}
+			segments = append(segments, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	segments = append(segments, cur.String())
+
+	return func(s string) bool {
+		return matchWildcard(s, segments)
+	}, nil
+}
+
+// matchWildcard reports whether s matches the segments that are separated by "*".
+func matchWildcard(s string, segments []string) bool {
+	if len(segments) == 1 {
+		return s == segments[0]
+	}
+
+	first, last := segments[0], segments[len(segments)-1]
+	if len(s) < len(first)+len(last) || !strings.HasPrefix(s, first) || !strings.HasSuffix(s, last) {
+		return false
+	}
+
+	rest := s[len(first) : len(s)-len(last)]
+	for _, seg := range segments[1 : len(segments)-1] {
+		i := strings.Index(rest, seg)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(seg):]
+	}
+	return true
+}
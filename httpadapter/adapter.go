@@ -0,0 +1,262 @@
+package httpadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/michimani/aws-lambda-api-go/runtime"
+)
+
+// New returns runtime.Handler that serves h for the events of API Gateway REST API (v1),
+// HTTP API (v2), ALB and function URL.
+// The event is converted into *http.Request, whose context is the context of the invocation,
+// and what h writes to http.ResponseWriter is converted into the response of the event type.
+// The body of the response is base64 encoded unless its Content-Type is textual.
+// If the event is none of the types, the handler returns ErrUnknownEvent.
+func New(h http.Handler) runtime.Handler {
+	return func(ctx context.Context, event []byte) ([]byte, error) {
+		pr := proxyRequest{}
+		if err := json.Unmarshal(event, &pr); err != nil {
+			return nil, err
+		}
+
+		et, err := pr.eventType()
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := newRequest(ctx, &pr, et)
+		if err != nil {
+			return nil, err
+		}
+
+		w := newResponseWriter()
+		h.ServeHTTP(w, req)
+
+		return json.Marshal(w.proxyResponse(et, pr.MultiValueHeaders != nil))
+	}
+}
+
+// newRequest converts the event into *http.Request.
+func newRequest(ctx context.Context, pr *proxyRequest, et EventType) (*http.Request, error) {
+	body := []byte(pr.Body)
+	if pr.IsBase64Encoded {
+		b, err := base64.StdEncoding.DecodeString(pr.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode base64 body: %w", err)
+		}
+		body = b
+	}
+
+	u := &url.URL{}
+	header := http.Header{}
+	var method, sourceIP string
+
+	switch et {
+	case EventTypeAPIGatewayV2, EventTypeFunctionURL:
+		method = pr.RequestContext.HTTP.Method
+		sourceIP = pr.RequestContext.HTTP.SourceIP
+		// rawPath is percent-encoded, e.g. /a%20b/c%2Fd
+		path, err := url.PathUnescape(pr.RawPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rawPath: %w", err)
+		}
+		u.Path = path
+		u.RawPath = pr.RawPath
+		u.RawQuery = pr.RawQueryString
+
+		for k, v := range pr.Headers {
+			header.Set(k, v)
+		}
+		if len(pr.Cookies) > 0 {
+			header.Set("Cookie", strings.Join(pr.Cookies, "; "))
+		}
+	default:
+		method = pr.HTTPMethod
+		if pr.RequestContext.Identity != nil {
+			sourceIP = pr.RequestContext.Identity.SourceIP
+		}
+		u.Path = pr.Path
+		u.RawQuery = queryValues(pr, et == EventTypeALB).Encode()
+
+		if pr.MultiValueHeaders != nil {
+			for k, vs := range pr.MultiValueHeaders {
+				for _, v := range vs {
+					header.Add(k, v)
+				}
+			}
+		} else {
+			for k, v := range pr.Headers {
+				header.Set(k, v)
+			}
+		}
+	}
+
+	if sourceIP == "" {
+		sourceIP = firstForwardedFor(header)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = header
+	req.Host = header.Get("Host")
+	if req.Host == "" {
+		req.Host = pr.RequestContext.DomainName
+	}
+	req.URL.Host = req.Host
+	req.RequestURI = u.RequestURI()
+	req.RemoteAddr = sourceIP
+
+	return req, nil
+}
+
+// queryValues returns the query string parameters of the event of payload format version 1.0 or ALB.
+// The parameters of ALB are not decoded, so they are decoded if escaped is true.
+func queryValues(pr *proxyRequest, escaped bool) url.Values {
+	unescape := func(s string) string {
+		if !escaped {
+			return s
+		}
+		if u, err := url.QueryUnescape(s); err == nil {
+			return u
+		}
+		return s
+	}
+
+	q := url.Values{}
+	if pr.MultiValueQueryStringParameters != nil {
+		for k, vs := range pr.MultiValueQueryStringParameters {
+			for _, v := range vs {
+				q.Add(unescape(k), unescape(v))
+			}
+		}
+		return q
+	}
+
+	for k, v := range pr.QueryStringParameters {
+		q.Set(unescape(k), unescape(v))
+	}
+	return q
+}
+
+func firstForwardedFor(h http.Header) string {
+	ff := h.Get("X-Forwarded-For")
+	if i := strings.Index(ff, ","); i >= 0 {
+		ff = ff[:i]
+	}
+	return strings.TrimSpace(ff)
+}
+
+// responseWriter is http.ResponseWriter that captures the response in memory.
+type responseWriter struct {
+	header      http.Header
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func newResponseWriter() *responseWriter {
+	return &responseWriter{header: http.Header{}, statusCode: http.StatusOK}
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.statusCode = statusCode
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// proxyResponse converts the captured response into the response of the event type.
+// For ALB, multiValue must be true when the multi-value headers are enabled on the target group.
+func (w *responseWriter) proxyResponse(et EventType, multiValue bool) *proxyResponse {
+	body := w.body.Bytes()
+	if w.header.Get("Content-Type") == "" && len(body) > 0 {
+		w.header.Set("Content-Type", http.DetectContentType(body))
+	}
+
+	res := &proxyResponse{StatusCode: w.statusCode}
+	if isText(w.header.Get("Content-Type"), body) {
+		res.Body = string(body)
+	} else {
+		res.Body = base64.StdEncoding.EncodeToString(body)
+		res.IsBase64Encoded = true
+	}
+
+	switch et {
+	case EventTypeAPIGatewayV2, EventTypeFunctionURL:
+		res.Headers, res.Cookies = splitCookies(w.header)
+	case EventTypeALB:
+		res.StatusDescription = strconv.Itoa(w.statusCode) + " " + http.StatusText(w.statusCode)
+		if !multiValue {
+			res.Headers = map[string]string{}
+			for k, vs := range w.header {
+				res.Headers[k] = strings.Join(vs, ",")
+			}
+			break
+		}
+		res.MultiValueHeaders = map[string][]string(w.header.Clone())
+	default:
+		res.MultiValueHeaders = map[string][]string(w.header.Clone())
+	}
+
+	return res
+}
+
+// splitCookies returns the headers of payload format version 2.0, whose values are joined by comma,
+// and the values of Set-Cookie header, that are returned as cookies.
+func splitCookies(h http.Header) (map[string]string, []string) {
+	headers := map[string]string{}
+	var cookies []string
+	for k, vs := range h {
+		if k == "Set-Cookie" {
+			cookies = append(cookies, vs...)
+			continue
+		}
+		headers[k] = strings.Join(vs, ",")
+	}
+	return headers, cookies
+}
+
+// isText reports whether the body of contentType can be returned without base64 encoding.
+func isText(contentType string, body []byte) bool {
+	if !utf8.Valid(body) {
+		return false
+	}
+
+	ct := strings.ToLower(contentType)
+	if i := strings.Index(ct, ";"); i >= 0 {
+		ct = strings.TrimSpace(ct[:i])
+	}
+
+	switch {
+	case ct == "", strings.HasPrefix(ct, "text/"):
+		return true
+	case strings.HasSuffix(ct, "json"), strings.HasSuffix(ct, "xml"), strings.HasSuffix(ct, "javascript"):
+		return true
+	case ct == "application/x-www-form-urlencoded":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,341 @@
+package httpadapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/httpadapter"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	apiGatewayV1Event = `{
+  "resource": "/{proxy+}",
+  "path": "/hello/world",
+  "httpMethod": "POST",
+  "headers": {"Host": "example.execute-api.us-east-1.amazonaws.com", "X-Test": "b"},
+  "multiValueHeaders": {"Host": ["example.execute-api.us-east-1.amazonaws.com"], "X-Test": ["a", "b"]},
+  "queryStringParameters": {"q": "2"},
+  "multiValueQueryStringParameters": {"q": ["1", "2"]},
+  "requestContext": {"requestId": "test-request-id", "stage": "prod", "identity": {"sourceIp": "192.0.2.1"}},
+  "body": "hello",
+  "isBase64Encoded": false
+}`
+
+	apiGatewayV2Event = `{
+  "version": "2.0",
+  "rawPath": "/hello/world",
+  "rawQueryString": "q=1&q=2",
+  "cookies": ["c1=v1", "c2=v2"],
+  "headers": {"host": "example.execute-api.us-east-1.amazonaws.com", "x-test": "a,b"},
+  "requestContext": {
+    "domainName": "example.execute-api.us-east-1.amazonaws.com",
+    "requestId": "test-request-id",
+    "http": {"method": "POST", "path": "/hello/world", "protocol": "HTTP/1.1", "sourceIp": "192.0.2.1"}
+  },
+  "body": "aGVsbG8=",
+  "isBase64Encoded": true
+}`
+
+	functionURLEvent = `{
+  "version": "2.0",
+  "rawPath": "/hello/world",
+  "rawQueryString": "q=1&q=2",
+  "headers": {"host": "example.lambda-url.us-east-1.on.aws", "x-test": "a,b"},
+  "requestContext": {
+    "domainName": "example.lambda-url.us-east-1.on.aws",
+    "requestId": "test-request-id",
+    "http": {"method": "POST", "path": "/hello/world", "protocol": "HTTP/1.1", "sourceIp": "192.0.2.1"}
+  },
+  "body": "hello",
+  "isBase64Encoded": false
+}`
+
+	albEvent = `{
+  "requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test/0123456789abcdef"}},
+  "httpMethod": "POST",
+  "path": "/hello/world",
+  "queryStringParameters": {"q": "a%20b"},
+  "headers": {"host": "example.com", "x-forwarded-for": "192.0.2.1, 198.51.100.1", "x-test": "b"},
+  "body": "hello",
+  "isBase64Encoded": false
+}`
+
+	albMultiValueEvent = `{
+  "requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test/0123456789abcdef"}},
+  "httpMethod": "POST",
+  "path": "/hello/world",
+  "multiValueQueryStringParameters": {"q": ["1", "2"]},
+  "multiValueHeaders": {"host": ["example.com"], "x-forwarded-for": ["192.0.2.1"], "x-test": ["a", "b"]},
+  "body": "hello",
+  "isBase64Encoded": false
+}`
+)
+
+func Test_DetectEventType(t *testing.T) {
+	cases := []struct {
+		name    string
+		event   string
+		expect  httpadapter.EventType
+		wantErr bool
+	}{
+		{
+			name:   "ok: API Gateway REST API",
+			event:  apiGatewayV1Event,
+			expect: httpadapter.EventTypeAPIGatewayV1,
+		},
+		{
+			name:   "ok: API Gateway HTTP API",
+			event:  apiGatewayV2Event,
+			expect: httpadapter.EventTypeAPIGatewayV2,
+		},
+		{
+			name:   "ok: function URL",
+			event:  functionURLEvent,
+			expect: httpadapter.EventTypeFunctionURL,
+		},
+		{
+			name:   "ok: ALB",
+			event:  albEvent,
+			expect: httpadapter.EventTypeALB,
+		},
+		{
+			name:    "ng: unknown event",
+			event:   `{"Records":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "ng: invalid JSON",
+			event:   `///`,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			et, err := httpadapter.DetectEventType([]byte(c.event))
+			if c.wantErr {
+				asst.Error(err)
+				asst.Empty(et)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, et)
+			asst.True(et.Valid())
+		})
+	}
+}
+
+// echoHandler writes the request as JSON, with the multi-value header and a cookie.
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	b, _ := io.ReadAll(r.Body)
+	cookies := []string{}
+	for _, c := range r.Cookies() {
+		cookies = append(cookies, c.String())
+	}
+	sort.Strings(cookies)
+	requestID, _ := runtime.RequestIDFromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("X-Multi", "1")
+	w.Header().Add("X-Multi", "2")
+	http.SetCookie(w, &http.Cookie{Name: "s", Value: "v"})
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"method":     r.Method,
+		"host":       r.Host,
+		"requestURI": r.RequestURI,
+		"query":      r.URL.Query(),
+		"test":       r.Header.Values("X-Test"),
+		"cookies":    cookies,
+		"remoteAddr": r.RemoteAddr,
+		"body":       string(b),
+		"requestID":  requestID,
+	})
+})
+
+// pathHandler writes the decoded and the escaped path of the request.
+var pathHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "%s %s", r.URL.Path, r.URL.EscapedPath())
+})
+
+func Test_New(t *testing.T) {
+	cases := []struct {
+		name    string
+		event   string
+		handler http.Handler
+		expect  string
+		wantErr error
+	}{
+		{
+			name:    "ok: API Gateway REST API",
+			event:   apiGatewayV1Event,
+			handler: echoHandler,
+			expect: `{
+  "statusCode": 201,
+  "multiValueHeaders": {"Content-Type": ["application/json"], "X-Multi": ["1", "2"], "Set-Cookie": ["s=v"]},
+  "body": "{\"body\":\"hello\",\"cookies\":[],\"host\":\"example.execute-api.us-east-1.amazonaws.com\",\"method\":\"POST\",\"query\":{\"q\":[\"1\",\"2\"]},\"remoteAddr\":\"192.0.2.1\",\"requestID\":\"test-invocation-id\",\"requestURI\":\"/hello/world?q=1\\u0026q=2\",\"test\":[\"a\",\"b\"]}\n",
+  "isBase64Encoded": false
+}`,
+		},
+		{
+			name:    "ok: API Gateway HTTP API",
+			event:   apiGatewayV2Event,
+			handler: echoHandler,
+			expect: `{
+  "statusCode": 201,
+  "headers": {"Content-Type": "application/json", "X-Multi": "1,2"},
+  "cookies": ["s=v"],
+  "body": "{\"body\":\"hello\",\"cookies\":[\"c1=v1\",\"c2=v2\"],\"host\":\"example.execute-api.us-east-1.amazonaws.com\",\"method\":\"POST\",\"query\":{\"q\":[\"1\",\"2\"]},\"remoteAddr\":\"192.0.2.1\",\"requestID\":\"test-invocation-id\",\"requestURI\":\"/hello/world?q=1\\u0026q=2\",\"test\":[\"a,b\"]}\n",
+  "isBase64Encoded": false
+}`,
+		},
+		{
+			name:    "ok: function URL",
+			event:   functionURLEvent,
+			handler: echoHandler,
+			expect: `{
+  "statusCode": 201,
+  "headers": {"Content-Type": "application/json", "X-Multi": "1,2"},
+  "cookies": ["s=v"],
+  "body": "{\"body\":\"hello\",\"cookies\":[],\"host\":\"example.lambda-url.us-east-1.on.aws\",\"method\":\"POST\",\"query\":{\"q\":[\"1\",\"2\"]},\"remoteAddr\":\"192.0.2.1\",\"requestID\":\"test-invocation-id\",\"requestURI\":\"/hello/world?q=1\\u0026q=2\",\"test\":[\"a,b\"]}\n",
+  "isBase64Encoded": false
+}`,
+		},
+		{
+			name:    "ok: ALB",
+			event:   albEvent,
+			handler: echoHandler,
+			expect: `{
+  "statusCode": 201,
+  "statusDescription": "201 Created",
+  "headers": {"Content-Type": "application/json", "X-Multi": "1,2", "Set-Cookie": "s=v"},
+  "body": "{\"body\":\"hello\",\"cookies\":[],\"host\":\"example.com\",\"method\":\"POST\",\"query\":{\"q\":[\"a b\"]},\"remoteAddr\":\"192.0.2.1\",\"requestID\":\"test-invocation-id\",\"requestURI\":\"/hello/world?q=a+b\",\"test\":[\"b\"]}\n",
+  "isBase64Encoded": false
+}`,
+		},
+		{
+			name:    "ok: ALB with multi-value headers",
+			event:   albMultiValueEvent,
+			handler: echoHandler,
+			expect: `{
+  "statusCode": 201,
+  "statusDescription": "201 Created",
+  "multiValueHeaders": {"Content-Type": ["application/json"], "X-Multi": ["1", "2"], "Set-Cookie": ["s=v"]},
+  "body": "{\"body\":\"hello\",\"cookies\":[],\"host\":\"example.com\",\"method\":\"POST\",\"query\":{\"q\":[\"1\",\"2\"]},\"remoteAddr\":\"192.0.2.1\",\"requestID\":\"test-invocation-id\",\"requestURI\":\"/hello/world?q=1\\u0026q=2\",\"test\":[\"a\",\"b\"]}\n",
+  "isBase64Encoded": false
+}`,
+		},
+		{
+			name:  "ok: binary body",
+			event: apiGatewayV2Event,
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				w.Write([]byte{0x89, 0x50, 0x4e, 0x47})
+			}),
+			expect: `{
+  "statusCode": 200,
+  "headers": {"Content-Type": "image/png"},
+  "body": "iVBORw==",
+  "isBase64Encoded": true
+}`,
+		},
+		{
+			name:  "ok: detected content type",
+			event: apiGatewayV2Event,
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "plain text")
+			}),
+			expect: `{
+  "statusCode": 200,
+  "headers": {"Content-Type": "text/plain; charset=utf-8"},
+  "body": "plain text",
+  "isBase64Encoded": false
+}`,
+		},
+		{
+			name:  "ok: no body",
+			event: apiGatewayV2Event,
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}),
+			expect: `{
+  "statusCode": 204,
+  "body": "",
+  "isBase64Encoded": false
+}`,
+		},
+		{
+			name:    "ok: API Gateway HTTP API with encoded path",
+			event:   strings.Replace(apiGatewayV2Event, `"rawPath": "/hello/world"`, `"rawPath": "/a%20b/c%2Fd"`, 1),
+			handler: pathHandler,
+			expect: `{
+  "statusCode": 200,
+  "headers": {"Content-Type": "text/plain; charset=utf-8"},
+  "body": "/a b/c/d /a%20b/c%2Fd",
+  "isBase64Encoded": false
+}`,
+		},
+		{
+			name:    "ok: function URL with encoded path",
+			event:   strings.Replace(functionURLEvent, `"rawPath": "/hello/world"`, `"rawPath": "/a%20b/c%2Fd"`, 1),
+			handler: pathHandler,
+			expect: `{
+  "statusCode": 200,
+  "headers": {"Content-Type": "text/plain; charset=utf-8"},
+  "body": "/a b/c/d /a%20b/c%2Fd",
+  "isBase64Encoded": false
+}`,
+		},
+		{
+			name:    "ng: unknown event",
+			event:   `{"Records":[]}`,
+			handler: echoHandler,
+			wantErr: httpadapter.ErrUnknownEvent,
+		},
+		{
+			name:    "ng: invalid encoding of rawPath",
+			event:   strings.Replace(apiGatewayV2Event, `"rawPath": "/hello/world"`, `"rawPath": "/a%zz"`, 1),
+			handler: echoHandler,
+			wantErr: assert.AnError,
+		},
+		{
+			name:    "ng: invalid base64 body",
+			event:   strings.Replace(apiGatewayV2Event, "aGVsbG8=", "///", 1),
+			handler: echoHandler,
+			wantErr: assert.AnError,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			ctx, cancel := runtime.NewInvocationContext(context.Background(), &runtime.NextOutput{AWSRequestID: "test-invocation-id"})
+			defer cancel()
+
+			out, err := httpadapter.New(c.handler)(ctx, []byte(c.event))
+			if c.wantErr != nil {
+				asst.Error(err)
+				if c.wantErr != assert.AnError {
+					asst.ErrorIs(err, c.wantErr)
+				}
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.JSONEq(c.expect, string(out))
+		})
+	}
+}
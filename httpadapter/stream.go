@@ -0,0 +1,163 @@
+package httpadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/michimani/aws-lambda-api-go/runtime"
+)
+
+const (
+	// Content-Type of the streaming response of function URL with the HTTP integration prelude.
+	StreamContentType string = "application/vnd.awslambda.http-integration-response"
+
+	// The number of NUL bytes that separate the prelude from the body.
+	preludeDelimiterSize int = 8
+)
+
+// StreamFlusher is the writer of the streaming response, e.g. *runtime.ResponseStreamWriter.
+type StreamFlusher interface {
+	io.Writer
+	Flush() error
+}
+
+// StreamResponseWriter is http.ResponseWriter and http.Flusher that writes the response
+// in the format of the streaming response of function URL.
+// The status code, headers and cookies are written as the JSON prelude, followed by 8 NUL bytes,
+// when the header is written, that is, at the first call of WriteHeader, Write or Flush.
+// The body is written after that as it is.
+type StreamResponseWriter struct {
+	mu          sync.Mutex
+	w           StreamFlusher
+	header      http.Header
+	wroteHeader bool
+	err         error
+}
+
+type streamPrelude struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Cookies    []string          `json:"cookies,omitempty"`
+}
+
+// NewStreamResponseWriter returns StreamResponseWriter that writes to w.
+// The Content-Type of the stream must be StreamContentType.
+func NewStreamResponseWriter(w StreamFlusher) *StreamResponseWriter {
+	return &StreamResponseWriter{w: w, header: http.Header{}}
+}
+
+func (w *StreamResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader writes the prelude with statusCode. The informational status codes (1xx) are ignored.
+func (w *StreamResponseWriter) WriteHeader(statusCode int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeader(statusCode, nil)
+}
+
+func (w *StreamResponseWriter) writeHeader(statusCode int, body []byte) {
+	if w.wroteHeader || (statusCode >= 100 && statusCode < 200) {
+		return
+	}
+	w.wroteHeader = true
+
+	if w.header.Get("Content-Type") == "" && len(body) > 0 {
+		w.header.Set("Content-Type", http.DetectContentType(body))
+	}
+
+	p := streamPrelude{StatusCode: statusCode}
+	p.Headers, p.Cookies = splitCookies(w.header)
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		w.err = err
+		return
+	}
+
+	b = append(b, make([]byte, preludeDelimiterSize)...)
+	if _, err := w.w.Write(b); err != nil {
+		w.err = fmt.Errorf("failed to write prelude: %w", err)
+	}
+}
+
+// Write writes p to the body. If the header has not been written, the prelude is written
+// with the status code 200 first.
+func (w *StreamResponseWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writeHeader(http.StatusOK, p)
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	return w.w.Write(p)
+}
+
+// Flush sends the written data to the client.
+func (w *StreamResponseWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writeHeader(http.StatusOK, nil)
+	if w.err != nil {
+		return
+	}
+
+	if err := w.w.Flush(); err != nil {
+		w.err = err
+	}
+}
+
+// Err returns the first error that occurred while writing the prelude or flushing.
+func (w *StreamResponseWriter) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// NewStreaming returns runtime.Handler that serves h for the events of function URL
+// (and API Gateway HTTP API) with the streaming response.
+// The response is sent by runtime.OpenResponseStream in the format of StreamResponseWriter,
+// so the handler must be run by runtime.StartHandler. h can send the response progressively
+// by http.Flusher, e.g. for server-sent events.
+// If the event is not of payload format version 2.0, the handler returns ErrUnknownEvent.
+func NewStreaming(h http.Handler) runtime.Handler {
+	return func(ctx context.Context, event []byte) ([]byte, error) {
+		pr := proxyRequest{}
+		if err := json.Unmarshal(event, &pr); err != nil {
+			return nil, err
+		}
+
+		et, err := pr.eventType()
+		if err != nil {
+			return nil, err
+		}
+		if et != EventTypeFunctionURL && et != EventTypeAPIGatewayV2 {
+			return nil, ErrUnknownEvent
+		}
+
+		req, err := newRequest(ctx, &pr, et)
+		if err != nil {
+			return nil, err
+		}
+
+		sw, err := runtime.OpenResponseStream(ctx, StreamContentType)
+		if err != nil {
+			return nil, err
+		}
+
+		w := NewStreamResponseWriter(sw)
+		h.ServeHTTP(w, req)
+
+		// The prelude must be written even if h writes nothing.
+		w.WriteHeader(http.StatusOK)
+		return nil, w.Err()
+	}
+}
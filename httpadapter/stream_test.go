@@ -0,0 +1,200 @@
+package httpadapter_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/httpadapter"
+	"github.com/michimani/aws-lambda-api-go/internal/runtimetest"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+type testFlusher struct {
+	bytes.Buffer
+	flushed []string
+	err     error
+}
+
+func (f *testFlusher) Write(p []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	return f.Buffer.Write(p)
+}
+
+func (f *testFlusher) Flush() error {
+	f.flushed = append(f.flushed, f.String())
+	return f.err
+}
+
+const nul8 = "\x00\x00\x00\x00\x00\x00\x00\x00"
+
+func Test_StreamResponseWriter(t *testing.T) {
+	cases := []struct {
+		name          string
+		handler       http.HandlerFunc
+		err           error
+		expect        string
+		expectFlushed []string
+		wantErr       bool
+	}{
+		{
+			name: "ok",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Header().Add("X-Multi", "1")
+				w.Header().Add("X-Multi", "2")
+				http.SetCookie(w, &http.Cookie{Name: "s", Value: "v"})
+				w.WriteHeader(http.StatusAccepted)
+				fmt.Fprint(w, "data: 1\n\n")
+				w.(http.Flusher).Flush()
+				fmt.Fprint(w, "data: 2\n\n")
+			},
+			expect: `{"statusCode":202,"headers":{"Content-Type":"text/event-stream","X-Multi":"1,2"},"cookies":["s=v"]}` + nul8 + "data: 1\n\ndata: 2\n\n",
+			expectFlushed: []string{
+				`{"statusCode":202,"headers":{"Content-Type":"text/event-stream","X-Multi":"1,2"},"cookies":["s=v"]}` + nul8 + "data: 1\n\n",
+			},
+		},
+		{
+			name: "ok: implicit status code and detected content type",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "hello")
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			expect: `{"statusCode":200,"headers":{"Content-Type":"text/plain; charset=utf-8"}}` + nul8 + "hello",
+		},
+		{
+			name: "ok: flush before write",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusContinue)
+				w.(http.Flusher).Flush()
+			},
+			expect:        `{"statusCode":200}` + nul8,
+			expectFlushed: []string{`{"statusCode":200}` + nul8},
+		},
+		{
+			name: "ng: failed to write prelude",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, err := fmt.Fprint(w, "hello")
+				if err == nil {
+					panic("error is expected")
+				}
+			},
+			err:     errors.New("test-error"),
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			f := &testFlusher{err: c.err}
+			w := httpadapter.NewStreamResponseWriter(f)
+			c.handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if c.wantErr {
+				asst.Error(w.Err())
+				return
+			}
+
+			asst.NoError(w.Err())
+			asst.Equal(c.expect, f.String())
+			asst.Equal(c.expectFlushed, f.flushed)
+		})
+	}
+}
+
+func Test_NewStreaming(t *testing.T) {
+	type result struct {
+		contentType string
+		body        string
+	}
+
+	cases := []struct {
+		name    string
+		event   string
+		handler http.HandlerFunc
+		expect  result
+	}{
+		{
+			name:  "ok",
+			event: functionURLEvent,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+				w.(http.Flusher).Flush()
+				fmt.Fprint(w, " done")
+			},
+			expect: result{
+				contentType: httpadapter.StreamContentType,
+				body:        `{"statusCode":200,"headers":{"Content-Type":"text/plain"}}` + nul8 + "POST /hello/world done",
+			},
+		},
+		{
+			name:    "ok: handler writes nothing",
+			event:   functionURLEvent,
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			expect: result{
+				contentType: httpadapter.StreamContentType,
+				body:        `{"statusCode":200}` + nul8,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			f := runtimetest.NewServer(tt, runtimetest.Event{RequestID: "req-1", Body: c.event})
+
+			err := runtime.StartHandler(httpadapter.NewStreaming(c.handler), runtime.WithClient(f.Client(tt)))
+			var rae *runtime.RuntimeAPIError
+			asst.ErrorAs(err, &rae)
+
+			results := []result{}
+			for _, r := range f.Results() {
+				results = append(results, result{contentType: r.Header.Get("Content-Type"), body: r.Body})
+			}
+			asst.Equal([]result{c.expect}, results)
+		})
+	}
+}
+
+func Test_NewStreaming_error(t *testing.T) {
+	cases := []struct {
+		name    string
+		ctx     context.Context
+		event   string
+		wantErr error
+	}{
+		{
+			name:    "ng: not payload format version 2.0",
+			ctx:     context.Background(),
+			event:   apiGatewayV1Event,
+			wantErr: httpadapter.ErrUnknownEvent,
+		},
+		{
+			name:    "ng: not started by runtime.StartHandler",
+			ctx:     context.Background(),
+			event:   functionURLEvent,
+			wantErr: runtime.ErrNoResponseStream,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := httpadapter.NewStreaming(http.NotFoundHandler())(c.ctx, []byte(c.event))
+			asst.ErrorIs(err, c.wantErr)
+			asst.Nil(out)
+		})
+	}
+}
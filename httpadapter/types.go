@@ -0,0 +1,116 @@
+package httpadapter
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// EventType is the type of the event that the adapter handles.
+type EventType string
+
+const (
+	// API Gateway REST API (payload format version 1.0)
+	EventTypeAPIGatewayV1 EventType = "APIGatewayV1"
+
+	// API Gateway HTTP API (payload format version 2.0)
+	EventTypeAPIGatewayV2 EventType = "APIGatewayV2"
+
+	// Application Load Balancer
+	EventTypeALB EventType = "ALB"
+
+	// Lambda function URL (same format as API Gateway HTTP API)
+	EventTypeFunctionURL EventType = "FunctionURL"
+)
+
+func (et EventType) Valid() bool {
+	return et == EventTypeAPIGatewayV1 || et == EventTypeAPIGatewayV2 || et == EventTypeALB || et == EventTypeFunctionURL
+}
+
+// ErrUnknownEvent is returned when the event is none of the event types that the adapter handles.
+var ErrUnknownEvent = errors.New("unknown event type")
+
+// proxyRequest is the union of the fields of API Gateway REST API, HTTP API, ALB and function URL events.
+type proxyRequest struct {
+	// payload format version 2.0
+	Version        string            `json:"version"`
+	RawPath        string            `json:"rawPath"`
+	RawQueryString string            `json:"rawQueryString"`
+	Cookies        []string          `json:"cookies"`
+	Headers        map[string]string `json:"headers"`
+
+	// payload format version 1.0 and ALB
+	Resource                        string              `json:"resource"`
+	Path                            string              `json:"path"`
+	HTTPMethod                      string              `json:"httpMethod"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters"`
+
+	RequestContext  proxyRequestContext `json:"requestContext"`
+	Body            string              `json:"body"`
+	IsBase64Encoded bool                `json:"isBase64Encoded"`
+}
+
+type proxyRequestContext struct {
+	RequestID  string `json:"requestId"`
+	DomainName string `json:"domainName"`
+	Stage      string `json:"stage"`
+
+	// payload format version 1.0
+	Identity *struct {
+		SourceIP string `json:"sourceIp"`
+	} `json:"identity"`
+
+	// payload format version 2.0
+	HTTP *struct {
+		Method   string `json:"method"`
+		Path     string `json:"path"`
+		Protocol string `json:"protocol"`
+		SourceIP string `json:"sourceIp"`
+	} `json:"http"`
+
+	// ALB
+	ELB *struct {
+		TargetGroupArn string `json:"targetGroupArn"`
+	} `json:"elb"`
+}
+
+// eventType returns the type of the event.
+func (r *proxyRequest) eventType() (EventType, error) {
+	switch {
+	case r.RequestContext.ELB != nil:
+		return EventTypeALB, nil
+	case r.Version == "2.0" && r.RequestContext.HTTP != nil:
+		if strings.Contains(r.RequestContext.DomainName, ".lambda-url.") {
+			return EventTypeFunctionURL, nil
+		}
+		return EventTypeAPIGatewayV2, nil
+	case r.HTTPMethod != "":
+		return EventTypeAPIGatewayV1, nil
+	}
+
+	return "", ErrUnknownEvent
+}
+
+// DetectEventType returns the type of the event.
+// If the event is none of the types, it returns ErrUnknownEvent.
+func DetectEventType(event []byte) (EventType, error) {
+	r := proxyRequest{}
+	if err := json.Unmarshal(event, &r); err != nil {
+		return "", err
+	}
+	return r.eventType()
+}
+
+// proxyResponse is the union of the fields of the responses for API Gateway REST API,
+// HTTP API, ALB and function URL.
+type proxyResponse struct {
+	StatusCode        int                 `json:"statusCode"`
+	StatusDescription string              `json:"statusDescription,omitempty"`
+	Headers           map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	Cookies           []string            `json:"cookies,omitempty"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
@@ -0,0 +1,10 @@
+package idempotency
+
+import "time"
+
+// Exported_WithClock sets the function that returns the current time, instead of time.Now.
+func Exported_WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
@@ -0,0 +1,133 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore is Store that saves each record as a JSON file in the directory, e.g. under /tmp.
+// It is safe for concurrent use in the process, but not across the processes sharing the directory.
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore returns FileStore that saves the records in dir. dir is created if it does not exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) PutInProgress(ctx context.Context, record *Record, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.read(record.Key)
+	if err != nil && !errors.Is(err, ErrRecordNotFound) {
+		return err
+	}
+	if r.Active(now) {
+		return ErrRecordExists
+	}
+
+	return s.write(record)
+}
+
+func (s *FileStore) Get(ctx context.Context, key string, now time.Time) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.read(key)
+	if err != nil {
+		return nil, err
+	}
+	if !now.Before(r.ExpiresAt) {
+		if err := s.remove(key); err != nil {
+			return nil, err
+		}
+		return nil, ErrRecordNotFound
+	}
+
+	return r, nil
+}
+
+func (s *FileStore) Update(ctx context.Context, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(record)
+}
+
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.remove(key)
+}
+
+// path returns the path of the file for the key, whose name is the hash of the key.
+func (s *FileStore) path(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(h[:])+".json")
+}
+
+func (s *FileStore) read(key string) (*Record, error) {
+	b, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Record{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// write writes the record to the temporary file and renames it, so that the file is not read partially written.
+func (s *FileStore) write(record *Record) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(s.dir, ".record-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), s.path(record.Key))
+}
+
+func (s *FileStore) remove(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
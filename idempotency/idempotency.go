@@ -0,0 +1,220 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/michimani/aws-lambda-api-go/envelope"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+)
+
+const (
+	// ErrorTypeAlreadyInProgress is the error type of InProgressError.
+	ErrorTypeAlreadyInProgress string = "Idempotency.AlreadyInProgress"
+
+	// DefaultExpiry is the default expiry of the idempotency records.
+	DefaultExpiry = time.Hour
+)
+
+// ErrNoKey is returned when the idempotency key is null and WithRequireKey is set.
+var ErrNoKey = errors.New("idempotency key not found in the event")
+
+// InProgressError is returned when the invocation with the same key is in progress.
+// Lambda retries the asynchronous invocation and the message of SQS is returned to the queue,
+// so that the invocation runs again after the in-progress one completes or fails.
+type InProgressError struct {
+	Key string
+}
+
+func (e *InProgressError) Error() string {
+	return fmt.Sprintf("invocation with the idempotency key %s is already in progress", e.Key)
+}
+
+// FunctionError returns the error document with the error type Idempotency.AlreadyInProgress.
+func (e *InProgressError) FunctionError() *runtime.FunctionError {
+	return &runtime.FunctionError{
+		ErrorMessage: e.Error(),
+		ErrorType:    ErrorTypeAlreadyInProgress,
+	}
+}
+
+// Option is the option of the idempotency middleware.
+type Option func(*options)
+
+type options struct {
+	key        *envelope.Envelope
+	expiry     time.Duration
+	prefix     string
+	requireKey bool
+	now        func() time.Time
+}
+
+// WithEventKey sets the envelope that extracts the part of the event used as the idempotency key,
+// e.g. envelope.MustNew("[body.orderId, body.customerId]"). The whole event is used by default.
+func WithEventKey(e *envelope.Envelope) Option {
+	return func(o *options) {
+		o.key = e
+	}
+}
+
+// WithExpiry sets the expiry of the records. The default is DefaultExpiry.
+func WithExpiry(d time.Duration) Option {
+	return func(o *options) {
+		o.expiry = d
+	}
+}
+
+// WithKeyPrefix sets the prefix of the keys.
+// The default is the function name given by AWS_LAMBDA_FUNCTION_NAME.
+func WithKeyPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithRequireKey makes the middleware return the error that wraps ErrNoKey when the key extracted from the event is null.
+// By default, the handler is called without idempotency in that case.
+func WithRequireKey() Option {
+	return func(o *options) {
+		o.requireKey = true
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		key:    envelope.MustNew("@"),
+		expiry: DefaultExpiry,
+		prefix: os.Getenv("AWS_LAMBDA_FUNCTION_NAME"),
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// New returns the middleware that makes the handler idempotent with the records in store.
+//
+// The key is the hash of the part of the event given by WithEventKey. Before the handler is called,
+// the record of the key is saved as in progress until the deadline of the invocation (ctx.Deadline).
+// When the handler succeeds, the record is updated as completed with the response, and the same response
+// is returned without calling the handler for the events with the same key until the record expires.
+// When the handler fails or panics, the record is deleted so that the retry calls the handler again.
+// The invocation with the key of an in-progress record fails with *InProgressError.
+//
+// Only the buffered response returned by the handler is saved. When the handler streams the response
+// by runtime.OpenResponseStream, the record is deleted after the handler returns, so that the events
+// with the same key call the handler again instead of getting an empty response.
+func New(store Store, opts ...Option) runtime.Middleware {
+	o := newOptions(opts)
+
+	return func(next runtime.Handler) runtime.Handler {
+		return func(ctx context.Context, event []byte) ([]byte, error) {
+			key, err := o.idempotencyKey(event)
+			if err != nil {
+				return nil, err
+			}
+			if key == "" {
+				return next(ctx, event)
+			}
+
+			now := o.now()
+			record := &Record{
+				Key:       key,
+				Status:    StatusInProgress,
+				ExpiresAt: now.Add(o.expiry),
+			}
+			if deadline, ok := ctx.Deadline(); ok {
+				record.InProgressExpiresAt = deadline
+			}
+
+			if err := store.PutInProgress(ctx, record, now); err != nil {
+				if !errors.Is(err, ErrRecordExists) {
+					return nil, fmt.Errorf("failed to save the idempotency record: %w", err)
+				}
+				return existingResponse(ctx, store, key, now)
+			}
+
+			return callAndSave(ctx, store, record, next, event)
+		}
+	}
+}
+
+// idempotencyKey returns the key of the event. It returns the empty string if the key is null.
+func (o *options) idempotencyKey(event []byte) (string, error) {
+	v, err := o.key.Extract(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract the idempotency key: %w", err)
+	}
+	if v == nil {
+		if o.requireKey {
+			return "", fmt.Errorf("%w: %s", ErrNoKey, o.key)
+		}
+		return "", nil
+	}
+
+	// json.Marshal sorts the keys of the objects, so the same value results in the same hash
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+
+	return o.prefix + "#" + hex.EncodeToString(h[:]), nil
+}
+
+func existingResponse(ctx context.Context, store Store, key string, now time.Time) ([]byte, error) {
+	r, err := store.Get(ctx, key, now)
+	if errors.Is(err, ErrRecordNotFound) {
+		// the record is deleted by the failed invocation after PutInProgress
+		return nil, &InProgressError{Key: key}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the idempotency record: %w", err)
+	}
+
+	if r.Status == StatusCompleted {
+		return r.Response, nil
+	}
+	return nil, &InProgressError{Key: key}
+}
+
+func callAndSave(ctx context.Context, store Store, record *Record, next runtime.Handler, event []byte) (out []byte, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			// release the lock, and let the runtime recover the panic
+			_ = store.Delete(ctx, record.Key)
+			panic(p)
+		}
+	}()
+
+	out, err = next(ctx, event)
+	if err != nil {
+		if derr := store.Delete(ctx, record.Key); derr != nil {
+			return nil, errors.Join(err, fmt.Errorf("failed to delete the idempotency record: %w", derr))
+		}
+		return nil, err
+	}
+
+	// the streamed response is not available to save
+	if runtime.ResponseStreamOpened(ctx) {
+		if err := store.Delete(ctx, record.Key); err != nil {
+			return nil, fmt.Errorf("failed to delete the idempotency record: %w", err)
+		}
+		return out, nil
+	}
+
+	record.Status = StatusCompleted
+	record.Response = out
+	if err := store.Update(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to save the idempotency record: %w", err)
+	}
+
+	return out, nil
+}
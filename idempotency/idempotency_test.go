@@ -0,0 +1,355 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/michimani/aws-lambda-api-go/envelope"
+	"github.com/michimani/aws-lambda-api-go/idempotency"
+	"github.com/michimani/aws-lambda-api-go/internal/runtimetest"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTest = errors.New("test-error")
+
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// countingHandler returns the handler that returns the number of the calls,
+// and fails for the events in failures.
+func countingHandler(failures ...string) (runtime.Handler, func() int) {
+	mu := sync.Mutex{}
+	calls := 0
+	h := func(ctx context.Context, event []byte) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		for _, f := range failures {
+			if string(event) == f {
+				return nil, errTest
+			}
+		}
+		return []byte{byte('0' + calls)}, nil
+	}
+	return h, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func Test_New(t *testing.T) {
+	type call struct {
+		event   string
+		advance time.Duration
+		expect  string
+		wantErr error
+	}
+
+	cases := []struct {
+		name        string
+		opts        []idempotency.Option
+		failures    []string
+		calls       []call
+		expectCalls int
+	}{
+		{
+			name: "ok: duplicated event returns the saved response",
+			calls: []call{
+				{event: `{"a":1,"b":2}`, expect: "1"},
+				{event: `{"b":2, "a":1}`, expect: "1"},
+				{event: `{"a":2}`, expect: "2"},
+			},
+			expectCalls: 2,
+		},
+		{
+			name: "ok: record expires",
+			opts: []idempotency.Option{idempotency.WithExpiry(time.Minute)},
+			calls: []call{
+				{event: `{"a":1}`, expect: "1"},
+				{event: `{"a":1}`, advance: 59 * time.Second, expect: "1"},
+				{event: `{"a":1}`, advance: time.Second, expect: "2"},
+			},
+			expectCalls: 2,
+		},
+		{
+			name: "ok: key from the part of the event",
+			opts: []idempotency.Option{idempotency.WithEventKey(envelope.MustNew("powertools_json(body).orderId"))},
+			calls: []call{
+				{event: `{"requestId":"r1","body":"{\"orderId\":\"o-1\"}"}`, expect: "1"},
+				{event: `{"requestId":"r2","body":"{\"orderId\":\"o-1\"}"}`, expect: "1"},
+				{event: `{"requestId":"r3","body":"{\"orderId\":\"o-2\"}"}`, expect: "2"},
+			},
+			expectCalls: 2,
+		},
+		{
+			name: "ok: no key",
+			opts: []idempotency.Option{idempotency.WithEventKey(envelope.MustNew("orderId"))},
+			calls: []call{
+				{event: `{"a":1}`, expect: "1"},
+				{event: `{"a":1}`, expect: "2"},
+			},
+			expectCalls: 2,
+		},
+		{
+			name:     "ok: failed invocation is not saved",
+			failures: []string{`{"a":1}`},
+			calls: []call{
+				{event: `{"a":1}`, wantErr: errTest},
+				{event: `{"a":1}`, wantErr: errTest},
+			},
+			expectCalls: 2,
+		},
+		{
+			name: "ng: no key with WithRequireKey",
+			opts: []idempotency.Option{idempotency.WithEventKey(envelope.MustNew("orderId")), idempotency.WithRequireKey()},
+			calls: []call{
+				{event: `{"a":1}`, wantErr: idempotency.ErrNoKey},
+			},
+			expectCalls: 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			clk := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+			opts := append([]idempotency.Option{idempotency.Exported_WithClock(clk.Now)}, c.opts...)
+			h, calls := countingHandler(c.failures...)
+			h = idempotency.New(idempotency.NewMemoryStore(), opts...)(h)
+
+			for _, call := range c.calls {
+				clk.Add(call.advance)
+				out, err := h(context.Background(), []byte(call.event))
+				if call.wantErr != nil {
+					asst.ErrorIs(err, call.wantErr)
+					asst.Nil(out)
+					continue
+				}
+
+				asst.NoError(err)
+				asst.Equal(call.expect, string(out))
+			}
+
+			asst.Equal(c.expectCalls, calls())
+		})
+	}
+}
+
+func Test_New_inProgress(t *testing.T) {
+	asst := assert.New(t)
+
+	clk := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := idempotency.New(idempotency.NewMemoryStore(), idempotency.Exported_WithClock(clk.Now))(
+		func(ctx context.Context, event []byte) ([]byte, error) {
+			close(started)
+			<-release
+			return []byte(`"done"`), nil
+		})
+
+	ctx, cancel := context.WithDeadline(context.Background(), clk.Now().Add(time.Minute))
+	defer cancel()
+
+	done := make(chan []byte)
+	go func() {
+		out, _ := h(ctx, []byte(`{"a":1}`))
+		done <- out
+	}()
+	<-started
+
+	out, err := h(context.Background(), []byte(`{"a":1}`))
+	var ipe *idempotency.InProgressError
+	asst.ErrorAs(err, &ipe)
+	asst.Nil(out)
+	fe := runtime.NewFunctionError(err)
+	asst.Equal(idempotency.ErrorTypeAlreadyInProgress, fe.ErrorType)
+
+	close(release)
+	asst.Equal(`"done"`, string(<-done))
+
+	out, err = h(context.Background(), []byte(`{"a":1}`))
+	asst.NoError(err)
+	asst.Equal(`"done"`, string(out))
+}
+
+func Test_New_deadline(t *testing.T) {
+	asst := assert.New(t)
+
+	ctx := context.Background()
+	clk := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := idempotency.NewMemoryStore()
+	h, calls := countingHandler()
+	h = idempotency.New(store, idempotency.WithKeyPrefix("fn"), idempotency.Exported_WithClock(clk.Now))(h)
+
+	// the record of the invocation that timed out
+	key := "fn#015abd7f5cc57a2dd94b7590f04ad8084273905ee33ec5cebeae62276a97f862" // sha256 of {"a":1}
+	asst.NoError(store.PutInProgress(ctx, &idempotency.Record{
+		Key:                 key,
+		Status:              idempotency.StatusInProgress,
+		ExpiresAt:           clk.Now().Add(time.Hour),
+		InProgressExpiresAt: clk.Now().Add(time.Minute),
+	}, clk.Now()))
+
+	_, err := h(ctx, []byte(`{"a":1}`))
+	asst.ErrorAs(err, new(*idempotency.InProgressError))
+
+	clk.Add(time.Minute)
+	out, err := h(ctx, []byte(`{"a":1}`))
+	asst.NoError(err)
+	asst.Equal("1", string(out))
+	asst.Equal(1, calls())
+
+	r, err := store.Get(ctx, key, clk.Now())
+	asst.NoError(err)
+	asst.Equal(idempotency.StatusCompleted, r.Status)
+}
+
+func Test_New_panic(t *testing.T) {
+	asst := assert.New(t)
+
+	ctx := context.Background()
+	store := idempotency.NewMemoryStore()
+	h := idempotency.New(store, idempotency.WithKeyPrefix("fn"))(func(ctx context.Context, event []byte) ([]byte, error) {
+		panic("test-panic")
+	})
+
+	asst.PanicsWithValue("test-panic", func() { h(ctx, []byte(`{"a":1}`)) })
+
+	_, err := store.Get(ctx, "fn#015abd7f5cc57a2dd94b7590f04ad8084273905ee33ec5cebeae62276a97f862", time.Now())
+	asst.ErrorIs(err, idempotency.ErrRecordNotFound)
+}
+
+func Test_New_responseStream(t *testing.T) {
+	asst := assert.New(t)
+
+	// Runtime API that sends the same event twice
+	f := runtimetest.NewServer(t,
+		runtimetest.Event{RequestID: "req-1", Body: `{"a":1}`},
+		runtimetest.Event{RequestID: "req-2", Body: `{"a":1}`},
+	)
+
+	store := idempotency.NewMemoryStore()
+	calls := 0
+	err := runtime.StartHandler(func(ctx context.Context, event []byte) ([]byte, error) {
+		calls++
+		w, err := runtime.OpenResponseStream(ctx, "text/plain")
+		if err != nil {
+			return nil, err
+		}
+		_, err = fmt.Fprintf(w, "streamed-%d", calls)
+		return nil, err
+	}, runtime.WithClient(f.Client(t)), runtime.WithMiddleware(idempotency.New(store, idempotency.WithKeyPrefix("fn"))))
+
+	var rae *runtime.RuntimeAPIError
+	asst.ErrorAs(err, &rae)
+
+	// the streamed response is not saved, so the duplicated event calls the handler again
+	asst.Equal(2, calls)
+	bodies := []string{}
+	for _, r := range f.Results() {
+		bodies = append(bodies, r.Body)
+	}
+	asst.Equal([]string{"streamed-1", "streamed-2"}, bodies)
+
+	_, err = store.Get(context.Background(), "fn#015abd7f5cc57a2dd94b7590f04ad8084273905ee33ec5cebeae62276a97f862", time.Now())
+	asst.ErrorIs(err, idempotency.ErrRecordNotFound)
+}
+
+type failingStore struct {
+	idempotency.Store
+	put, update, delete error
+}
+
+func (s *failingStore) PutInProgress(ctx context.Context, r *idempotency.Record, now time.Time) error {
+	if s.put != nil {
+		return s.put
+	}
+	return s.Store.PutInProgress(ctx, r, now)
+}
+
+func (s *failingStore) Update(ctx context.Context, r *idempotency.Record) error {
+	if s.update != nil {
+		return s.update
+	}
+	return s.Store.Update(ctx, r)
+}
+
+func (s *failingStore) Delete(ctx context.Context, key string) error {
+	if s.delete != nil {
+		return s.delete
+	}
+	return s.Store.Delete(ctx, key)
+}
+
+func Test_New_storeError(t *testing.T) {
+	errStore := errors.New("store-error")
+
+	cases := []struct {
+		name     string
+		store    *failingStore
+		event    string
+		failures []string
+		wantErrs []error
+	}{
+		{
+			name:     "ng: failed to put",
+			store:    &failingStore{Store: idempotency.NewMemoryStore(), put: errStore},
+			event:    `{"a":1}`,
+			wantErrs: []error{errStore},
+		},
+		{
+			name:     "ng: failed to update",
+			store:    &failingStore{Store: idempotency.NewMemoryStore(), update: errStore},
+			event:    `{"a":1}`,
+			wantErrs: []error{errStore},
+		},
+		{
+			name:     "ng: failed to delete",
+			store:    &failingStore{Store: idempotency.NewMemoryStore(), delete: errStore},
+			event:    `{"a":1}`,
+			failures: []string{`{"a":1}`},
+			wantErrs: []error{errStore, errTest},
+		},
+		{
+			name:  "ng: event is not JSON",
+			store: &failingStore{Store: idempotency.NewMemoryStore()},
+			event: `{`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			h, _ := countingHandler(c.failures...)
+			out, err := idempotency.New(c.store)(h)(context.Background(), []byte(c.event))
+			asst.Error(err)
+			for _, w := range c.wantErrs {
+				asst.ErrorIs(err, w)
+			}
+			asst.Nil(out)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is Store that keeps the records in memory.
+// The records are shared by the invocations in the same execution environment,
+// but not across the execution environments.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns the empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]Record{}}
+}
+
+func (s *MemoryStore) PutInProgress(ctx context.Context, record *Record, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[record.Key]; ok && r.Active(now) {
+		return ErrRecordExists
+	}
+
+	s.records[record.Key] = *record
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string, now time.Time) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[key]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	if !now.Before(r.ExpiresAt) {
+		delete(s.records, key)
+		return nil, ErrRecordNotFound
+	}
+
+	return &r, nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := *record
+	r.Response = bytes.Clone(record.Response)
+	s.records[record.Key] = r
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
@@ -0,0 +1,71 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrRecordExists is returned by Store.PutInProgress when the active record exists for the key.
+	ErrRecordExists = errors.New("idempotency record already exists")
+
+	// ErrRecordNotFound is returned by Store.Get when no record exists for the key, or the record is expired.
+	ErrRecordNotFound = errors.New("idempotency record not found")
+)
+
+// Status is the status of the idempotency record.
+type Status string
+
+const (
+	StatusInProgress Status = "INPROGRESS"
+	StatusCompleted  Status = "COMPLETED"
+)
+
+// Record is the idempotency record, that is saved for each key.
+type Record struct {
+	Key    string `json:"key"`
+	Status Status `json:"status"`
+
+	// The record is expired at ExpiresAt, and the invocation with the same key runs again after it.
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	// The lock of StatusInProgress is released at InProgressExpiresAt, that is the deadline of the invocation.
+	// It is zero if the invocation has no deadline.
+	InProgressExpiresAt time.Time `json:"inProgressExpiresAt,omitempty"`
+
+	// The response of the handler, that is set with StatusCompleted.
+	Response []byte `json:"response,omitempty"`
+}
+
+// Active reports whether the record prevents the invocation with the same key at now:
+// the record is not expired, and it is completed or its in-progress lock is not released.
+func (r *Record) Active(now time.Time) bool {
+	if r == nil || !now.Before(r.ExpiresAt) {
+		return false
+	}
+	if r.Status == StatusCompleted {
+		return true
+	}
+	return r.InProgressExpiresAt.IsZero() || now.Before(r.InProgressExpiresAt)
+}
+
+// Store is the persistence store of the idempotency records.
+// The implementations must be safe for concurrent use.
+type Store interface {
+	// PutInProgress saves the record with StatusInProgress,
+	// unless the record that is Active at now exists for the same key.
+	// In that case, it returns ErrRecordExists. Checking and saving must be atomic,
+	// e.g. a conditional write of DynamoDB.
+	PutInProgress(ctx context.Context, record *Record, now time.Time) error
+
+	// Get returns the record for the key.
+	// It returns ErrRecordNotFound if the record does not exist or it is expired at now.
+	Get(ctx context.Context, key string, now time.Time) (*Record, error)
+
+	// Update overwrites the record for the key, e.g. with StatusCompleted and the response.
+	Update(ctx context.Context, record *Record) error
+
+	// Delete deletes the record for the key. It does not return an error if the record does not exist.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,151 @@
+package idempotency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/michimani/aws-lambda-api-go/idempotency"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Record_Active(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		record *idempotency.Record
+		expect bool
+	}{
+		{
+			name:   "ok: completed",
+			record: &idempotency.Record{Status: idempotency.StatusCompleted, ExpiresAt: now.Add(time.Second), InProgressExpiresAt: now.Add(-time.Second)},
+			expect: true,
+		},
+		{
+			name:   "ok: in progress",
+			record: &idempotency.Record{Status: idempotency.StatusInProgress, ExpiresAt: now.Add(time.Second), InProgressExpiresAt: now.Add(time.Second)},
+			expect: true,
+		},
+		{
+			name:   "ok: in progress without deadline",
+			record: &idempotency.Record{Status: idempotency.StatusInProgress, ExpiresAt: now.Add(time.Second)},
+			expect: true,
+		},
+		{
+			name:   "ok: in-progress lock is released",
+			record: &idempotency.Record{Status: idempotency.StatusInProgress, ExpiresAt: now.Add(time.Second), InProgressExpiresAt: now},
+			expect: false,
+		},
+		{
+			name:   "ok: expired",
+			record: &idempotency.Record{Status: idempotency.StatusCompleted, ExpiresAt: now},
+			expect: false,
+		},
+		{
+			name:   "ok: nil",
+			record: nil,
+			expect: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			asst.Equal(c.expect, c.record.Active(now))
+		})
+	}
+}
+
+func Test_Store(t *testing.T) {
+	cases := []struct {
+		name  string
+		store func(t *testing.T) idempotency.Store
+	}{
+		{
+			name:  "ok: MemoryStore",
+			store: func(t *testing.T) idempotency.Store { return idempotency.NewMemoryStore() },
+		},
+		{
+			name: "ok: FileStore",
+			store: func(t *testing.T) idempotency.Store {
+				s, err := idempotency.NewFileStore(t.TempDir())
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			ctx := context.Background()
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			s := c.store(tt)
+
+			r, err := s.Get(ctx, "k", now)
+			asst.ErrorIs(err, idempotency.ErrRecordNotFound)
+			asst.Nil(r)
+
+			inProgress := &idempotency.Record{
+				Key:                 "k",
+				Status:              idempotency.StatusInProgress,
+				ExpiresAt:           now.Add(time.Hour),
+				InProgressExpiresAt: now.Add(time.Minute),
+			}
+			asst.NoError(s.PutInProgress(ctx, inProgress, now))
+			asst.ErrorIs(s.PutInProgress(ctx, inProgress, now), idempotency.ErrRecordExists)
+
+			r, err = s.Get(ctx, "k", now)
+			asst.NoError(err)
+			asst.Equal(idempotency.StatusInProgress, r.Status)
+			asst.True(inProgress.ExpiresAt.Equal(r.ExpiresAt))
+
+			// the lock is released at the deadline
+			asst.NoError(s.PutInProgress(ctx, inProgress, now.Add(time.Minute)))
+
+			completed := *inProgress
+			completed.Status = idempotency.StatusCompleted
+			completed.Response = []byte(`"ok"`)
+			asst.NoError(s.Update(ctx, &completed))
+			asst.ErrorIs(s.PutInProgress(ctx, inProgress, now.Add(time.Minute)), idempotency.ErrRecordExists)
+
+			r, err = s.Get(ctx, "k", now)
+			asst.NoError(err)
+			asst.Equal(idempotency.StatusCompleted, r.Status)
+			asst.Equal([]byte(`"ok"`), r.Response)
+
+			// expired
+			r, err = s.Get(ctx, "k", now.Add(time.Hour))
+			asst.ErrorIs(err, idempotency.ErrRecordNotFound)
+			asst.Nil(r)
+			asst.NoError(s.PutInProgress(ctx, inProgress, now.Add(time.Hour)))
+
+			asst.NoError(s.Delete(ctx, "k"))
+			asst.NoError(s.Delete(ctx, "k"))
+			_, err = s.Get(ctx, "k", now)
+			asst.ErrorIs(err, idempotency.ErrRecordNotFound)
+		})
+	}
+}
+
+func Test_FileStore_persistence(t *testing.T) {
+	asst := assert.New(t)
+
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+
+	s1, err := idempotency.NewFileStore(dir)
+	asst.NoError(err)
+	asst.NoError(s1.Update(ctx, &idempotency.Record{Key: "k", Status: idempotency.StatusCompleted, ExpiresAt: now.Add(time.Hour)}))
+
+	s2, err := idempotency.NewFileStore(dir)
+	asst.NoError(err)
+	r, err := s2.Get(ctx, "k", now)
+	asst.NoError(err)
+	asst.Equal(idempotency.StatusCompleted, r.Status)
+}
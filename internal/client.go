@@ -3,6 +3,7 @@ package internal
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -16,11 +17,31 @@ type Header struct {
 
 // CallAPI execute http request using alago.Client
 // and returns StatusCode, ResponseHeader, ResponseBody as slice of bytes and error.
+// If ctx is canceled or its deadline is exceeded during the request,
+// the returned error wraps context.Canceled or context.DeadlineExceeded.
 func CallAPI(ctx context.Context, c alago.AlagoClient, method, url string, body io.Reader, headers ...Header) (int, map[string][]string, []byte, error) {
-	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	res, err := CallAPIStream(ctx, c, method, url, body, headers...)
 	if err != nil {
 		return 0, nil, nil, err
 	}
+	defer res.Body.Close()
+
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, res.Body); err != nil {
+		return 0, nil, nil, ContextError(ctx, err)
+	}
+
+	return res.StatusCode, res.Header, buf.Bytes(), nil
+}
+
+// CallAPIStream execute http request using alago.Client
+// and returns the response whose body has not been read yet.
+// The caller must close the response body.
+func CallAPIStream(ctx context.Context, c alago.AlagoClient, method, url string, body io.Reader, headers ...Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
 
 	req.Header.Set("Content-Type", "application/json;charset=UTF-8")
 
@@ -31,14 +52,18 @@ func CallAPI(ctx context.Context, c alago.AlagoClient, method, url string, body
 
 	res, err := c.HttpClient().Do(req)
 	if err != nil {
-		return 0, nil, nil, err
+		return nil, ContextError(ctx, err)
 	}
-	defer res.Body.Close()
 
-	buf := new(bytes.Buffer)
-	if _, err := io.Copy(buf, res.Body); err != nil {
-		return 0, nil, nil, err
-	}
+	return res, nil
+}
 
-	return res.StatusCode, res.Header, buf.Bytes(), nil
+// ContextError returns the error that wraps the error of ctx if ctx is done,
+// so that the cancellation can be distinguished from the other errors by errors.Is.
+// Otherwise it returns err as it is.
+func ContextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w: %v", ctxErr, err)
+	}
+	return err
 }
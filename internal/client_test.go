@@ -2,8 +2,12 @@ package internal_test
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/michimani/aws-lambda-api-go/alago"
 	"github.com/michimani/aws-lambda-api-go/internal"
@@ -163,3 +167,126 @@ func Test_CallAPI(t *testing.T) {
 		})
 	}
 }
+
+func Test_CallAPI_context(t *testing.T) {
+	cases := []struct {
+		name   string
+		ctx    func() (context.Context, context.CancelFunc)
+		expect error
+	}{
+		{
+			name: "ng: canceled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				go func() {
+					time.Sleep(10 * time.Millisecond)
+					cancel()
+				}()
+				return ctx, cancel
+			},
+			expect: context.Canceled,
+		},
+		{
+			name: "ng: deadline exceeded",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			expect: context.DeadlineExceeded,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			// the server blocks until the request is canceled
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			}))
+			defer s.Close()
+			tt.Setenv("AWS_LAMBDA_RUNTIME_API", "test-env-value")
+
+			ac, err := alago.NewClient(&alago.NewClientInput{HttpClient: s.Client()})
+			asst.NoError(err)
+
+			ctx, cancel := c.ctx()
+			defer cancel()
+
+			sc, h, b, err := internal.CallAPI(ctx, ac, http.MethodGet, s.URL, nil)
+			asst.ErrorIs(err, c.expect)
+			asst.Equal(0, sc)
+			asst.Nil(h)
+			asst.Nil(b)
+		})
+	}
+}
+
+func Test_CallAPIStream(t *testing.T) {
+	asst := assert.New(t)
+
+	// the server sends the first chunk and blocks until the request is canceled
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Test-Header-Name", "test-header-value")
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer s.Close()
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", "test-env-value")
+
+	ac, err := alago.NewClient(&alago.NewClientInput{HttpClient: s.Client()})
+	asst.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	res, err := internal.CallAPIStream(ctx, ac, http.MethodGet, s.URL, nil)
+	asst.NoError(err)
+	defer res.Body.Close()
+
+	// the response is returned before the whole body is received
+	asst.Equal(200, res.StatusCode)
+	asst.Equal("test-header-value", res.Header.Get("Test-Header-Name"))
+
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(res.Body, buf)
+	asst.NoError(err)
+	asst.Equal("first", string(buf))
+}
+
+func Test_ContextError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := []struct {
+		name     string
+		ctx      context.Context
+		err      error
+		expectIs []error
+	}{
+		{
+			name:     "ok: context is done",
+			ctx:      canceled,
+			err:      errors.New("test-error"),
+			expectIs: []error{context.Canceled},
+		},
+		{
+			name:     "ok: context is not done",
+			ctx:      context.Background(),
+			err:      io.ErrUnexpectedEOF,
+			expectIs: []error{io.ErrUnexpectedEOF},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			err := internal.ContextError(c.ctx, c.err)
+			asst.Contains(err.Error(), c.err.Error())
+			for _, e := range c.expectIs {
+				asst.ErrorIs(err, e)
+			}
+		})
+	}
+}
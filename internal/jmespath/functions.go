@@ -0,0 +1,387 @@
+package jmespath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type argType int
+
+const (
+	jpAny argType = iota
+	jpNumber
+	jpString
+	jpBoolean
+	jpArray
+	jpObject
+	jpExpref
+	jpArrayNumber
+	jpArrayString
+)
+
+var argTypeNames = map[argType]string{
+	jpAny:         "any",
+	jpNumber:      "number",
+	jpString:      "string",
+	jpBoolean:     "boolean",
+	jpArray:       "array",
+	jpObject:      "object",
+	jpExpref:      "expref",
+	jpArrayNumber: "array[number]",
+	jpArrayString: "array[string]",
+}
+
+func (t argType) matches(v any) bool {
+	switch t {
+	case jpAny:
+		_, isRef := v.(exprRef)
+		return !isRef
+	case jpNumber:
+		_, ok := toNumber(v)
+		return ok
+	case jpString:
+		_, ok := v.(string)
+		return ok
+	case jpBoolean:
+		_, ok := v.(bool)
+		return ok
+	case jpArray:
+		_, ok := v.([]any)
+		return ok
+	case jpObject:
+		_, ok := v.(map[string]any)
+		return ok
+	case jpExpref:
+		_, ok := v.(exprRef)
+		return ok
+	case jpArrayNumber, jpArrayString:
+		a, ok := v.([]any)
+		if !ok {
+			return false
+		}
+		elem := jpNumber
+		if t == jpArrayString {
+			elem = jpString
+		}
+		for _, e := range a {
+			if !elem.matches(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// function is the signature and the implementation of the function.
+type function struct {
+	// the types accepted by each argument
+	args [][]argType
+	// the last argument can be repeated
+	variadic bool
+	handler  func(e *Expression, args []any) (any, error)
+}
+
+func (f *function) checkArity(n int) error {
+	if f.variadic {
+		if n < len(f.args) {
+			return fmt.Errorf("%w: expected at least %d arguments, but got %d", ErrInvalidArity, len(f.args), n)
+		}
+		return nil
+	}
+	if n != len(f.args) {
+		return fmt.Errorf("%w: expected %d arguments, but got %d", ErrInvalidArity, len(f.args), n)
+	}
+	return nil
+}
+
+func (f *function) call(e *Expression, args []any) (any, error) {
+	for i, a := range args {
+		types := f.args[min(i, len(f.args)-1)]
+		ok := false
+		for _, t := range types {
+			ok = ok || t.matches(a)
+		}
+		if !ok {
+			names := make([]string, 0, len(types))
+			for _, t := range types {
+				names = append(names, argTypeNames[t])
+			}
+			return nil, fmt.Errorf("%w: argument %d must be %s, but got %s", ErrInvalidType, i+1, strings.Join(names, " or "), typeOf(a))
+		}
+	}
+	return f.handler(e, args)
+}
+
+func customFunction(fn Function) *function {
+	args := make([][]argType, fn.Arity)
+	for i := range args {
+		args[i] = []argType{jpAny}
+	}
+	return &function{
+		args: args,
+		handler: func(_ *Expression, args []any) (any, error) {
+			return fn.Call(args)
+		},
+	}
+}
+
+func sig(types ...argType) []argType {
+	return types
+}
+
+var builtins = map[string]*function{
+	"abs": {args: [][]argType{sig(jpNumber)}, handler: func(_ *Expression, args []any) (any, error) {
+		n, _ := toNumber(args[0])
+		return math.Abs(n), nil
+	}},
+	"avg": {args: [][]argType{sig(jpArrayNumber)}, handler: func(_ *Expression, args []any) (any, error) {
+		a := args[0].([]any)
+		if len(a) == 0 {
+			return nil, nil
+		}
+		return sum(a) / float64(len(a)), nil
+	}},
+	"ceil": {args: [][]argType{sig(jpNumber)}, handler: func(_ *Expression, args []any) (any, error) {
+		n, _ := toNumber(args[0])
+		return math.Ceil(n), nil
+	}},
+	"contains": {args: [][]argType{sig(jpArray, jpString), sig(jpAny)}, handler: func(_ *Expression, args []any) (any, error) {
+		if s, ok := args[0].(string); ok {
+			sub, ok := args[1].(string)
+			return ok && strings.Contains(s, sub), nil
+		}
+		for _, v := range args[0].([]any) {
+			if equal(v, args[1]) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}},
+	"ends_with": {args: [][]argType{sig(jpString), sig(jpString)}, handler: func(_ *Expression, args []any) (any, error) {
+		return strings.HasSuffix(args[0].(string), args[1].(string)), nil
+	}},
+	"floor": {args: [][]argType{sig(jpNumber)}, handler: func(_ *Expression, args []any) (any, error) {
+		n, _ := toNumber(args[0])
+		return math.Floor(n), nil
+	}},
+	"join": {args: [][]argType{sig(jpString), sig(jpArrayString)}, handler: func(_ *Expression, args []any) (any, error) {
+		a := args[1].([]any)
+		s := make([]string, 0, len(a))
+		for _, v := range a {
+			s = append(s, v.(string))
+		}
+		return strings.Join(s, args[0].(string)), nil
+	}},
+	"keys": {args: [][]argType{sig(jpObject)}, handler: func(_ *Expression, args []any) (any, error) {
+		keys := []any{}
+		for _, k := range objectKeys(args[0].(map[string]any)) {
+			keys = append(keys, k)
+		}
+		return keys, nil
+	}},
+	"length": {args: [][]argType{sig(jpString, jpArray, jpObject)}, handler: func(_ *Expression, args []any) (any, error) {
+		switch v := args[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(v)), nil
+		case []any:
+			return float64(len(v)), nil
+		}
+		return float64(len(args[0].(map[string]any))), nil
+	}},
+	"map": {args: [][]argType{sig(jpExpref), sig(jpArray)}, handler: func(e *Expression, args []any) (any, error) {
+		ref := args[0].(exprRef)
+		mapped := []any{}
+		for _, v := range args[1].([]any) {
+			r, err := e.eval(ref.n, v)
+			if err != nil {
+				return nil, err
+			}
+			mapped = append(mapped, r)
+		}
+		return mapped, nil
+	}},
+	"max": {args: [][]argType{sig(jpArrayNumber, jpArrayString)}, handler: func(_ *Expression, args []any) (any, error) {
+		return extreme(args[0].([]any), 1)
+	}},
+	"max_by": {args: [][]argType{sig(jpArray), sig(jpExpref)}, handler: func(e *Expression, args []any) (any, error) {
+		return extremeBy(e, args[0].([]any), args[1].(exprRef), 1)
+	}},
+	"merge": {args: [][]argType{sig(jpObject)}, variadic: true, handler: func(_ *Expression, args []any) (any, error) {
+		merged := map[string]any{}
+		for _, a := range args {
+			for k, v := range a.(map[string]any) {
+				merged[k] = v
+			}
+		}
+		return merged, nil
+	}},
+	"min": {args: [][]argType{sig(jpArrayNumber, jpArrayString)}, handler: func(_ *Expression, args []any) (any, error) {
+		return extreme(args[0].([]any), -1)
+	}},
+	"min_by": {args: [][]argType{sig(jpArray), sig(jpExpref)}, handler: func(e *Expression, args []any) (any, error) {
+		return extremeBy(e, args[0].([]any), args[1].(exprRef), -1)
+	}},
+	"not_null": {args: [][]argType{sig(jpAny)}, variadic: true, handler: func(_ *Expression, args []any) (any, error) {
+		for _, a := range args {
+			if a != nil {
+				return a, nil
+			}
+		}
+		return nil, nil
+	}},
+	"reverse": {args: [][]argType{sig(jpString, jpArray)}, handler: func(_ *Expression, args []any) (any, error) {
+		if s, ok := args[0].(string); ok {
+			r := []rune(s)
+			for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+				r[i], r[j] = r[j], r[i]
+			}
+			return string(r), nil
+		}
+		a := args[0].([]any)
+		reversed := make([]any, 0, len(a))
+		for i := len(a) - 1; i >= 0; i-- {
+			reversed = append(reversed, a[i])
+		}
+		return reversed, nil
+	}},
+	"sort": {args: [][]argType{sig(jpArrayNumber, jpArrayString)}, handler: func(_ *Expression, args []any) (any, error) {
+		sorted := append([]any{}, args[0].([]any)...)
+		sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+		return sorted, nil
+	}},
+	"sort_by": {args: [][]argType{sig(jpArray), sig(jpExpref)}, handler: func(e *Expression, args []any) (any, error) {
+		a := args[0].([]any)
+		keys, err := sortKeys(e, a, args[1].(exprRef))
+		if err != nil {
+			return nil, err
+		}
+		indexes := make([]int, len(a))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		sort.SliceStable(indexes, func(i, j int) bool { return less(keys[indexes[i]], keys[indexes[j]]) })
+		sorted := make([]any, 0, len(a))
+		for _, i := range indexes {
+			sorted = append(sorted, a[i])
+		}
+		return sorted, nil
+	}},
+	"starts_with": {args: [][]argType{sig(jpString), sig(jpString)}, handler: func(_ *Expression, args []any) (any, error) {
+		return strings.HasPrefix(args[0].(string), args[1].(string)), nil
+	}},
+	"sum": {args: [][]argType{sig(jpArrayNumber)}, handler: func(_ *Expression, args []any) (any, error) {
+		return sum(args[0].([]any)), nil
+	}},
+	"to_array": {args: [][]argType{sig(jpAny)}, handler: func(_ *Expression, args []any) (any, error) {
+		if a, ok := args[0].([]any); ok {
+			return a, nil
+		}
+		return []any{args[0]}, nil
+	}},
+	"to_number": {args: [][]argType{sig(jpAny)}, handler: func(_ *Expression, args []any) (any, error) {
+		if n, ok := toNumber(args[0]); ok {
+			return n, nil
+		}
+		if s, ok := args[0].(string); ok {
+			if n, err := strconv.ParseFloat(s, 64); err == nil {
+				return n, nil
+			}
+		}
+		return nil, nil
+	}},
+	"to_string": {args: [][]argType{sig(jpAny)}, handler: func(_ *Expression, args []any) (any, error) {
+		if s, ok := args[0].(string); ok {
+			return s, nil
+		}
+		return marshal(args[0])
+	}},
+	"type": {args: [][]argType{sig(jpAny)}, handler: func(_ *Expression, args []any) (any, error) {
+		return typeOf(args[0]), nil
+	}},
+	"values": {args: [][]argType{sig(jpObject)}, handler: func(_ *Expression, args []any) (any, error) {
+		return objectValues(args[0].(map[string]any)), nil
+	}},
+}
+
+func sum(a []any) float64 {
+	s := 0.0
+	for _, v := range a {
+		n, _ := toNumber(v)
+		s += n
+	}
+	return s
+}
+
+// less compares two numbers or two strings.
+func less(a, b any) bool {
+	if an, ok := toNumber(a); ok {
+		bn, _ := toNumber(b)
+		return an < bn
+	}
+	return a.(string) < b.(string)
+}
+
+// extreme returns the max (sign 1) or the min (sign -1) of the numbers or the strings.
+func extreme(a []any, sign int) (any, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+	result := a[0]
+	for _, v := range a[1:] {
+		if (sign > 0 && less(result, v)) || (sign < 0 && less(v, result)) {
+			result = v
+		}
+	}
+	return result, nil
+}
+
+func extremeBy(e *Expression, a []any, ref exprRef, sign int) (any, error) {
+	keys, err := sortKeys(e, a, ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(a) == 0 {
+		return nil, nil
+	}
+	i := 0
+	for j := 1; j < len(a); j++ {
+		if (sign > 0 && less(keys[i], keys[j])) || (sign < 0 && less(keys[j], keys[i])) {
+			i = j
+		}
+	}
+	return a[i], nil
+}
+
+// sortKeys evaluates ref for each element, that must result in all numbers or all strings.
+func sortKeys(e *Expression, a []any, ref exprRef) ([]any, error) {
+	keys := make([]any, 0, len(a))
+	for _, v := range a {
+		k, err := e.eval(ref.n, v)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) > 0 && !jpArrayNumber.matches(keys) && !jpArrayString.matches(keys) {
+		return nil, fmt.Errorf("%w: expression must result in all numbers or all strings", ErrInvalidType)
+	}
+	return keys, nil
+}
+
+// marshal encodes v as JSON without escaping HTML characters.
+func marshal(v any) (string, error) {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
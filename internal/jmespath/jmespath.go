@@ -0,0 +1,459 @@
+// Package jmespath implements JMESPath, the query language for JSON.
+//
+// document: https://jmespath.org/specification.html
+package jmespath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrSyntax is returned when the expression is invalid.
+	ErrSyntax = errors.New("syntax error")
+
+	// ErrUnknownFunction is returned when the expression calls the function that does not exist.
+	ErrUnknownFunction = errors.New("unknown function")
+
+	// ErrInvalidArity is returned when the function is called with the wrong number of arguments.
+	ErrInvalidArity = errors.New("invalid arity")
+
+	// ErrInvalidType is returned when the function is called with the argument of the wrong type.
+	ErrInvalidType = errors.New("invalid type")
+)
+
+func syntaxError(pos int, msg string) error {
+	return fmt.Errorf("%w at %d: %s", ErrSyntax, pos, msg)
+}
+
+// Function is the custom function that can be called from the expression.
+type Function struct {
+	// The number of the arguments.
+	Arity int
+
+	// Call is called with the evaluated arguments.
+	Call func(args []any) (any, error)
+}
+
+// Expression is the compiled JMESPath expression.
+type Expression struct {
+	ast   *node
+	funcs map[string]*function
+}
+
+// Compile compiles the expression with the built-in functions and the custom functions.
+// The custom functions cannot have the same names as the built-in functions.
+func Compile(expr string, funcs map[string]Function) (*Expression, error) {
+	all := make(map[string]*function, len(builtins)+len(funcs))
+	for name, fn := range builtins {
+		all[name] = fn
+	}
+	for name, fn := range funcs {
+		if _, ok := all[name]; ok {
+			return nil, fmt.Errorf("function %s() is already defined", name)
+		}
+		all[name] = customFunction(fn)
+	}
+
+	ast, err := parse(expr, all)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expression{ast: ast, funcs: all}, nil
+}
+
+// Search compiles the expression without custom functions and evaluates it against data.
+func Search(expr string, data any) (any, error) {
+	e, err := Compile(expr, nil)
+	if err != nil {
+		return nil, err
+	}
+	return e.Search(data)
+}
+
+// Search evaluates the expression against data, that is the value decoded from JSON.
+// The numbers in data can be float64 or json.Number, and the numbers returned by the functions are float64.
+func (e *Expression) Search(data any) (any, error) {
+	return e.eval(e.ast, data)
+}
+
+// exprRef is the value of the expression reference (&expr), that is passed to the functions.
+type exprRef struct {
+	n *node
+}
+
+func (e *Expression) eval(n *node, data any) (any, error) {
+	switch n.typ {
+	case nodeCurrent:
+		return data, nil
+	case nodeLiteral:
+		return n.value, nil
+	case nodeField:
+		if m, ok := data.(map[string]any); ok {
+			return m[n.value.(string)], nil
+		}
+		return nil, nil
+	case nodeSubexpr, nodePipe:
+		left, err := e.eval(n.children[0], data)
+		if err != nil {
+			return nil, err
+		}
+		return e.eval(n.children[1], left)
+	case nodeIndexExpr:
+		left, err := e.eval(n.children[0], data)
+		if err != nil {
+			return nil, err
+		}
+		return e.eval(n.children[1], left)
+	case nodeIndex:
+		a, ok := data.([]any)
+		if !ok {
+			return nil, nil
+		}
+		i := n.value.(int)
+		if i < 0 {
+			i += len(a)
+		}
+		if i < 0 || i >= len(a) {
+			return nil, nil
+		}
+		return a[i], nil
+	case nodeSlice:
+		a, ok := data.([]any)
+		if !ok {
+			return nil, nil
+		}
+		return sliceArray(a, n.value.(slice)), nil
+	case nodeProjection:
+		left, err := e.eval(n.children[0], data)
+		if err != nil {
+			return nil, err
+		}
+		a, ok := left.([]any)
+		if !ok {
+			return nil, nil
+		}
+		return e.project(a, n.children[1])
+	case nodeValueProjection:
+		left, err := e.eval(n.children[0], data)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := left.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		return e.project(objectValues(m), n.children[1])
+	case nodeFlatten:
+		left, err := e.eval(n.children[0], data)
+		if err != nil {
+			return nil, err
+		}
+		a, ok := left.([]any)
+		if !ok {
+			return nil, nil
+		}
+		flattened := []any{}
+		for _, v := range a {
+			if inner, ok := v.([]any); ok {
+				flattened = append(flattened, inner...)
+			} else {
+				flattened = append(flattened, v)
+			}
+		}
+		return flattened, nil
+	case nodeFilterProjection:
+		left, err := e.eval(n.children[0], data)
+		if err != nil {
+			return nil, err
+		}
+		a, ok := left.([]any)
+		if !ok {
+			return nil, nil
+		}
+		filtered := []any{}
+		for _, v := range a {
+			cond, err := e.eval(n.children[2], v)
+			if err != nil {
+				return nil, err
+			}
+			if truthy(cond) {
+				filtered = append(filtered, v)
+			}
+		}
+		return e.project(filtered, n.children[1])
+	case nodeComparator:
+		left, err := e.eval(n.children[0], data)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.eval(n.children[1], data)
+		if err != nil {
+			return nil, err
+		}
+		return compare(n.value.(tokenType), left, right), nil
+	case nodeOr, nodeAnd:
+		left, err := e.eval(n.children[0], data)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(left) == (n.typ == nodeOr) {
+			return left, nil
+		}
+		return e.eval(n.children[1], data)
+	case nodeNot:
+		v, err := e.eval(n.children[0], data)
+		if err != nil {
+			return nil, err
+		}
+		return !truthy(v), nil
+	case nodeMultiSelectList:
+		if data == nil {
+			return nil, nil
+		}
+		list := make([]any, 0, len(n.children))
+		for _, c := range n.children {
+			v, err := e.eval(c, data)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case nodeMultiSelectHash:
+		if data == nil {
+			return nil, nil
+		}
+		keys := n.value.([]string)
+		hash := make(map[string]any, len(keys))
+		for i, c := range n.children {
+			v, err := e.eval(c, data)
+			if err != nil {
+				return nil, err
+			}
+			hash[keys[i]] = v
+		}
+		return hash, nil
+	case nodeExpref:
+		return exprRef{n: n.children[0]}, nil
+	case nodeFunction:
+		args := make([]any, 0, len(n.children))
+		for _, c := range n.children {
+			v, err := e.eval(c, data)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, v)
+		}
+		name := n.value.(string)
+		v, err := e.funcs[name].call(e, args)
+		if err != nil {
+			return nil, fmt.Errorf("%s(): %w", name, err)
+		}
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("unknown node type %d", n.typ)
+}
+
+// project evaluates n for each element, and returns the results that are not null.
+func (e *Expression) project(a []any, n *node) (any, error) {
+	projected := []any{}
+	for _, v := range a {
+		r, err := e.eval(n, v)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			projected = append(projected, r)
+		}
+	}
+	return projected, nil
+}
+
+func sliceArray(a []any, s slice) []any {
+	length := len(a)
+	start, stop := 0, length
+	if s.step < 0 {
+		start, stop = length-1, -1
+	}
+	if s.start != nil {
+		start = capSlice(length, *s.start, s.step)
+	}
+	if s.stop != nil {
+		stop = capSlice(length, *s.stop, s.step)
+	}
+
+	sliced := []any{}
+	if s.step > 0 {
+		for i := start; i < stop; i += s.step {
+			sliced = append(sliced, a[i])
+		}
+	} else {
+		for i := start; i > stop; i += s.step {
+			sliced = append(sliced, a[i])
+		}
+	}
+	return sliced
+}
+
+func capSlice(length, i, step int) int {
+	if i < 0 {
+		i += length
+		if i < 0 {
+			if step < 0 {
+				return -1
+			}
+			return 0
+		}
+		return i
+	}
+	if i >= length {
+		if step < 0 {
+			return length - 1
+		}
+		return length
+	}
+	return i
+}
+
+// objectValues returns the values of the object in the order of the keys.
+func objectValues(m map[string]any) []any {
+	keys := objectKeys(m)
+	values := make([]any, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, m[k])
+	}
+	return values
+}
+
+func objectKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// truthy reports whether v is true in JMESPath.
+// false, null, empty strings, empty arrays and empty objects are false.
+func truthy(v any) bool {
+	switch vv := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return vv
+	case string:
+		return vv != ""
+	case []any:
+		return len(vv) > 0
+	case map[string]any:
+		return len(vv) > 0
+	}
+	return true
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// compare returns the result of the comparator. The ordering comparators return null for non-numbers.
+func compare(op tokenType, left, right any) any {
+	switch op {
+	case tEQ:
+		return equal(left, right)
+	case tNE:
+		return !equal(left, right)
+	}
+
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	if !lok || !rok {
+		return nil
+	}
+	switch op {
+	case tLT:
+		return l < r
+	case tLTE:
+		return l <= r
+	case tGT:
+		return l > r
+	}
+	return l >= r
+}
+
+func equal(a, b any) bool {
+	if an, ok := toNumber(a); ok {
+		bn, ok := toNumber(b)
+		return ok && an == bn
+	}
+
+	switch av := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		bv, ok := b.(bool)
+		return ok && av == bv
+	case string:
+		bv, ok := b.(string)
+		return ok && av == bv
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			w, ok := bv[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// typeOf returns the JMESPath type of v.
+func typeOf(v any) string {
+	if _, ok := toNumber(v); ok {
+		return "number"
+	}
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case exprRef:
+		return "expref"
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", v), "*")
+}
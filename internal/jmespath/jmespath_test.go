@@ -0,0 +1,260 @@
+package jmespath_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/internal/jmespath"
+	"github.com/stretchr/testify/assert"
+)
+
+func decode(t *testing.T, s string) any {
+	var v any
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+const people = `{
+  "people": [
+    {"name": "b", "age": 30, "tags": ["x"]},
+    {"name": "a", "age": 50, "tags": ["y", "z"]},
+    {"name": "c", "age": 40}
+  ],
+  "owner": {"name": "alice", "age": 20}
+}`
+
+func Test_Search(t *testing.T) {
+	cases := []struct {
+		name   string
+		data   string
+		expr   string
+		expect string
+	}{
+		// identifiers and sub-expressions
+		{name: "ok: field", data: `{"a":"foo"}`, expr: `a`, expect: `"foo"`},
+		{name: "ok: missing field", data: `{"a":"foo"}`, expr: `b`, expect: `null`},
+		{name: "ok: field of non-object", data: `["a"]`, expr: `a`, expect: `null`},
+		{name: "ok: sub-expression", data: `{"a":{"b":{"c":"v"}}}`, expr: `a.b.c`, expect: `"v"`},
+		{name: "ok: sub-expression of null", data: `{"a":null}`, expr: `a.b.c`, expect: `null`},
+		{name: "ok: quoted identifier", data: `{"foo bar":{"détail":1}}`, expr: `"foo bar"."détail"`, expect: `1`},
+		{name: "ok: current node", data: `{"a":1}`, expr: `@`, expect: `{"a":1}`},
+
+		// index and slice
+		{name: "ok: index", data: `["a","b","c"]`, expr: `[1]`, expect: `"b"`},
+		{name: "ok: negative index", data: `["a","b","c"]`, expr: `[-1]`, expect: `"c"`},
+		{name: "ok: index out of range", data: `["a","b","c"]`, expr: `[3]`, expect: `null`},
+		{name: "ok: index of field", data: `{"a":[{"b":1},{"b":2}]}`, expr: `a[1].b`, expect: `2`},
+		{name: "ok: slice", data: `[0,1,2,3,4,5]`, expr: `[1:3]`, expect: `[1,2]`},
+		{name: "ok: slice with step", data: `[0,1,2,3,4,5]`, expr: `[::2]`, expect: `[0,2,4]`},
+		{name: "ok: slice reversed", data: `[0,1,2,3,4,5]`, expr: `[::-1]`, expect: `[5,4,3,2,1,0]`},
+		{name: "ok: slice with negative bounds", data: `[0,1,2,3,4,5]`, expr: `[-2:]`, expect: `[4,5]`},
+		{name: "ok: slice out of range", data: `[0,1,2]`, expr: `[5:10]`, expect: `[]`},
+		{name: "ok: slice is projection", data: `{"a":[{"b":1},{"b":2},{"b":3}]}`, expr: `a[:2].b`, expect: `[1,2]`},
+
+		// projections
+		{name: "ok: list projection", data: people, expr: `people[*].name`, expect: `["b","a","c"]`},
+		{name: "ok: list projection skips null", data: people, expr: `people[*].tags`, expect: `[["x"],["y","z"]]`},
+		{name: "ok: nested projection", data: people, expr: `people[*].tags[0]`, expect: `["x","y"]`},
+		{name: "ok: projection of non-array", data: `{"a":{"b":1}}`, expr: `a[*].b`, expect: `null`},
+		{name: "ok: object projection", data: `{"a":{"x":{"v":1},"y":{"v":2},"z":{"w":3}}}`, expr: `a.*.v`, expect: `[1,2]`},
+		{name: "ok: flatten", data: `[[1,2],3,[4,[5]]]`, expr: `[]`, expect: `[1,2,3,4,[5]]`},
+		{name: "ok: flatten projection", data: people, expr: `people[].tags[]`, expect: `["x","y","z"]`},
+		{name: "ok: pipe stops projection", data: people, expr: `people[*].name | [0]`, expect: `"b"`},
+		{name: "ok: projection not stopped", data: people, expr: `people[*].tags[0]`, expect: `["x","y"]`},
+
+		// filters
+		{name: "ok: filter", data: people, expr: `people[?age > ` + "`35`" + `].name`, expect: `["a","c"]`},
+		{name: "ok: filter with equality", data: people, expr: `people[?name == 'a'].age`, expect: `[50]`},
+		{name: "ok: filter with and", data: people, expr: `people[?age >= ` + "`30`" + ` && tags].name`, expect: `["b","a"]`},
+		{name: "ok: filter with not", data: people, expr: `people[?!tags].name`, expect: `["c"]`},
+		{name: "ok: filter then flatten", data: `{"a":[[1],[2,3]]}`, expr: `a[?length(@) > ` + "`1`" + `][]`, expect: `[2,3]`},
+		{name: "ok: filter of non-array", data: `{"a":"b"}`, expr: `a[?b]`, expect: `null`},
+
+		// comparators and boolean
+		{name: "ok: equality of objects", data: `{"a":{"x":[1,2]},"b":{"x":[1.0,2]}}`, expr: `a == b`, expect: `true`},
+		{name: "ok: inequality", data: `{"a":"x","b":1}`, expr: `a != b`, expect: `true`},
+		{name: "ok: ordering of non-numbers", data: `{"a":"x","b":"y"}`, expr: `a < b`, expect: `null`},
+		{name: "ok: less or equal", data: `{"a":1,"b":1}`, expr: `a <= b`, expect: `true`},
+		{name: "ok: or", data: `{"a":null,"b":"x"}`, expr: `a || b`, expect: `"x"`},
+		{name: "ok: or with truthy left", data: `{"a":[1],"b":"x"}`, expr: `a || b`, expect: `[1]`},
+		{name: "ok: and", data: `{"a":"","b":"x"}`, expr: `a && b`, expect: `""`},
+		{name: "ok: not of empty object", data: `{"a":{}}`, expr: `!a`, expect: `true`},
+		{name: "ok: parenthesized", data: `{"a":false,"b":true,"c":false}`, expr: `(a || b) && c`, expect: `false`},
+
+		// multi-select
+		{name: "ok: multi-select list", data: `{"a":1,"b":2}`, expr: `[a, b, c]`, expect: `[1,2,null]`},
+		{name: "ok: multi-select hash", data: `{"a":1,"b":{"c":2}}`, expr: `{x: a, "y z": b.c}`, expect: `{"x":1,"y z":2}`},
+		{name: "ok: multi-select of null", data: `{"a":null}`, expr: `a.[b]`, expect: `null`},
+		{name: "ok: multi-select in projection", data: people, expr: `people[*].{n: name, a: age}`, expect: `[{"n":"b","a":30},{"n":"a","a":50},{"n":"c","a":40}]`},
+
+		// literals
+		{name: "ok: JSON literal", data: `{}`, expr: "`{\"a\": [1, true]}`", expect: `{"a":[1,true]}`},
+		{name: "ok: JSON literal with escaped backtick", data: `{}`, expr: "`\"a\\`b\"`", expect: "\"a`b\""},
+		{name: "ok: raw string", data: `{}`, expr: `'it\'s'`, expect: `"it's"`},
+
+		// functions
+		{name: "ok: abs", data: `{"a":-1.5}`, expr: `abs(a)`, expect: `1.5`},
+		{name: "ok: avg", data: `[1,2,3]`, expr: `avg(@)`, expect: `2`},
+		{name: "ok: avg of empty array", data: `[]`, expr: `avg(@)`, expect: `null`},
+		{name: "ok: ceil and floor", data: `{"a":1.5}`, expr: `[ceil(a), floor(a)]`, expect: `[2,1]`},
+		{name: "ok: contains of array", data: `[1,"a"]`, expr: `contains(@, 'a')`, expect: `true`},
+		{name: "ok: contains of string", data: `"foobar"`, expr: `contains(@, 'oba')`, expect: `true`},
+		{name: "ok: starts_with and ends_with", data: `"foobar"`, expr: `[starts_with(@, 'foo'), ends_with(@, 'foo')]`, expect: `[true,false]`},
+		{name: "ok: join", data: `["a","b"]`, expr: `join(', ', @)`, expect: `"a, b"`},
+		{name: "ok: keys and values", data: `{"b":2,"a":1}`, expr: `[keys(@), values(@)]`, expect: `[["a","b"],[1,2]]`},
+		{name: "ok: length", data: `{"s":"日本語","a":[1,2],"o":{"k":1}}`, expr: `[length(s), length(a), length(o)]`, expect: `[3,2,1]`},
+		{name: "ok: map", data: people, expr: `map(&tags[0], people)`, expect: `["x","y",null]`},
+		{name: "ok: max and min", data: `[3,1,2]`, expr: `[max(@), min(@)]`, expect: `[3,1]`},
+		{name: "ok: max of strings", data: `["b","c","a"]`, expr: `max(@)`, expect: `"c"`},
+		{name: "ok: max of empty array", data: `[]`, expr: `max(@)`, expect: `null`},
+		{name: "ok: max_by and min_by", data: people, expr: `[max_by(people, &age).name, min_by(people, &age).name]`, expect: `["a","b"]`},
+		{name: "ok: merge", data: `{"a":{"x":1,"y":1},"b":{"y":2}}`, expr: `merge(a, b, ` + "`{}`" + `)`, expect: `{"x":1,"y":2}`},
+		{name: "ok: not_null", data: `{"b":2}`, expr: `not_null(a, b)`, expect: `2`},
+		{name: "ok: reverse", data: `{"s":"abc","a":[1,2]}`, expr: `[reverse(s), reverse(a)]`, expect: `["cba",[2,1]]`},
+		{name: "ok: sort", data: `["b","a","c"]`, expr: `sort(@)`, expect: `["a","b","c"]`},
+		{name: "ok: sort_by", data: people, expr: `sort_by(people, &age)[*].name`, expect: `["b","c","a"]`},
+		{name: "ok: sort_by of strings", data: people, expr: `sort_by(people, &name)[*].age`, expect: `[50,30,40]`},
+		{name: "ok: sum", data: `[1,2,3.5]`, expr: `sum(@)`, expect: `6.5`},
+		{name: "ok: to_array", data: `{"a":1,"b":[1]}`, expr: `[to_array(a), to_array(b)]`, expect: `[[1],[1]]`},
+		{name: "ok: to_number", data: `{"a":"1.5","b":"x","c":2}`, expr: `[to_number(a), to_number(b), to_number(c)]`, expect: `[1.5,null,2]`},
+		{name: "ok: to_string", data: `{"a":"x","b":{"c":"<d>"}}`, expr: `[to_string(a), to_string(b)]`, expect: `["x","{\"c\":\"<d>\"}"]`},
+		{name: "ok: type", data: `{"a":1,"b":"x","c":true,"d":[],"e":{},"f":null}`, expr: `[type(a), type(b), type(c), type(d), type(e), type(f)]`, expect: `["number","string","boolean","array","object","null"]`},
+		{name: "ok: function in sub-expression", data: people, expr: `people[*].length(name)`, expect: `[1,1,1]`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := jmespath.Search(c.expr, decode(tt, c.data))
+			asst.NoError(err)
+
+			b, err := json.Marshal(out)
+			asst.NoError(err)
+			asst.JSONEq(c.expect, string(b))
+		})
+	}
+}
+
+func Test_Search_error(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    string
+		expr    string
+		wantErr error
+	}{
+		{name: "ng: empty", expr: ``, wantErr: jmespath.ErrSyntax},
+		{name: "ng: trailing dot", expr: `a.`, wantErr: jmespath.ErrSyntax},
+		{name: "ng: unclosed bracket", expr: `a[0`, wantErr: jmespath.ErrSyntax},
+		{name: "ng: single equal", expr: `a = b`, wantErr: jmespath.ErrSyntax},
+		{name: "ng: unclosed quote", expr: `"a`, wantErr: jmespath.ErrSyntax},
+		{name: "ng: invalid literal", expr: "`{a}`", wantErr: jmespath.ErrSyntax},
+		{name: "ng: unexpected character", expr: `a#b`, wantErr: jmespath.ErrSyntax},
+		{name: "ng: slice step 0", expr: `[::0]`, wantErr: jmespath.ErrSyntax},
+		{name: "ng: index after dot", expr: `a.[0]`, wantErr: jmespath.ErrSyntax},
+		{name: "ng: trailing token", expr: `a b`, wantErr: jmespath.ErrSyntax},
+		{name: "ng: empty multi-select hash", expr: `{}`, wantErr: jmespath.ErrSyntax},
+		{name: "ng: quoted function name", expr: `"length"(@)`, wantErr: jmespath.ErrSyntax},
+		{name: "ng: unknown function", expr: `foo(@)`, wantErr: jmespath.ErrUnknownFunction},
+		{name: "ng: arity", expr: `length(a, b)`, wantErr: jmespath.ErrInvalidArity},
+		{name: "ng: variadic arity", expr: `merge()`, wantErr: jmespath.ErrInvalidArity},
+		{name: "ng: type of argument", data: `{"a":1}`, expr: `length(a)`, wantErr: jmespath.ErrInvalidType},
+		{name: "ng: type of array elements", data: `[1,"a"]`, expr: `sort(@)`, wantErr: jmespath.ErrInvalidType},
+		{name: "ng: expref for any", data: `{}`, expr: `type(&a)`, wantErr: jmespath.ErrInvalidType},
+		{name: "ng: sort_by keys of mixed types", data: `[{"a":1},{"a":"x"}]`, expr: `sort_by(@, &a)`, wantErr: jmespath.ErrInvalidType},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			var data any
+			if c.data != "" {
+				data = decode(tt, c.data)
+			}
+
+			out, err := jmespath.Search(c.expr, data)
+			asst.ErrorIs(err, c.wantErr)
+			asst.Nil(out)
+		})
+	}
+}
+
+func Test_Compile(t *testing.T) {
+	upper := jmespath.Function{
+		Arity: 1,
+		Call: func(args []any) (any, error) {
+			s, ok := args[0].(string)
+			if !ok {
+				return nil, errors.New("not a string")
+			}
+			return strings.ToUpper(s), nil
+		},
+	}
+
+	cases := []struct {
+		name    string
+		expr    string
+		funcs   map[string]jmespath.Function
+		data    string
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:   "ok: custom function",
+			expr:   `a[*].upper(@)`,
+			funcs:  map[string]jmespath.Function{"upper": upper},
+			data:   `{"a":["x","y"]}`,
+			expect: `["X","Y"]`,
+		},
+		{
+			name:    "ng: error of custom function",
+			expr:    `upper(a)`,
+			funcs:   map[string]jmespath.Function{"upper": upper},
+			data:    `{"a":1}`,
+			wantErr: true,
+		},
+		{
+			name:    "ng: arity of custom function",
+			expr:    `upper(a, b)`,
+			funcs:   map[string]jmespath.Function{"upper": upper},
+			data:    `{}`,
+			wantErr: true,
+		},
+		{
+			name:    "ng: custom function overrides built-in function",
+			expr:    `length(a)`,
+			funcs:   map[string]jmespath.Function{"length": upper},
+			data:    `{}`,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			e, err := jmespath.Compile(c.expr, c.funcs)
+			if err == nil {
+				var out any
+				out, err = e.Search(decode(tt, c.data))
+				if !c.wantErr {
+					asst.NoError(err)
+					b, err := json.Marshal(out)
+					asst.NoError(err)
+					asst.JSONEq(c.expect, string(b))
+					return
+				}
+			}
+
+			asst.True(c.wantErr)
+			asst.Error(err)
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package jmespath
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenType int
+
+const (
+	tEOF tokenType = iota
+	tUnquotedIdentifier
+	tQuotedIdentifier
+	tRawString
+	tJSONLiteral
+	tNumber
+	tDot
+	tStar
+	tLbracket
+	tRbracket
+	tLbrace
+	tRbrace
+	tLparen
+	tRparen
+	tComma
+	tColon
+	tCurrent
+	tExpref
+	tPipe
+	tOr
+	tAnd
+	tNot
+	tEQ
+	tNE
+	tLT
+	tLTE
+	tGT
+	tGTE
+	tFilter
+	tFlatten
+)
+
+var tokenNames = map[tokenType]string{
+	tEOF:                "end of expression",
+	tUnquotedIdentifier: "identifier",
+	tQuotedIdentifier:   "quoted identifier",
+	tRawString:          "raw string",
+	tJSONLiteral:        "literal",
+	tNumber:             "number",
+	tDot:                `"."`,
+	tStar:               `"*"`,
+	tLbracket:           `"["`,
+	tRbracket:           `"]"`,
+	tLbrace:             `"{"`,
+	tRbrace:             `"}"`,
+	tLparen:             `"("`,
+	tRparen:             `")"`,
+	tComma:              `","`,
+	tColon:              `":"`,
+	tCurrent:            `"@"`,
+	tExpref:             `"&"`,
+	tPipe:               `"|"`,
+	tOr:                 `"||"`,
+	tAnd:                `"&&"`,
+	tNot:                `"!"`,
+	tEQ:                 `"=="`,
+	tNE:                 `"!="`,
+	tLT:                 `"<"`,
+	tLTE:                `"<="`,
+	tGT:                 `">"`,
+	tGTE:                `">="`,
+	tFilter:             `"[?"`,
+	tFlatten:            `"[]"`,
+}
+
+func (t tokenType) String() string {
+	return tokenNames[t]
+}
+
+type token struct {
+	typ   tokenType
+	value any // string for identifiers and raw strings, int for numbers, decoded value for literals
+	pos   int
+}
+
+var simpleTokens = map[byte]tokenType{
+	'.': tDot,
+	'*': tStar,
+	']': tRbracket,
+	'{': tLbrace,
+	'}': tRbrace,
+	'(': tLparen,
+	')': tRparen,
+	',': tComma,
+	':': tColon,
+	'@': tCurrent,
+}
+
+// tokenize splits the expression into the tokens, that ends with tEOF.
+func tokenize(expr string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		start := i
+
+		if tt, ok := simpleTokens[c]; ok {
+			tokens = append(tokens, token{typ: tt, pos: start})
+			i++
+			continue
+		}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '[':
+			switch {
+			case strings.HasPrefix(expr[i:], "[]"):
+				tokens = append(tokens, token{typ: tFlatten, pos: start})
+				i += 2
+			case strings.HasPrefix(expr[i:], "[?"):
+				tokens = append(tokens, token{typ: tFilter, pos: start})
+				i += 2
+			default:
+				tokens = append(tokens, token{typ: tLbracket, pos: start})
+				i++
+			}
+		case c == '|' || c == '&':
+			single, double := tPipe, tOr
+			if c == '&' {
+				single, double = tExpref, tAnd
+			}
+			if i+1 < len(expr) && expr[i+1] == c {
+				tokens = append(tokens, token{typ: double, pos: start})
+				i += 2
+			} else {
+				tokens = append(tokens, token{typ: single, pos: start})
+				i++
+			}
+		case c == '!' || c == '<' || c == '>' || c == '=':
+			tt, n, err := comparator(expr[i:])
+			if err != nil {
+				return nil, syntaxError(start, err.Error())
+			}
+			tokens = append(tokens, token{typ: tt, pos: start})
+			i += n
+		case c == '"':
+			end, err := scanQuoted(expr, i, '"')
+			if err != nil {
+				return nil, err
+			}
+			var s string
+			if err := json.Unmarshal([]byte(expr[i:end]), &s); err != nil {
+				return nil, syntaxError(start, "invalid quoted identifier: "+err.Error())
+			}
+			tokens = append(tokens, token{typ: tQuotedIdentifier, value: s, pos: start})
+			i = end
+		case c == '\'':
+			end, err := scanQuoted(expr, i, '\'')
+			if err != nil {
+				return nil, err
+			}
+			s := strings.ReplaceAll(expr[i+1:end-1], `\'`, `'`)
+			tokens = append(tokens, token{typ: tRawString, value: s, pos: start})
+			i = end
+		case c == '`':
+			end, err := scanQuoted(expr, i, '`')
+			if err != nil {
+				return nil, err
+			}
+			var v any
+			if err := json.Unmarshal([]byte(strings.ReplaceAll(expr[i+1:end-1], "\\`", "`")), &v); err != nil {
+				return nil, syntaxError(start, "invalid literal: "+err.Error())
+			}
+			tokens = append(tokens, token{typ: tJSONLiteral, value: v, pos: start})
+			i = end
+		case c == '-' || isDigit(c):
+			i++
+			for i < len(expr) && isDigit(expr[i]) {
+				i++
+			}
+			n, err := strconv.Atoi(expr[start:i])
+			if err != nil {
+				return nil, syntaxError(start, "invalid number "+expr[start:i])
+			}
+			tokens = append(tokens, token{typ: tNumber, value: n, pos: start})
+		case isIdentStart(c):
+			i++
+			for i < len(expr) && (isIdentStart(expr[i]) || isDigit(expr[i])) {
+				i++
+			}
+			tokens = append(tokens, token{typ: tUnquotedIdentifier, value: expr[start:i], pos: start})
+		default:
+			return nil, syntaxError(start, fmt.Sprintf("unexpected character %q", c))
+		}
+	}
+
+	return append(tokens, token{typ: tEOF, pos: len(expr)}), nil
+}
+
+func comparator(s string) (tokenType, int, error) {
+	switch {
+	case strings.HasPrefix(s, "=="):
+		return tEQ, 2, nil
+	case strings.HasPrefix(s, "!="):
+		return tNE, 2, nil
+	case strings.HasPrefix(s, "<="):
+		return tLTE, 2, nil
+	case strings.HasPrefix(s, ">="):
+		return tGTE, 2, nil
+	case s[0] == '<':
+		return tLT, 1, nil
+	case s[0] == '>':
+		return tGT, 1, nil
+	case s[0] == '!':
+		return tNot, 1, nil
+	}
+	return 0, 0, fmt.Errorf(`unexpected "=", use "==" to compare`)
+}
+
+// scanQuoted returns the position after the closing quote of the string that starts at start.
+func scanQuoted(expr string, start int, quote byte) (int, error) {
+	for i := start + 1; i < len(expr); i++ {
+		switch expr[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1, nil
+		}
+	}
+	return 0, syntaxError(start, fmt.Sprintf("unclosed %c", quote))
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '_'
+}
@@ -0,0 +1,453 @@
+package jmespath
+
+import (
+	"fmt"
+)
+
+type nodeType int
+
+const (
+	nodeCurrent nodeType = iota
+	nodeField
+	nodeSubexpr
+	nodeIndexExpr
+	nodeIndex
+	nodeSlice
+	nodeProjection
+	nodeValueProjection
+	nodeFlatten
+	nodeFilterProjection
+	nodeComparator
+	nodeOr
+	nodeAnd
+	nodeNot
+	nodeMultiSelectList
+	nodeMultiSelectHash
+	nodeLiteral
+	nodeFunction
+	nodeExpref
+	nodePipe
+)
+
+// node is the node of AST.
+// value is the field name, the literal, the index, the slice, the comparator, the function name
+// or the keys of the multi-select hash, depending on typ.
+type node struct {
+	typ      nodeType
+	value    any
+	children []*node
+}
+
+type slice struct {
+	start, stop *int
+	step        int
+}
+
+// the projection stops at the tokens whose binding power is lower than this.
+const projectionStop = 10
+
+var bindingPowers = map[tokenType]int{
+	tPipe:     1,
+	tOr:       2,
+	tAnd:      3,
+	tEQ:       5,
+	tNE:       5,
+	tLT:       5,
+	tLTE:      5,
+	tGT:       5,
+	tGTE:      5,
+	tFlatten:  9,
+	tStar:     20,
+	tFilter:   21,
+	tDot:      40,
+	tNot:      45,
+	tLbrace:   50,
+	tLbracket: 55,
+	tLparen:   60,
+}
+
+type parser struct {
+	tokens []token
+	i      int
+	funcs  map[string]*function
+}
+
+func parse(expr string, funcs map[string]*function) (*node, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, funcs: funcs}
+	n, err := p.expression(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tEOF {
+		return nil, p.unexpected(t)
+	}
+
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.i+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.i+offset]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.typ != tEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) expect(tt tokenType) error {
+	if t := p.next(); t.typ != tt {
+		return syntaxError(t.pos, fmt.Sprintf("expected %s, but got %s", tt, t.typ))
+	}
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	return syntaxError(t.pos, fmt.Sprintf("unexpected %s", t.typ))
+}
+
+// expression parses the expression with the Pratt parser.
+func (p *parser) expression(bp int) (*node, error) {
+	left, err := p.nud(p.next())
+	if err != nil {
+		return nil, err
+	}
+
+	for bp < bindingPowers[p.peek().typ] {
+		left, err = p.led(p.next(), left)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) nud(t token) (*node, error) {
+	switch t.typ {
+	case tJSONLiteral, tRawString:
+		return &node{typ: nodeLiteral, value: t.value}, nil
+	case tUnquotedIdentifier:
+		if p.peek().typ == tLparen {
+			p.next()
+			return p.function(t)
+		}
+		return &node{typ: nodeField, value: t.value}, nil
+	case tQuotedIdentifier:
+		if p.peek().typ == tLparen {
+			return nil, syntaxError(t.pos, "quoted identifier cannot be a function name")
+		}
+		return &node{typ: nodeField, value: t.value}, nil
+	case tStar:
+		right, err := p.projectionRHS(bindingPowers[tStar])
+		if err != nil {
+			return nil, err
+		}
+		return &node{typ: nodeValueProjection, children: []*node{{typ: nodeCurrent}, right}}, nil
+	case tFilter:
+		return p.filter(&node{typ: nodeCurrent})
+	case tFlatten:
+		right, err := p.projectionRHS(bindingPowers[tFlatten])
+		if err != nil {
+			return nil, err
+		}
+		flatten := &node{typ: nodeFlatten, children: []*node{{typ: nodeCurrent}}}
+		return &node{typ: nodeProjection, children: []*node{flatten, right}}, nil
+	case tLbracket:
+		switch {
+		case p.peek().typ == tNumber || p.peek().typ == tColon:
+			return p.indexExpression(&node{typ: nodeCurrent})
+		case p.peek().typ == tStar && p.peekAt(1).typ == tRbracket:
+			p.next()
+			p.next()
+			right, err := p.projectionRHS(bindingPowers[tStar])
+			if err != nil {
+				return nil, err
+			}
+			return &node{typ: nodeProjection, children: []*node{{typ: nodeCurrent}, right}}, nil
+		}
+		return p.multiSelectList()
+	case tLbrace:
+		return p.multiSelectHash()
+	case tCurrent:
+		return &node{typ: nodeCurrent}, nil
+	case tExpref:
+		n, err := p.expression(bindingPowers[tExpref])
+		if err != nil {
+			return nil, err
+		}
+		return &node{typ: nodeExpref, children: []*node{n}}, nil
+	case tNot:
+		n, err := p.expression(bindingPowers[tNot])
+		if err != nil {
+			return nil, err
+		}
+		return &node{typ: nodeNot, children: []*node{n}}, nil
+	case tLparen:
+		n, err := p.expression(0)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tRparen); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+
+	return nil, p.unexpected(t)
+}
+
+func (p *parser) led(t token, left *node) (*node, error) {
+	switch t.typ {
+	case tDot:
+		if p.peek().typ == tStar {
+			p.next()
+			right, err := p.projectionRHS(bindingPowers[tDot])
+			if err != nil {
+				return nil, err
+			}
+			return &node{typ: nodeValueProjection, children: []*node{left, right}}, nil
+		}
+		right, err := p.dotRHS(bindingPowers[tDot])
+		if err != nil {
+			return nil, err
+		}
+		return &node{typ: nodeSubexpr, children: []*node{left, right}}, nil
+	case tPipe, tOr, tAnd:
+		right, err := p.expression(bindingPowers[t.typ])
+		if err != nil {
+			return nil, err
+		}
+		typ := map[tokenType]nodeType{tPipe: nodePipe, tOr: nodeOr, tAnd: nodeAnd}[t.typ]
+		return &node{typ: typ, children: []*node{left, right}}, nil
+	case tEQ, tNE, tLT, tLTE, tGT, tGTE:
+		right, err := p.expression(bindingPowers[t.typ])
+		if err != nil {
+			return nil, err
+		}
+		return &node{typ: nodeComparator, value: t.typ, children: []*node{left, right}}, nil
+	case tLbracket:
+		switch {
+		case p.peek().typ == tNumber || p.peek().typ == tColon:
+			return p.indexExpression(left)
+		case p.peek().typ == tStar && p.peekAt(1).typ == tRbracket:
+			p.next()
+			p.next()
+			right, err := p.projectionRHS(bindingPowers[tStar])
+			if err != nil {
+				return nil, err
+			}
+			return &node{typ: nodeProjection, children: []*node{left, right}}, nil
+		}
+		return nil, p.unexpected(p.peek())
+	case tFilter:
+		return p.filter(left)
+	case tFlatten:
+		right, err := p.projectionRHS(bindingPowers[tFlatten])
+		if err != nil {
+			return nil, err
+		}
+		flatten := &node{typ: nodeFlatten, children: []*node{left}}
+		return &node{typ: nodeProjection, children: []*node{flatten, right}}, nil
+	}
+
+	return nil, p.unexpected(t)
+}
+
+// dotRHS parses the right hand side of ".", that is an identifier, a multi-select list,
+// a multi-select hash or a function call.
+func (p *parser) dotRHS(bp int) (*node, error) {
+	switch p.peek().typ {
+	case tUnquotedIdentifier, tQuotedIdentifier, tStar:
+		return p.expression(bp)
+	case tLbracket:
+		p.next()
+		return p.multiSelectList()
+	case tLbrace:
+		p.next()
+		return p.multiSelectHash()
+	}
+	return nil, p.unexpected(p.peek())
+}
+
+// projectionRHS parses the expression applied to each element of the projection.
+func (p *parser) projectionRHS(bp int) (*node, error) {
+	t := p.peek()
+	switch {
+	case bindingPowers[t.typ] < projectionStop:
+		return &node{typ: nodeCurrent}, nil
+	case t.typ == tLbracket || t.typ == tFilter:
+		return p.expression(bp)
+	case t.typ == tDot:
+		p.next()
+		return p.dotRHS(bp)
+	}
+	return nil, p.unexpected(t)
+}
+
+// indexExpression parses the index or the slice after "[".
+// A slice is a projection of the sliced array.
+func (p *parser) indexExpression(left *node) (*node, error) {
+	parts := [3]*int{}
+	colons := 0
+	for {
+		t := p.next()
+		switch t.typ {
+		case tNumber:
+			n := t.value.(int)
+			if parts[colons] != nil {
+				return nil, p.unexpected(t)
+			}
+			parts[colons] = &n
+		case tColon:
+			colons++
+			if colons > 2 {
+				return nil, p.unexpected(t)
+			}
+		case tRbracket:
+			if colons == 0 {
+				if parts[0] == nil {
+					return nil, p.unexpected(t)
+				}
+				index := &node{typ: nodeIndex, value: *parts[0]}
+				return &node{typ: nodeIndexExpr, children: []*node{left, index}}, nil
+			}
+
+			s := slice{start: parts[0], stop: parts[1], step: 1}
+			if parts[2] != nil {
+				if *parts[2] == 0 {
+					return nil, syntaxError(t.pos, "slice step cannot be 0")
+				}
+				s.step = *parts[2]
+			}
+			sliced := &node{typ: nodeIndexExpr, children: []*node{left, {typ: nodeSlice, value: s}}}
+			right, err := p.projectionRHS(bindingPowers[tStar])
+			if err != nil {
+				return nil, err
+			}
+			return &node{typ: nodeProjection, children: []*node{sliced, right}}, nil
+		default:
+			return nil, p.unexpected(t)
+		}
+	}
+}
+
+func (p *parser) filter(left *node) (*node, error) {
+	cond, err := p.expression(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(tRbracket); err != nil {
+		return nil, err
+	}
+
+	right := &node{typ: nodeCurrent}
+	if p.peek().typ != tFlatten {
+		right, err = p.projectionRHS(bindingPowers[tFilter])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &node{typ: nodeFilterProjection, children: []*node{left, right, cond}}, nil
+}
+
+func (p *parser) multiSelectList() (*node, error) {
+	n := &node{typ: nodeMultiSelectList}
+	for {
+		e, err := p.expression(0)
+		if err != nil {
+			return nil, err
+		}
+		n.children = append(n.children, e)
+
+		t := p.next()
+		switch t.typ {
+		case tRbracket:
+			return n, nil
+		case tComma:
+		default:
+			return nil, p.unexpected(t)
+		}
+	}
+}
+
+func (p *parser) multiSelectHash() (*node, error) {
+	keys := []string{}
+	n := &node{typ: nodeMultiSelectHash}
+	for {
+		k := p.next()
+		if k.typ != tUnquotedIdentifier && k.typ != tQuotedIdentifier {
+			return nil, p.unexpected(k)
+		}
+		if err := p.expect(tColon); err != nil {
+			return nil, err
+		}
+		e, err := p.expression(0)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k.value.(string))
+		n.children = append(n.children, e)
+
+		t := p.next()
+		switch t.typ {
+		case tRbrace:
+			n.value = keys
+			return n, nil
+		case tComma:
+		default:
+			return nil, p.unexpected(t)
+		}
+	}
+}
+
+// function parses the arguments of the function after "(", and checks the name and the number of them.
+func (p *parser) function(name token) (*node, error) {
+	n := &node{typ: nodeFunction, value: name.value}
+	if p.peek().typ == tRparen {
+		p.next()
+	} else {
+	args:
+		for {
+			arg, err := p.expression(0)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, arg)
+
+			t := p.next()
+			switch t.typ {
+			case tRparen:
+				break args
+			case tComma:
+			default:
+				return nil, p.unexpected(t)
+			}
+		}
+	}
+
+	fn, ok := p.funcs[name.value.(string)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFunction, name.value)
+	}
+	if err := fn.checkArity(len(n.children)); err != nil {
+		return nil, fmt.Errorf("%s(): %w", name.value, err)
+	}
+
+	return n, nil
+}
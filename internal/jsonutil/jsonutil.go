@@ -0,0 +1,26 @@
+// Package jsonutil provides the helpers for JSON shared by the packages of this module.
+package jsonutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Decode decodes the JSON value, keeping the numbers as json.Number.
+// It returns an error if there is data after the top-level value.
+func Decode(b []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid character after top-level value")
+	}
+
+	return v, nil
+}
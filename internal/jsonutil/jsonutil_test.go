@@ -0,0 +1,41 @@
+package jsonutil_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/internal/jsonutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Decode(t *testing.T) {
+	cases := []struct {
+		name    string
+		b       string
+		expect  any
+		wantErr bool
+	}{
+		{name: "ok: object", b: `{"a":[1,"x",true,null]}`, expect: map[string]any{"a": []any{json.Number("1"), "x", true, nil}}},
+		{name: "ok: large number", b: `12345678901234567890`, expect: json.Number("12345678901234567890")},
+		{name: "ok: trailing whitespace", b: "\"x\" \n", expect: "x"},
+		{name: "ng: invalid JSON", b: `{`, wantErr: true},
+		{name: "ng: data after top-level value", b: `{} {}`, wantErr: true},
+		{name: "ng: empty", b: ``, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			v, err := jsonutil.Decode([]byte(c.b))
+			if c.wantErr {
+				asst.Error(err)
+				asst.Nil(v)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, v)
+		})
+	}
+}
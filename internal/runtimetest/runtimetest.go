@@ -0,0 +1,175 @@
+// Package runtimetest provides the fake Runtime API server for testing the event loop
+// of the custom runtime.
+package runtimetest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/alago"
+)
+
+// Event is the invocation returned by GET /runtime/invocation/next.
+type Event struct {
+	RequestID string
+	Body      string
+
+	// Additional response headers, e.g. Lambda-Runtime-Aws-Tenant-Id.
+	Header map[string]string
+}
+
+// Result is the request made by the runtime other than GET /runtime/invocation/next.
+type Result struct {
+	RequestID string
+
+	// "response" or "error" for the invocations, otherwise the path after /runtime/,
+	// e.g. "init/error" and "restore/next".
+	Kind string
+
+	Body    string
+	Header  http.Header
+	Trailer http.Header
+}
+
+// Server is the fake Runtime API server.
+// It returns the events in order, and then returns 500 error for GET /runtime/invocation/next,
+// so that the loop stops with the error whose type is Test.NoMoreEvents.
+// The exported fields must be set before the loop starts.
+type Server struct {
+	// Status code of POST /runtime/invocation/{AwsRequestId}/response. The default is 202.
+	ResponseStatusCode int
+
+	// Status code of GET /runtime/restore/next. The default is 200.
+	RestoreNextStatusCode int
+
+	// If true, GET /runtime/invocation/next blocks until the request is canceled
+	// instead of returning 500 error when there are no more events.
+	BlockWhenEmpty bool
+
+	mu      sync.Mutex
+	events  []Event
+	results []Result
+	polls   int
+
+	server *httptest.Server
+}
+
+// NewServer starts the server that returns events, and sets AWS_LAMBDA_RUNTIME_API to its address
+// for the test. The server is closed when the test completes.
+func NewServer(t testing.TB, events ...Event) *Server {
+	s := &Server{
+		ResponseStatusCode:    http.StatusAccepted,
+		RestoreNextStatusCode: http.StatusOK,
+		events:                events,
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.server.Close)
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", strings.TrimPrefix(s.server.URL, "http://"))
+	return s
+}
+
+// Client returns the client of the server.
+func (s *Server) Client(t testing.TB) alago.AlagoClient {
+	c, err := alago.NewClient(&alago.NewClientInput{HttpClient: s.server.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// Polls returns the number of GET /runtime/invocation/next.
+func (s *Server) Polls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.polls
+}
+
+// Results returns the requests made so far, in the order they are received.
+func (s *Server) Results() []Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Result{}, s.results...)
+}
+
+// RemainingEvents returns the number of the events that have not been returned yet.
+func (s *Server) RemainingEvents() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/2018-06-01/runtime/"
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+
+	if r.Method == http.MethodGet && path == "invocation/next" {
+		s.mu.Lock()
+		s.polls++
+		if len(s.events) == 0 {
+			block := s.BlockWhenEmpty
+			s.mu.Unlock()
+			if block {
+				<-r.Context().Done()
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"errorMessage":"no more events","errorType":"Test.NoMoreEvents"}`))
+			return
+		}
+		ev := s.events[0]
+		s.events = s.events[1:]
+		s.mu.Unlock()
+
+		w.Header().Set("Lambda-Runtime-Aws-Request-Id", ev.RequestID)
+		for k, v := range ev.Header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(ev.Body))
+		return
+	}
+
+	if r.Method == http.MethodGet && path == "restore/next" {
+		s.mu.Lock()
+		s.results = append(s.results, Result{Kind: path})
+		s.mu.Unlock()
+
+		w.WriteHeader(s.RestoreNextStatusCode)
+		if s.RestoreNextStatusCode != http.StatusOK {
+			w.Write([]byte(`{"errorMessage":"test-error-message","errorType":"Test.RestoreNext"}`))
+		}
+		return
+	}
+
+	b, _ := io.ReadAll(r.Body)
+	res := Result{Body: string(b), Header: r.Header, Trailer: r.Trailer}
+
+	statusCode := http.StatusAccepted
+	switch {
+	case strings.HasPrefix(path, "invocation/") && strings.HasSuffix(path, "/response"):
+		res.Kind = "response"
+		res.RequestID = strings.TrimSuffix(strings.TrimPrefix(path, "invocation/"), "/response")
+		statusCode = s.ResponseStatusCode
+	case strings.HasPrefix(path, "invocation/") && strings.HasSuffix(path, "/error"):
+		res.Kind = "error"
+		res.RequestID = strings.TrimSuffix(strings.TrimPrefix(path, "invocation/"), "/error")
+	default:
+		res.Kind = path
+	}
+
+	s.mu.Lock()
+	s.results = append(s.results, res)
+	s.mu.Unlock()
+
+	w.WriteHeader(statusCode)
+	if statusCode == http.StatusAccepted {
+		w.Write([]byte(`{"status":"OK"}`))
+		return
+	}
+	w.Write([]byte(fmt.Sprintf(`{"errorMessage":"test-error-message","errorType":"Test.Status%d"}`, statusCode)))
+}
@@ -0,0 +1,206 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/michimani/aws-lambda-api-go/httpadapter"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+)
+
+// Source is the source of the event.
+type Source string
+
+const (
+	SourceSQS            Source = "SQS"
+	SourceSNS            Source = "SNS"
+	SourceS3             Source = "S3"
+	SourceDynamoDB       Source = "DynamoDB"
+	SourceKinesis        Source = "Kinesis"
+	SourceEventBridge    Source = "EventBridge"
+	SourceCloudWatchLogs Source = "CloudWatchLogs"
+	SourceAPIGatewayV1   Source = "APIGatewayV1"
+	SourceAPIGatewayV2   Source = "APIGatewayV2"
+	SourceALB            Source = "ALB"
+	SourceFunctionURL    Source = "FunctionURL"
+	SourceCognito        Source = "Cognito"
+)
+
+var (
+	// ErrUnknownSource is returned when the source of the event is not detected.
+	ErrUnknownSource = errors.New("unknown event source")
+
+	// ErrAmbiguousEvent is returned when the event has the markers of more than one source.
+	// The error returned for the event is *AmbiguousEventError, that wraps ErrAmbiguousEvent.
+	ErrAmbiguousEvent = errors.New("ambiguous event source")
+
+	// ErrNoHandler is returned when no handler is registered for the source and there is no fallback handler.
+	ErrNoHandler = errors.New("no handler for the event source")
+)
+
+// AmbiguousEventError is the error that the event has the markers of more than one source.
+type AmbiguousEventError struct {
+	// The sources whose markers are found in the event.
+	Sources []Source
+}
+
+func (e *AmbiguousEventError) Error() string {
+	s := make([]string, 0, len(e.Sources))
+	for _, src := range e.Sources {
+		s = append(s, string(src))
+	}
+	return fmt.Sprintf("%v: %s", ErrAmbiguousEvent, strings.Join(s, ", "))
+}
+
+func (e *AmbiguousEventError) Unwrap() error {
+	return ErrAmbiguousEvent
+}
+
+var recordEventSources = map[string]Source{
+	"aws:sqs":      SourceSQS,
+	"aws:sns":      SourceSNS,
+	"aws:s3":       SourceS3,
+	"aws:dynamodb": SourceDynamoDB,
+	"aws:kinesis":  SourceKinesis,
+}
+
+var httpEventSources = map[httpadapter.EventType]Source{
+	httpadapter.EventTypeAPIGatewayV1: SourceAPIGatewayV1,
+	httpadapter.EventTypeAPIGatewayV2: SourceAPIGatewayV2,
+	httpadapter.EventTypeALB:          SourceALB,
+	httpadapter.EventTypeFunctionURL:  SourceFunctionURL,
+}
+
+// probe is the markers of the sources.
+type probe struct {
+	Records []struct {
+		// SNS uses EventSource, and the others use eventSource.
+		// Both are matched, since the keys of JSON are case-insensitive in encoding/json.
+		EventSource string `json:"eventSource"`
+	} `json:"Records"`
+
+	DetailType     *string         `json:"detail-type"`
+	AWSLogs        json.RawMessage `json:"awslogs"`
+	RequestContext json.RawMessage `json:"requestContext"`
+	TriggerSource  string          `json:"triggerSource"`
+	UserPoolID     string          `json:"userPoolId"`
+}
+
+// Detect returns the source of the event, that is detected by the markers in the event:
+// Records[].eventSource for SQS, SNS, S3, DynamoDB Streams and Kinesis, detail-type for EventBridge,
+// awslogs for CloudWatch Logs, requestContext for API Gateway, ALB and function URL,
+// and triggerSource and userPoolId for Cognito user pool triggers.
+// It returns ErrUnknownSource if no marker is found, including when the event is not a JSON object
+// (e.g. a string or an array) or a marker has an unexpected type, and *AmbiguousEventError
+// if the markers of more than one source are found.
+func Detect(event []byte) (Source, error) {
+	p := probe{}
+	if err := json.Unmarshal(event, &p); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnknownSource, err)
+	}
+
+	found := map[Source]bool{}
+	for _, r := range p.Records {
+		src, ok := recordEventSources[r.EventSource]
+		if !ok {
+			return "", fmt.Errorf("%w: Records[].eventSource is %q", ErrUnknownSource, r.EventSource)
+		}
+		found[src] = true
+	}
+	if p.DetailType != nil {
+		found[SourceEventBridge] = true
+	}
+	if len(p.AWSLogs) > 0 {
+		found[SourceCloudWatchLogs] = true
+	}
+	if len(p.RequestContext) > 0 {
+		if et, err := httpadapter.DetectEventType(event); err == nil {
+			found[httpEventSources[et]] = true
+		}
+	}
+	if p.TriggerSource != "" && p.UserPoolID != "" {
+		found[SourceCognito] = true
+	}
+
+	switch len(found) {
+	case 0:
+		return "", ErrUnknownSource
+	case 1:
+		for src := range found {
+			return src, nil
+		}
+	}
+
+	sources := make([]Source, 0, len(found))
+	for src := range found {
+		sources = append(sources, src)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i] < sources[j] })
+	return "", &AmbiguousEventError{Sources: sources}
+}
+
+// Router dispatches the event to the handler registered for its source.
+// Router is safe for concurrent use.
+type Router struct {
+	mu       sync.RWMutex
+	handlers map[Source]runtime.Handler
+	fallback runtime.Handler
+}
+
+// New returns a new Router that has no handlers.
+func New() *Router {
+	return &Router{handlers: map[Source]runtime.Handler{}}
+}
+
+// Handle registers the typed handler for the source.
+// The event is unmarshaled into TIn and the output is marshaled from TOut as JSON, in the same way as runtime.Start.
+// If a handler is already registered for the source, it is replaced.
+func Handle[TIn, TOut any](r *Router, source Source, handler func(context.Context, TIn) (TOut, error)) {
+	r.HandleRaw(source, runtime.NewHandler(handler))
+}
+
+// HandleRaw registers runtime.Handler for the source.
+// If a handler is already registered for the source, it is replaced.
+func (r *Router) HandleRaw(source Source, handler runtime.Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[source] = handler
+}
+
+// Fallback sets the handler that is called when the source is unknown
+// or no handler is registered for the source. It is not called for the ambiguous events.
+func (r *Router) Fallback(handler runtime.Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = handler
+}
+
+// Invoke detects the source of the event and calls the handler for it.
+// It can be used as runtime.Handler, e.g. runtime.StartHandler(r.Invoke).
+func (r *Router) Invoke(ctx context.Context, event []byte) ([]byte, error) {
+	src, err := Detect(event)
+	if err != nil && !errors.Is(err, ErrUnknownSource) {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	h, ok := r.handlers[src]
+	fallback := r.fallback
+	r.mu.RUnlock()
+
+	if err == nil && ok {
+		return h(ctx, event)
+	}
+	if fallback != nil {
+		return fallback(ctx, event)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoHandler, src)
+}
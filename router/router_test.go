@@ -0,0 +1,234 @@
+package router_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/events"
+	"github.com/michimani/aws-lambda-api-go/router"
+	"github.com/stretchr/testify/assert"
+)
+
+// fixture reads the event from the fixtures of the events package.
+func fixture(t *testing.T, name string) []byte {
+	b, err := os.ReadFile(filepath.Join("..", "events", "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func Test_Detect(t *testing.T) {
+	cases := []struct {
+		name          string
+		event         func(t *testing.T) []byte
+		expect        router.Source
+		expectSources []router.Source
+		wantErr       error
+	}{
+		{name: "ok: SQS", event: func(t *testing.T) []byte { return fixture(t, "sqs.json") }, expect: router.SourceSQS},
+		{name: "ok: SNS", event: func(t *testing.T) []byte { return fixture(t, "sns.json") }, expect: router.SourceSNS},
+		{name: "ok: S3", event: func(t *testing.T) []byte { return fixture(t, "s3.json") }, expect: router.SourceS3},
+		{name: "ok: DynamoDB Streams", event: func(t *testing.T) []byte { return fixture(t, "dynamodb.json") }, expect: router.SourceDynamoDB},
+		{name: "ok: Kinesis", event: func(t *testing.T) []byte { return fixture(t, "kinesis.json") }, expect: router.SourceKinesis},
+		{name: "ok: EventBridge", event: func(t *testing.T) []byte { return fixture(t, "eventbridge.json") }, expect: router.SourceEventBridge},
+		{name: "ok: CloudWatch Logs", event: func(t *testing.T) []byte { return fixture(t, "cloudwatch_logs.json") }, expect: router.SourceCloudWatchLogs},
+		{name: "ok: API Gateway REST API", event: func(t *testing.T) []byte { return fixture(t, "apigateway_v1.json") }, expect: router.SourceAPIGatewayV1},
+		{name: "ok: API Gateway HTTP API", event: func(t *testing.T) []byte { return fixture(t, "apigateway_v2.json") }, expect: router.SourceAPIGatewayV2},
+		{name: "ok: function URL", event: func(t *testing.T) []byte { return fixture(t, "function_url.json") }, expect: router.SourceFunctionURL},
+		{name: "ok: ALB", event: func(t *testing.T) []byte { return fixture(t, "alb.json") }, expect: router.SourceALB},
+		{name: "ok: Cognito", event: func(t *testing.T) []byte { return fixture(t, "cognito_pre_signup.json") }, expect: router.SourceCognito},
+		{
+			name:    "ng: unknown source",
+			event:   func(t *testing.T) []byte { return []byte(`{"name":"alice"}`) },
+			wantErr: router.ErrUnknownSource,
+		},
+		{
+			name:    "ng: unknown eventSource of Records",
+			event:   func(t *testing.T) []byte { return []byte(`{"Records":[{"eventSource":"aws:unknown"}]}`) },
+			wantErr: router.ErrUnknownSource,
+		},
+		{
+			name: "ng: mixed Records",
+			event: func(t *testing.T) []byte {
+				return []byte(`{"Records":[{"eventSource":"aws:sqs"},{"eventSource":"aws:kinesis"}]}`)
+			},
+			expectSources: []router.Source{router.SourceKinesis, router.SourceSQS},
+			wantErr:       router.ErrAmbiguousEvent,
+		},
+		{
+			name: "ng: markers of EventBridge and SQS",
+			event: func(t *testing.T) []byte {
+				return []byte(`{"detail-type":"test","Records":[{"eventSource":"aws:sqs"}]}`)
+			},
+			expectSources: []router.Source{router.SourceEventBridge, router.SourceSQS},
+			wantErr:       router.ErrAmbiguousEvent,
+		},
+		{
+			name:    "ng: string event",
+			event:   func(t *testing.T) []byte { return []byte(`"hello"`) },
+			wantErr: router.ErrUnknownSource,
+		},
+		{
+			name:    "ng: array event",
+			event:   func(t *testing.T) []byte { return []byte(`[1,2]`) },
+			wantErr: router.ErrUnknownSource,
+		},
+		{
+			name:    "ng: Records is not an array",
+			event:   func(t *testing.T) []byte { return []byte(`{"Records":"x"}`) },
+			wantErr: router.ErrUnknownSource,
+		},
+		{
+			name:    "ng: detail-type is not a string",
+			event:   func(t *testing.T) []byte { return []byte(`{"detail-type":1}`) },
+			wantErr: router.ErrUnknownSource,
+		},
+		{
+			name:    "ng: invalid JSON",
+			event:   func(t *testing.T) []byte { return []byte(`///`) },
+			wantErr: router.ErrUnknownSource,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			src, err := router.Detect(c.event(tt))
+			if c.wantErr != nil {
+				asst.Error(err)
+				if c.wantErr != assert.AnError {
+					asst.ErrorIs(err, c.wantErr)
+				}
+				if c.expectSources != nil {
+					var aerr *router.AmbiguousEventError
+					asst.ErrorAs(err, &aerr)
+					asst.Equal(c.expectSources, aerr.Sources)
+				}
+				asst.Empty(src)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, src)
+		})
+	}
+}
+
+func Test_Router_Invoke(t *testing.T) {
+	r := router.New()
+	router.Handle(r, router.SourceSQS, func(ctx context.Context, e events.SQSEvent) (string, error) {
+		return "sqs:" + e.Records[0].MessageID, nil
+	})
+	router.Handle(r, router.SourceEventBridge, func(ctx context.Context, e events.EventBridgeEvent) (string, error) {
+		return "eventbridge:" + e.DetailType, nil
+	})
+
+	withFallback := router.New()
+	withFallback.HandleRaw(router.SourceSQS, func(ctx context.Context, event []byte) ([]byte, error) {
+		return []byte(`"raw"`), nil
+	})
+	withFallback.Fallback(func(ctx context.Context, event []byte) ([]byte, error) {
+		return []byte(`"fallback"`), nil
+	})
+
+	cases := []struct {
+		name    string
+		router  *router.Router
+		event   func(t *testing.T) []byte
+		expect  string
+		wantErr error
+	}{
+		{
+			name:   "ok: SQS",
+			router: r,
+			event:  func(t *testing.T) []byte { return fixture(t, "sqs.json") },
+			expect: `"sqs:059f36b4-87a3-44ab-83d2-661975830a7d"`,
+		},
+		{
+			name:   "ok: EventBridge",
+			router: r,
+			event:  func(t *testing.T) []byte { return fixture(t, "eventbridge.json") },
+			expect: `"eventbridge:EC2 Instance State-change Notification"`,
+		},
+		{
+			name:   "ok: raw handler",
+			router: withFallback,
+			event:  func(t *testing.T) []byte { return fixture(t, "sqs.json") },
+			expect: `"raw"`,
+		},
+		{
+			name:   "ok: fallback for no handler",
+			router: withFallback,
+			event:  func(t *testing.T) []byte { return fixture(t, "s3.json") },
+			expect: `"fallback"`,
+		},
+		{
+			name:   "ok: fallback for unknown source",
+			router: withFallback,
+			event:  func(t *testing.T) []byte { return []byte(`{"name":"alice"}`) },
+			expect: `"fallback"`,
+		},
+		{
+			name:   "ok: fallback for string event",
+			router: withFallback,
+			event:  func(t *testing.T) []byte { return []byte(`"hello"`) },
+			expect: `"fallback"`,
+		},
+		{
+			name:   "ok: fallback for array event",
+			router: withFallback,
+			event:  func(t *testing.T) []byte { return []byte(`[1,2]`) },
+			expect: `"fallback"`,
+		},
+		{
+			name:   "ok: fallback for mistyped marker",
+			router: withFallback,
+			event:  func(t *testing.T) []byte { return []byte(`{"Records":"x"}`) },
+			expect: `"fallback"`,
+		},
+		{
+			name:    "ng: no handler",
+			router:  r,
+			event:   func(t *testing.T) []byte { return fixture(t, "s3.json") },
+			wantErr: router.ErrNoHandler,
+		},
+		{
+			name:    "ng: unknown source",
+			router:  r,
+			event:   func(t *testing.T) []byte { return []byte(`{"name":"alice"}`) },
+			wantErr: router.ErrUnknownSource,
+		},
+		{
+			name:    "ng: string event without fallback",
+			router:  r,
+			event:   func(t *testing.T) []byte { return []byte(`"hello"`) },
+			wantErr: router.ErrUnknownSource,
+		},
+		{
+			name:    "ng: ambiguous event is not passed to fallback",
+			router:  withFallback,
+			event:   func(t *testing.T) []byte { return []byte(`{"detail-type":"test","awslogs":{}}`) },
+			wantErr: router.ErrAmbiguousEvent,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := c.router.Invoke(context.Background(), c.event(tt))
+			if c.wantErr != nil {
+				asst.ErrorIs(err, c.wantErr)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.JSONEq(c.expect, string(out))
+		})
+	}
+}
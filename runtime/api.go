@@ -1,9 +1,12 @@
 package runtime
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/michimani/aws-lambda-api-go/alago"
@@ -13,6 +16,14 @@ import (
 const (
 	invocationNextEndpointFmt     string = "http://%s/2018-06-01/runtime/invocation/next"
 	invocationResponseEndpointFmt string = "http://%s/2018-06-01/runtime/invocation/%s/response"
+	invocationErrorEndpointFmt    string = "http://%s/2018-06-01/runtime/invocation/%s/error"
+	initErrorEndpointFmt          string = "http://%s/2018-06-01/runtime/init/error"
+	restoreNextEndpointFmt        string = "http://%s/2018-06-01/runtime/restore/next"
+	restoreErrorEndpointFmt       string = "http://%s/2018-06-01/runtime/restore/error"
+
+	// Request Header Names
+	requestHeaderLambdaRuntimeFunctionErrorType      string = "Lambda-Runtime-Function-Error-Type"
+	requestHeaderLambdaRuntimeFunctionXRayErrorCause string = "Lambda-Runtime-Function-XRay-Error-Cause"
 
 	// Response Header Names
 	responseHeaderLambdaRuntimeAwsRequestId       string = "Lambda-Runtime-Aws-Request-Id"
@@ -21,6 +32,7 @@ const (
 	responseHeaderLambdaRuntimeCognitoIdentity    string = "Lambda-Runtime-Cognito-Identity"
 	responseHeaderLambdaRuntimeDeadlineMs         string = "Lambda-Runtime-Deadline-Ms"
 	responseHeaderLambdaRuntimeInvokedFunctionArn string = "Lambda-Runtime-Invoked-Function-Arn"
+	responseHeaderLambdaRuntimeAwsTenantId        string = "Lambda-Runtime-Aws-Tenant-Id"
 )
 
 // Runtime makes this HTTP request when it is ready to receive and process a new invoke.
@@ -28,7 +40,7 @@ const (
 // document: https://docs.aws.amazon.com/lambda/latest/dg/runtimes-api.html#runtimes-api-next
 func InvocationNext(ctx context.Context, client alago.AlagoClient) (*NextOutput, error) {
 	url := fmt.Sprintf(invocationNextEndpointFmt, client.Host())
-	sc, h, b, err := internal.CallAPI(context.Background(), client, http.MethodGet, url, nil)
+	sc, h, b, err := internal.CallAPI(ctx, client, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -54,19 +66,73 @@ func generateNextOutput(sc int, header http.Header, body []byte) (*NextOutput, e
 		return &out, nil
 	}
 
+	setNextHeader(&out, header)
+	out.RawEventResponse = body
+
+	return &out, nil
+}
+
+// Runtime makes this HTTP request when it is ready to receive and process a new invoke.
+// Unlike InvocationNext, the event is not read into memory, and returned as NextStreamOutput.Body.
+// The event can be decoded into a structure without buffering by NextStreamOutput.Decode.
+// The caller must close NextStreamOutput.Body (or call Decode) before the next request.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/runtimes-api.html#runtimes-api-next
+func InvocationNextStream(ctx context.Context, client alago.AlagoClient) (*NextStreamOutput, error) {
+	url := fmt.Sprintf(invocationNextEndpointFmt, client.Host())
+	res, err := internal.CallAPIStream(ctx, client, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := generateNextStreamOutput(res.StatusCode, res.Header, res.Body)
+	if err != nil {
+		return nil, internal.ContextError(ctx, err)
+	}
+
+	return out, nil
+}
+
+func generateNextStreamOutput(sc int, header http.Header, body io.ReadCloser) (*NextStreamOutput, error) {
+	out := NextStreamOutput{}
+	out.StatusCode = sc
+
+	if sc != http.StatusOK {
+		defer func() {
+			_, _ = io.Copy(io.Discard, body)
+			body.Close()
+		}()
+
+		var errRes ErrorResponse
+		if err := json.NewDecoder(body).Decode(&errRes); err != nil {
+			return nil, err
+		}
+		out.Error = &errRes
+		return &out, nil
+	}
+
+	setNextHeader(&out.NextOutput, header)
+	out.Body = body
+
+	return &out, nil
+}
+
+func setNextHeader(out *NextOutput, header http.Header) {
 	out.AWSRequestID = header.Get(responseHeaderLambdaRuntimeAwsRequestId)
 	out.TraceID = header.Get(responseHeaderLambdaRuntimeTraceId)
 	out.ClientContext = header.Get(responseHeaderLambdaRuntimeClientContext)
 	out.CognitoIdentity = header.Get(responseHeaderLambdaRuntimeCognitoIdentity)
 	out.DeadlineMs = header.Get(responseHeaderLambdaRuntimeDeadlineMs)
 	out.InvokedFunctionArn = header.Get(responseHeaderLambdaRuntimeInvokedFunctionArn)
-	out.RawEventResponse = body
-
-	return &out, nil
+	out.TenantID = header.Get(responseHeaderLambdaRuntimeAwsTenantId)
 }
 
 // Runtime makes this request in order to submit a response.
 //
+// If the size of the response exceeds MaxResponsePayloadSize, the request is not made and
+// *PayloadTooLargeError is returned. With ResponseInput.ReportPayloadTooLarge, the error is
+// also reported by InvocationError with the error type Function.ResponseSizeTooLarge.
+//
 // document: https://docs.aws.amazon.com/lambda/latest/dg/runtimes-api.html#runtimes-api-response
 func InvocationResponse(ctx context.Context, client alago.AlagoClient, in *ResponseInput) (*ResponseOutput, error) {
 	if in == nil {
@@ -79,8 +145,22 @@ func InvocationResponse(ctx context.Context, client alago.AlagoClient, in *Respo
 		return nil, fmt.Errorf("ResponseInput.Response is nil")
 	}
 
+	res, err := limitPayload(in.Response, MaxResponsePayloadSize)
+	if err != nil {
+		var perr *PayloadTooLargeError
+		if in.ReportPayloadTooLarge && errors.As(err, &perr) {
+			if _, rerr := InvocationError(ctx, client, &InvocationErrorInput{
+				AWSRequestID: in.AWSRequestID,
+				Error:        perr.FunctionError(),
+			}); rerr != nil {
+				return nil, fmt.Errorf("failed to report error: %v, error: %w", rerr, err)
+			}
+		}
+		return nil, err
+	}
+
 	url := fmt.Sprintf(invocationResponseEndpointFmt, client.Host(), in.AWSRequestID)
-	sc, _, b, err := internal.CallAPI(context.Background(), client, http.MethodPost, url, in.Response)
+	sc, _, b, err := internal.CallAPI(ctx, client, http.MethodPost, url, res)
 	if err != nil {
 		return nil, err
 	}
@@ -112,3 +192,217 @@ func generateResponseOutput(sc int, body []byte) (*ResponseOutput, error) {
 
 	return &out, nil
 }
+
+// If the function returns an error, the runtime formats the error into a JSON document,
+// and makes this request in order to report it.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/runtimes-api.html#runtimes-api-invokeerror
+func InvocationError(ctx context.Context, client alago.AlagoClient, in *InvocationErrorInput) (*InvocationErrorOutput, error) {
+	if in == nil {
+		return nil, fmt.Errorf("InvocationErrorInput is nil")
+	}
+	if in.AWSRequestID == "" {
+		return nil, fmt.Errorf("InvocationErrorInput.AWSRequestID is empty")
+	}
+	if in.Error == nil {
+		return nil, fmt.Errorf("InvocationErrorInput.Error is nil")
+	}
+
+	reqBody, hs, err := errorRequest(in.Error, in.XRayErrorCause)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf(invocationErrorEndpointFmt, client.Host(), in.AWSRequestID)
+	sc, _, b, err := internal.CallAPI(ctx, client, http.MethodPost, url, reqBody, hs...)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := generateInvocationErrorOutput(sc, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// If the runtime encounters an error during initialization, the runtime posts an error message to the initialization error path.
+// The runtime should exit after reporting the error.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/runtimes-api.html#runtimes-api-initerror
+func InitError(ctx context.Context, client alago.AlagoClient, in *InitErrorInput) (*InitErrorOutput, error) {
+	if in == nil {
+		return nil, fmt.Errorf("InitErrorInput is nil")
+	}
+	if in.Error == nil {
+		return nil, fmt.Errorf("InitErrorInput.Error is nil")
+	}
+
+	reqBody, hs, err := errorRequest(in.Error, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf(initErrorEndpointFmt, client.Host())
+	sc, _, b, err := internal.CallAPI(ctx, client, http.MethodPost, url, reqBody, hs...)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := generateInitErrorOutput(sc, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func generateInitErrorOutput(sc int, body []byte) (*InitErrorOutput, error) {
+	out := InitErrorOutput{}
+	out.StatusCode = sc
+
+	if sc != http.StatusAccepted {
+		var errRes ErrorResponse
+		if err := json.Unmarshal(body, &errRes); err != nil {
+			return nil, err
+		}
+		out.Error = &errRes
+		return &out, nil
+	}
+
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("err:%v, body:%s", err, string(body))
+	}
+
+	return &out, nil
+}
+
+// Runtime makes this request when it is ready to be snapshotted with Lambda SnapStart.
+// The request blocks until the snapshot is restored, and then the runtime runs the after-restore hooks.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/runtimes-api.html#runtimes-api-restore-next
+func RestoreNext(ctx context.Context, client alago.AlagoClient) (*RestoreNextOutput, error) {
+	url := fmt.Sprintf(restoreNextEndpointFmt, client.Host())
+	sc, _, b, err := internal.CallAPI(ctx, client, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := generateRestoreNextOutput(sc, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func generateRestoreNextOutput(sc int, body []byte) (*RestoreNextOutput, error) {
+	out := RestoreNextOutput{}
+	out.StatusCode = sc
+
+	if sc != http.StatusOK {
+		var errRes ErrorResponse
+		if err := json.Unmarshal(body, &errRes); err != nil {
+			return nil, err
+		}
+		out.Error = &errRes
+		return &out, nil
+	}
+
+	return &out, nil
+}
+
+// If the runtime encounters an error while running the after-restore hooks,
+// the runtime posts an error message to the restore error path.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/runtimes-api.html#runtimes-api-restore-error
+func RestoreError(ctx context.Context, client alago.AlagoClient, in *RestoreErrorInput) (*RestoreErrorOutput, error) {
+	if in == nil {
+		return nil, fmt.Errorf("RestoreErrorInput is nil")
+	}
+	if in.Error == nil {
+		return nil, fmt.Errorf("RestoreErrorInput.Error is nil")
+	}
+
+	reqBody, hs, err := errorRequest(in.Error, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf(restoreErrorEndpointFmt, client.Host())
+	sc, _, b, err := internal.CallAPI(ctx, client, http.MethodPost, url, reqBody, hs...)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := generateRestoreErrorOutput(sc, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func generateRestoreErrorOutput(sc int, body []byte) (*RestoreErrorOutput, error) {
+	out := RestoreErrorOutput{}
+	out.StatusCode = sc
+
+	if sc != http.StatusAccepted {
+		var errRes ErrorResponse
+		if err := json.Unmarshal(body, &errRes); err != nil {
+			return nil, err
+		}
+		out.Error = &errRes
+		return &out, nil
+	}
+
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("err:%v, body:%s", err, string(body))
+	}
+
+	return &out, nil
+}
+
+// errorRequest returns the request body and headers that are common to the error reporting APIs.
+func errorRequest(fe *FunctionError, cause *XRayErrorCause) (io.Reader, []internal.Header, error) {
+	j, err := json.Marshal(fe)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hs := []internal.Header{}
+	if fe.ErrorType != "" {
+		hs = append(hs, internal.Header{Key: requestHeaderLambdaRuntimeFunctionErrorType, Value: fe.ErrorType})
+	}
+
+	if cause != nil {
+		c, err := json.Marshal(cause)
+		if err != nil {
+			return nil, nil, err
+		}
+		hs = append(hs, internal.Header{Key: requestHeaderLambdaRuntimeFunctionXRayErrorCause, Value: string(c)})
+	}
+
+	return bytes.NewReader(j), hs, nil
+}
+
+func generateInvocationErrorOutput(sc int, body []byte) (*InvocationErrorOutput, error) {
+	out := InvocationErrorOutput{}
+	out.StatusCode = sc
+
+	if sc != http.StatusAccepted {
+		var errRes ErrorResponse
+		if err := json.Unmarshal(body, &errRes); err != nil {
+			return nil, err
+		}
+		out.Error = &errRes
+		return &out, nil
+	}
+
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("err:%v, body:%s", err, string(body))
+	}
+
+	return &out, nil
+}
@@ -1,7 +1,9 @@
 package runtime_test
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"net/http"
 	"strings"
 	"testing"
@@ -31,6 +33,7 @@ func Test_InvocationNext(t *testing.T) {
 					{Key: "Lambda-Runtime-Cognito-Identity", Value: "lambda-runtime-cognito-identity"},
 					{Key: "Lambda-Runtime-Deadline-Ms", Value: "lambda-runtime-deadline-ms"},
 					{Key: "Lambda-Runtime-Invoked-Function-Arn", Value: "lambda-runtime-invoked-function-arn"},
+					{Key: "Lambda-Runtime-Aws-Tenant-Id", Value: "lambda-runtime-aws-tenant-id"},
 				},
 				BodyBytes: []byte(`test-response-body`),
 			}),
@@ -43,6 +46,7 @@ func Test_InvocationNext(t *testing.T) {
 				CognitoIdentity:    "lambda-runtime-cognito-identity",
 				DeadlineMs:         "lambda-runtime-deadline-ms",
 				InvokedFunctionArn: "lambda-runtime-invoked-function-arn",
+				TenantID:           "lambda-runtime-aws-tenant-id",
 				RawEventResponse:   []byte("test-response-body"),
 			},
 			wantErr: false,
@@ -131,6 +135,7 @@ func Test_generateNextOutput(t *testing.T) {
 				"Lambda-Runtime-Cognito-Identity":     {"lambda-runtime-cognito-identity"},
 				"Lambda-Runtime-Deadline-Ms":          {"lambda-runtime-deadline-ms"},
 				"Lambda-Runtime-Invoked-Function-Arn": {"lambda-runtime-invoked-function-arn"},
+				"Lambda-Runtime-Aws-Tenant-Id":        {"lambda-runtime-aws-tenant-id"},
 			},
 			body: []byte("test-response-body"),
 			expect: &runtime.NextOutput{
@@ -141,6 +146,7 @@ func Test_generateNextOutput(t *testing.T) {
 				CognitoIdentity:    "lambda-runtime-cognito-identity",
 				DeadlineMs:         "lambda-runtime-deadline-ms",
 				InvokedFunctionArn: "lambda-runtime-invoked-function-arn",
+				TenantID:           "lambda-runtime-aws-tenant-id",
 				RawEventResponse:   []byte("test-response-body"),
 			},
 			wantErr: false,
@@ -200,6 +206,191 @@ func Test_generateNextOutput(t *testing.T) {
 	}
 }
 
+func Test_InvocationNextStream(t *testing.T) {
+	type event struct {
+		Name string `json:"name"`
+	}
+
+	cases := []struct {
+		name        string
+		httpClient  *http.Client
+		host        string
+		expect      *runtime.NextOutput
+		expectEvent *event
+		wantErr     bool
+	}{
+		{
+			name: "ok",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 200,
+				Headers: []hcmock.Header{
+					{Key: "Lambda-Runtime-Aws-Request-Id", Value: "lambda-runtime-aws-request-id"},
+					{Key: "Lambda-Runtime-Trace-Id", Value: "lambda-runtime-trace-id"},
+					{Key: "Lambda-Runtime-Client-Context", Value: "lambda-runtime-client-context"},
+					{Key: "Lambda-Runtime-Cognito-Identity", Value: "lambda-runtime-cognito-identity"},
+					{Key: "Lambda-Runtime-Deadline-Ms", Value: "lambda-runtime-deadline-ms"},
+					{Key: "Lambda-Runtime-Invoked-Function-Arn", Value: "lambda-runtime-invoked-function-arn"},
+					{Key: "Lambda-Runtime-Aws-Tenant-Id", Value: "lambda-runtime-aws-tenant-id"},
+				},
+				BodyBytes: []byte(`{"name":"test-name"}`),
+			}),
+			host: "test-host",
+			expect: &runtime.NextOutput{
+				StatusCode:         200,
+				AWSRequestID:       "lambda-runtime-aws-request-id",
+				TraceID:            "lambda-runtime-trace-id",
+				ClientContext:      "lambda-runtime-client-context",
+				CognitoIdentity:    "lambda-runtime-cognito-identity",
+				DeadlineMs:         "lambda-runtime-deadline-ms",
+				InvokedFunctionArn: "lambda-runtime-invoked-function-arn",
+				TenantID:           "lambda-runtime-aws-tenant-id",
+			},
+			expectEvent: &event{Name: "test-name"},
+			wantErr:     false,
+		},
+		{
+			name: "ok: not OK status code",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 500,
+				BodyBytes:  []byte(`{"errorMessage":"test-error-message","errorType":"test-error-type"}`),
+			}),
+			host: "test-host",
+			expect: &runtime.NextOutput{
+				StatusCode: 500,
+				Error: &runtime.ErrorResponse{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ng: CallAPIStream returns error",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 200,
+				BodyBytes:  []byte(`{"name":"test-name"}`),
+			}),
+			host:    "\U00000001",
+			expect:  nil,
+			wantErr: true,
+		},
+		{
+			name: "ng: generateNextStreamOutput returns error",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 403,
+				BodyBytes:  []byte(`///`),
+			}),
+			host:    "test-host",
+			expect:  nil,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			tt.Setenv("AWS_LAMBDA_RUNTIME_API", c.host)
+
+			ac, err := alago.NewClient(&alago.NewClientInput{
+				HttpClient: c.httpClient,
+			})
+
+			asst.NoError(err)
+
+			out, err := runtime.InvocationNextStream(context.Background(), ac)
+			if c.wantErr {
+				asst.Error(err, err)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.NotNil(out)
+			asst.Equal(*c.expect, out.NextOutput)
+
+			if c.expectEvent == nil {
+				asst.Nil(out.Body)
+				return
+			}
+
+			e := event{}
+			asst.NoError(out.Decode(&e))
+			asst.Equal(*c.expectEvent, e)
+		})
+	}
+}
+
+func Test_generateNextStreamOutput(t *testing.T) {
+	cases := []struct {
+		name    string
+		sc      int
+		header  http.Header
+		body    string
+		expect  *runtime.NextOutput
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			sc:   200,
+			header: http.Header{
+				"Lambda-Runtime-Aws-Request-Id": {"lambda-runtime-aws-request-id"},
+				"Lambda-Runtime-Deadline-Ms":    {"lambda-runtime-deadline-ms"},
+			},
+			body: `test-response-body`,
+			expect: &runtime.NextOutput{
+				StatusCode:   200,
+				AWSRequestID: "lambda-runtime-aws-request-id",
+				DeadlineMs:   "lambda-runtime-deadline-ms",
+			},
+			wantErr: false,
+		},
+		{
+			name: "ok: not OK status code",
+			sc:   400,
+			body: `{"errorMessage":"test-error-message","errorType":"test-error-type"}`,
+			expect: &runtime.NextOutput{
+				StatusCode: 400,
+				Error: &runtime.ErrorResponse{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "ng: invalid error response",
+			sc:      400,
+			body:    `///`,
+			expect:  nil,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := runtime.Exported_generateNextStreamOutput(c.sc, c.header, io.NopCloser(strings.NewReader(c.body)))
+			if c.wantErr {
+				asst.Error(err)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(*c.expect, out.NextOutput)
+			if c.expect.Error != nil {
+				asst.Nil(out.Body)
+				return
+			}
+
+			b, err := io.ReadAll(out.Body)
+			asst.NoError(err)
+			asst.Equal(c.body, string(b))
+		})
+	}
+}
+
 func Test_InvocationResponse(t *testing.T) {
 	cases := []struct {
 		name       string
@@ -320,6 +511,115 @@ func Test_InvocationResponse(t *testing.T) {
 	}
 }
 
+func Test_InvocationResponse_payloadTooLarge(t *testing.T) {
+	// the limit stated by Lambda, that is 100 bytes more than 6 MiB
+	const limit = 6291556
+	assert.Equal(t, int64(limit), runtime.MaxResponsePayloadSize)
+
+	cases := []struct {
+		name        string
+		response    io.Reader
+		report      bool
+		expectPaths []string
+		expectSize  int64
+		wantErr     bool
+	}{
+		{
+			name:        "ok: payload size is equal to the limit",
+			response:    bytes.NewReader(make([]byte, limit)),
+			expectPaths: []string{"/2018-06-01/runtime/invocation/test-request-id/response"},
+			wantErr:     false,
+		},
+		{
+			name:        "ok: payload size is equal to the limit, size is unknown",
+			response:    io.MultiReader(bytes.NewReader(make([]byte, limit))),
+			expectPaths: []string{"/2018-06-01/runtime/invocation/test-request-id/response"},
+			wantErr:     false,
+		},
+		{
+			name:        "ok: payload size is more than 6 MiB and less than the limit",
+			response:    bytes.NewReader(make([]byte, 6*1024*1024+1)),
+			expectPaths: []string{"/2018-06-01/runtime/invocation/test-request-id/response"},
+			wantErr:     false,
+		},
+		{
+			name:        "ng: payload size exceeds the limit by 1 byte",
+			response:    bytes.NewReader(make([]byte, limit+1)),
+			expectPaths: []string{},
+			expectSize:  int64(limit + 1),
+			wantErr:     true,
+		},
+		{
+			name:        "ng: payload size exceeds the limit",
+			response:    bytes.NewReader(make([]byte, limit+10)),
+			expectPaths: []string{},
+			expectSize:  int64(limit + 10),
+			wantErr:     true,
+		},
+		{
+			name:        "ng: payload size exceeds the limit, size is unknown",
+			response:    io.MultiReader(bytes.NewReader(make([]byte, limit+10))),
+			expectPaths: []string{},
+			expectSize:  int64(limit + 1),
+			wantErr:     true,
+		},
+		{
+			name:        "ng: payload size exceeds the limit, reported",
+			response:    bytes.NewReader(make([]byte, limit+10)),
+			report:      true,
+			expectPaths: []string{"/2018-06-01/runtime/invocation/test-request-id/error"},
+			expectSize:  int64(limit + 10),
+			wantErr:     true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			paths := []string{}
+			var errorType string
+			hc := &http.Client{
+				Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					paths = append(paths, r.URL.Path)
+					errorType = r.Header.Get("Lambda-Runtime-Function-Error-Type")
+					_, _ = io.Copy(io.Discard, r.Body)
+					return &http.Response{
+						StatusCode: 202,
+						Body:       io.NopCloser(strings.NewReader(`{"status":"OK"}`)),
+					}, nil
+				}),
+			}
+
+			tt.Setenv("AWS_LAMBDA_RUNTIME_API", "test-host")
+			ac, err := alago.NewClient(&alago.NewClientInput{HttpClient: hc})
+			asst.NoError(err)
+
+			out, err := runtime.InvocationResponse(context.Background(), ac, &runtime.ResponseInput{
+				AWSRequestID:          "test-request-id",
+				Response:              c.response,
+				ReportPayloadTooLarge: c.report,
+			})
+			asst.Equal(c.expectPaths, paths)
+			if c.wantErr {
+				asst.ErrorIs(err, runtime.ErrPayloadTooLarge)
+				var perr *runtime.PayloadTooLargeError
+				asst.ErrorAs(err, &perr)
+				asst.Equal(c.expectSize, perr.Size)
+				asst.Equal(runtime.MaxResponsePayloadSize, perr.Limit)
+				asst.Nil(out)
+				if c.report {
+					asst.Equal(runtime.ErrorTypeResponseSizeTooLarge, errorType)
+				}
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(202, out.StatusCode)
+		})
+	}
+}
+
 func Test_generateResponseOutput(t *testing.T) {
 	cases := []struct {
 		name       string
@@ -383,3 +683,837 @@ func Test_generateResponseOutput(t *testing.T) {
 		})
 	}
 }
+
+func Test_InvocationError(t *testing.T) {
+	cases := []struct {
+		name       string
+		httpClient *http.Client
+		in         *runtime.InvocationErrorInput
+		host       string
+		expect     *runtime.InvocationErrorOutput
+		wantErr    bool
+	}{
+		{
+			name: "ok",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in: &runtime.InvocationErrorInput{
+				AWSRequestID: "test-request-id",
+				Error: &runtime.FunctionError{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+					StackTrace:   []string{"test-stack-trace"},
+				},
+			},
+			host: "test-host",
+			expect: &runtime.InvocationErrorOutput{
+				StatusCode: 202,
+				Status:     "OK",
+			},
+			wantErr: false,
+		},
+		{
+			name: "ok: with X-Ray error cause",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in: &runtime.InvocationErrorInput{
+				AWSRequestID: "test-request-id",
+				Error: &runtime.FunctionError{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+				},
+				XRayErrorCause: &runtime.XRayErrorCause{
+					WorkingDirectory: "/var/task",
+					Exceptions: []runtime.XRayErrorException{
+						{Type: "test-error-type", Message: "test-error-message"},
+					},
+				},
+			},
+			host: "test-host",
+			expect: &runtime.InvocationErrorOutput{
+				StatusCode: 202,
+				Status:     "OK",
+			},
+			wantErr: false,
+		},
+		{
+			name: "ok: not OK status code",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 400,
+				BodyBytes:  []byte(`{"errorMessage":"test-error-message", "errorType":"InvalidRequestID"}`),
+			}),
+			in: &runtime.InvocationErrorInput{
+				AWSRequestID: "test-request-id",
+				Error:        &runtime.FunctionError{ErrorMessage: "test-error-message"},
+			},
+			host: "test-host",
+			expect: &runtime.InvocationErrorOutput{
+				StatusCode: 400,
+				Error: &runtime.ErrorResponse{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "InvalidRequestID",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ng: CallAPI returns error",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in: &runtime.InvocationErrorInput{
+				AWSRequestID: "test-request-id",
+				Error:        &runtime.FunctionError{ErrorMessage: "test-error-message"},
+			},
+			host:    "\U00000001",
+			expect:  nil,
+			wantErr: true,
+		},
+		{
+			name: "ng: AWSRequestID is empty",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in: &runtime.InvocationErrorInput{
+				Error: &runtime.FunctionError{ErrorMessage: "test-error-message"},
+			},
+			host:    "test-host",
+			expect:  nil,
+			wantErr: true,
+		},
+		{
+			name: "ng: Error is nil",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in: &runtime.InvocationErrorInput{
+				AWSRequestID: "test-request-id",
+			},
+			host:    "test-host",
+			expect:  nil,
+			wantErr: true,
+		},
+		{
+			name: "ng: InvocationErrorInput is nil",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in:      nil,
+			host:    "test-host",
+			expect:  nil,
+			wantErr: true,
+		},
+		{
+			name: "ng: generateInvocationErrorOutput returns error",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 403,
+				BodyBytes:  []byte(`///`),
+			}),
+			in: &runtime.InvocationErrorInput{
+				AWSRequestID: "test-request-id",
+				Error:        &runtime.FunctionError{ErrorMessage: "test-error-message"},
+			},
+			host:    "test-host",
+			expect:  nil,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			tt.Setenv("AWS_LAMBDA_RUNTIME_API", c.host)
+
+			ac, err := alago.NewClient(&alago.NewClientInput{
+				HttpClient: c.httpClient,
+			})
+
+			asst.NoError(err)
+
+			out, err := runtime.InvocationError(context.Background(), ac, c.in)
+			if c.wantErr {
+				asst.Error(err, err)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.NotNil(out)
+			asst.Equal(*c.expect, *out)
+		})
+	}
+}
+
+func Test_InvocationError_request(t *testing.T) {
+	asst := assert.New(t)
+
+	var req *http.Request
+	var body []byte
+	hc := &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			req = r
+			body, _ = io.ReadAll(r.Body)
+			return &http.Response{
+				StatusCode: 202,
+				Body:       io.NopCloser(strings.NewReader(`{"status":"OK"}`)),
+			}, nil
+		}),
+	}
+
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", "test-host")
+	ac, err := alago.NewClient(&alago.NewClientInput{HttpClient: hc})
+	asst.NoError(err)
+
+	_, err = runtime.InvocationError(context.Background(), ac, &runtime.InvocationErrorInput{
+		AWSRequestID: "test-request-id",
+		Error: &runtime.FunctionError{
+			ErrorMessage: "test-error-message",
+			ErrorType:    "test-error-type",
+			StackTrace:   []string{"test-stack-trace"},
+		},
+		XRayErrorCause: &runtime.XRayErrorCause{WorkingDirectory: "/var/task"},
+	})
+	asst.NoError(err)
+
+	asst.Equal(http.MethodPost, req.Method)
+	asst.Equal("http://test-host/2018-06-01/runtime/invocation/test-request-id/error", req.URL.String())
+	asst.Equal("test-error-type", req.Header.Get("Lambda-Runtime-Function-Error-Type"))
+	asst.JSONEq(`{"working_directory":"/var/task","exceptions":null,"paths":null}`, req.Header.Get("Lambda-Runtime-Function-XRay-Error-Cause"))
+	asst.JSONEq(`{"errorMessage":"test-error-message","errorType":"test-error-type","stackTrace":["test-stack-trace"]}`, string(body))
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func Test_generateInvocationErrorOutput(t *testing.T) {
+	cases := []struct {
+		name       string
+		statusCode int
+		body       []byte
+		expect     *runtime.InvocationErrorOutput
+		wantErr    bool
+	}{
+		{
+			name:       "ok",
+			statusCode: 202,
+			body:       []byte(`{"status":"OK"}`),
+			expect: &runtime.InvocationErrorOutput{
+				StatusCode: 202,
+				Status:     "OK",
+			},
+			wantErr: false,
+		},
+		{
+			name:       "ok: not OK status code",
+			statusCode: 500,
+			body:       []byte(`{"errorMessage":"test-error-message", "errorType":"test-error-type"}`),
+			expect: &runtime.InvocationErrorOutput{
+				StatusCode: 500,
+				Error: &runtime.ErrorResponse{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:       "ng: failed to unmarshal ok response",
+			statusCode: 202,
+			body:       []byte(`///`),
+			expect:     nil,
+			wantErr:    true,
+		},
+		{
+			name:       "ng: failed to unmarshal error response",
+			statusCode: 403,
+			body:       []byte(`///`),
+			expect:     nil,
+			wantErr:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := runtime.Exported_generateInvocationErrorOutput(c.statusCode, c.body)
+			if c.wantErr {
+				asst.Error(err)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(*c.expect, *out)
+		})
+	}
+}
+
+func Test_InitError(t *testing.T) {
+	cases := []struct {
+		name       string
+		httpClient *http.Client
+		in         *runtime.InitErrorInput
+		host       string
+		expect     *runtime.InitErrorOutput
+		wantErr    bool
+	}{
+		{
+			name: "ok",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in: &runtime.InitErrorInput{
+				Error: &runtime.FunctionError{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+				},
+			},
+			host: "test-host",
+			expect: &runtime.InitErrorOutput{
+				StatusCode: 202,
+				Status:     "OK",
+			},
+			wantErr: false,
+		},
+		{
+			name: "ok: not OK status code",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 403,
+				BodyBytes:  []byte(`{"errorMessage":"test-error-message", "errorType":"test-error-type"}`),
+			}),
+			in: &runtime.InitErrorInput{
+				Error: &runtime.FunctionError{ErrorMessage: "test-error-message"},
+			},
+			host: "test-host",
+			expect: &runtime.InitErrorOutput{
+				StatusCode: 403,
+				Error: &runtime.ErrorResponse{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ng: CallAPI returns error",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in: &runtime.InitErrorInput{
+				Error: &runtime.FunctionError{ErrorMessage: "test-error-message"},
+			},
+			host:    "\U00000001",
+			expect:  nil,
+			wantErr: true,
+		},
+		{
+			name: "ng: Error is nil",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in:      &runtime.InitErrorInput{},
+			host:    "test-host",
+			expect:  nil,
+			wantErr: true,
+		},
+		{
+			name: "ng: InitErrorInput is nil",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in:      nil,
+			host:    "test-host",
+			expect:  nil,
+			wantErr: true,
+		},
+		{
+			name: "ng: generateInitErrorOutput returns error",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 403,
+				BodyBytes:  []byte(`///`),
+			}),
+			in: &runtime.InitErrorInput{
+				Error: &runtime.FunctionError{ErrorMessage: "test-error-message"},
+			},
+			host:    "test-host",
+			expect:  nil,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			tt.Setenv("AWS_LAMBDA_RUNTIME_API", c.host)
+
+			ac, err := alago.NewClient(&alago.NewClientInput{
+				HttpClient: c.httpClient,
+			})
+
+			asst.NoError(err)
+
+			out, err := runtime.InitError(context.Background(), ac, c.in)
+			if c.wantErr {
+				asst.Error(err, err)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.NotNil(out)
+			asst.Equal(*c.expect, *out)
+		})
+	}
+}
+
+func Test_generateInitErrorOutput(t *testing.T) {
+	cases := []struct {
+		name       string
+		statusCode int
+		body       []byte
+		expect     *runtime.InitErrorOutput
+		wantErr    bool
+	}{
+		{
+			name:       "ok",
+			statusCode: 202,
+			body:       []byte(`{"status":"OK"}`),
+			expect: &runtime.InitErrorOutput{
+				StatusCode: 202,
+				Status:     "OK",
+			},
+			wantErr: false,
+		},
+		{
+			name:       "ok: not OK status code",
+			statusCode: 500,
+			body:       []byte(`{"errorMessage":"test-error-message", "errorType":"test-error-type"}`),
+			expect: &runtime.InitErrorOutput{
+				StatusCode: 500,
+				Error: &runtime.ErrorResponse{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:       "ng: failed to unmarshal ok response",
+			statusCode: 202,
+			body:       []byte(`///`),
+			expect:     nil,
+			wantErr:    true,
+		},
+		{
+			name:       "ng: failed to unmarshal error response",
+			statusCode: 403,
+			body:       []byte(`///`),
+			expect:     nil,
+			wantErr:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := runtime.Exported_generateInitErrorOutput(c.statusCode, c.body)
+			if c.wantErr {
+				asst.Error(err)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(*c.expect, *out)
+		})
+	}
+}
+
+func Test_canceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", "127.0.0.1:1")
+	ac, err := alago.NewClient(&alago.NewClientInput{HttpClient: &http.Client{}})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name string
+		call func() (any, error)
+	}{
+		{
+			name: "InvocationNext",
+			call: func() (any, error) {
+				return runtime.InvocationNext(ctx, ac)
+			},
+		},
+		{
+			name: "InvocationResponse",
+			call: func() (any, error) {
+				return runtime.InvocationResponse(ctx, ac, &runtime.ResponseInput{AWSRequestID: "test-request-id", Response: strings.NewReader("test")})
+			},
+		},
+		{
+			name: "InvocationError",
+			call: func() (any, error) {
+				return runtime.InvocationError(ctx, ac, &runtime.InvocationErrorInput{AWSRequestID: "test-request-id", Error: &runtime.FunctionError{}})
+			},
+		},
+		{
+			name: "InitError",
+			call: func() (any, error) {
+				return runtime.InitError(ctx, ac, &runtime.InitErrorInput{Error: &runtime.FunctionError{}})
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := c.call()
+			asst.ErrorIs(err, context.Canceled)
+			asst.Nil(out)
+		})
+	}
+}
+
+func Test_RestoreNext(t *testing.T) {
+	cases := []struct {
+		name       string
+		httpClient *http.Client
+		host       string
+		expect     *runtime.RestoreNextOutput
+		wantErr    bool
+	}{
+		{
+			name: "ok",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 200,
+			}),
+			host: "test-host",
+			expect: &runtime.RestoreNextOutput{
+				StatusCode: 200,
+			},
+			wantErr: false,
+		},
+		{
+			name: "ok: not OK status code",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 500,
+				BodyBytes:  []byte(`{"errorMessage":"test-error-message", "errorType":"test-error-type"}`),
+			}),
+			host: "test-host",
+			expect: &runtime.RestoreNextOutput{
+				StatusCode: 500,
+				Error: &runtime.ErrorResponse{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ng: CallAPI returns error",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 200,
+			}),
+			host:    "\U00000001",
+			expect:  nil,
+			wantErr: true,
+		},
+		{
+			name: "ng: generateRestoreNextOutput returns error",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 500,
+				BodyBytes:  []byte(`///`),
+			}),
+			host:    "test-host",
+			expect:  nil,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			tt.Setenv("AWS_LAMBDA_RUNTIME_API", c.host)
+
+			ac, err := alago.NewClient(&alago.NewClientInput{
+				HttpClient: c.httpClient,
+			})
+
+			asst.NoError(err)
+
+			out, err := runtime.RestoreNext(context.Background(), ac)
+			if c.wantErr {
+				asst.Error(err, err)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.NotNil(out)
+			asst.Equal(*c.expect, *out)
+		})
+	}
+}
+
+func Test_generateRestoreNextOutput(t *testing.T) {
+	cases := []struct {
+		name       string
+		statusCode int
+		body       []byte
+		expect     *runtime.RestoreNextOutput
+		wantErr    bool
+	}{
+		{
+			name:       "ok",
+			statusCode: 200,
+			body:       nil,
+			expect: &runtime.RestoreNextOutput{
+				StatusCode: 200,
+			},
+			wantErr: false,
+		},
+		{
+			name:       "ok: not OK status code",
+			statusCode: 500,
+			body:       []byte(`{"errorMessage":"test-error-message", "errorType":"test-error-type"}`),
+			expect: &runtime.RestoreNextOutput{
+				StatusCode: 500,
+				Error: &runtime.ErrorResponse{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:       "ng: failed to unmarshal error response",
+			statusCode: 500,
+			body:       []byte(`///`),
+			expect:     nil,
+			wantErr:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := runtime.Exported_generateRestoreNextOutput(c.statusCode, c.body)
+			if c.wantErr {
+				asst.Error(err)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(*c.expect, *out)
+		})
+	}
+}
+
+func Test_RestoreError(t *testing.T) {
+	cases := []struct {
+		name       string
+		httpClient *http.Client
+		in         *runtime.RestoreErrorInput
+		host       string
+		expect     *runtime.RestoreErrorOutput
+		wantErr    bool
+	}{
+		{
+			name: "ok",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in: &runtime.RestoreErrorInput{
+				Error: &runtime.FunctionError{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+				},
+			},
+			host: "test-host",
+			expect: &runtime.RestoreErrorOutput{
+				StatusCode: 202,
+				Status:     "OK",
+			},
+			wantErr: false,
+		},
+		{
+			name: "ok: not OK status code",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 403,
+				BodyBytes:  []byte(`{"errorMessage":"test-error-message", "errorType":"test-error-type"}`),
+			}),
+			in: &runtime.RestoreErrorInput{
+				Error: &runtime.FunctionError{ErrorMessage: "test-error-message"},
+			},
+			host: "test-host",
+			expect: &runtime.RestoreErrorOutput{
+				StatusCode: 403,
+				Error: &runtime.ErrorResponse{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ng: CallAPI returns error",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in: &runtime.RestoreErrorInput{
+				Error: &runtime.FunctionError{ErrorMessage: "test-error-message"},
+			},
+			host:    "\U00000001",
+			expect:  nil,
+			wantErr: true,
+		},
+		{
+			name: "ng: Error is nil",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in:      &runtime.RestoreErrorInput{},
+			host:    "test-host",
+			expect:  nil,
+			wantErr: true,
+		},
+		{
+			name: "ng: RestoreErrorInput is nil",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 202,
+				BodyBytes:  []byte(`{"status":"OK"}`),
+			}),
+			in:      nil,
+			host:    "test-host",
+			expect:  nil,
+			wantErr: true,
+		},
+		{
+			name: "ng: generateRestoreErrorOutput returns error",
+			httpClient: hcmock.New(&hcmock.MockInput{
+				StatusCode: 403,
+				BodyBytes:  []byte(`///`),
+			}),
+			in: &runtime.RestoreErrorInput{
+				Error: &runtime.FunctionError{ErrorMessage: "test-error-message"},
+			},
+			host:    "test-host",
+			expect:  nil,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			tt.Setenv("AWS_LAMBDA_RUNTIME_API", c.host)
+
+			ac, err := alago.NewClient(&alago.NewClientInput{
+				HttpClient: c.httpClient,
+			})
+
+			asst.NoError(err)
+
+			out, err := runtime.RestoreError(context.Background(), ac, c.in)
+			if c.wantErr {
+				asst.Error(err, err)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.NotNil(out)
+			asst.Equal(*c.expect, *out)
+		})
+	}
+}
+
+func Test_generateRestoreErrorOutput(t *testing.T) {
+	cases := []struct {
+		name       string
+		statusCode int
+		body       []byte
+		expect     *runtime.RestoreErrorOutput
+		wantErr    bool
+	}{
+		{
+			name:       "ok",
+			statusCode: 202,
+			body:       []byte(`{"status":"OK"}`),
+			expect: &runtime.RestoreErrorOutput{
+				StatusCode: 202,
+				Status:     "OK",
+			},
+			wantErr: false,
+		},
+		{
+			name:       "ok: not OK status code",
+			statusCode: 500,
+			body:       []byte(`{"errorMessage":"test-error-message", "errorType":"test-error-type"}`),
+			expect: &runtime.RestoreErrorOutput{
+				StatusCode: 500,
+				Error: &runtime.ErrorResponse{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "test-error-type",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:       "ng: failed to unmarshal ok response",
+			statusCode: 202,
+			body:       []byte(`///`),
+			expect:     nil,
+			wantErr:    true,
+		},
+		{
+			name:       "ng: failed to unmarshal error response",
+			statusCode: 403,
+			body:       []byte(`///`),
+			expect:     nil,
+			wantErr:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := runtime.Exported_generateRestoreErrorOutput(c.statusCode, c.body)
+			if c.wantErr {
+				asst.Error(err)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(*c.expect, *out)
+		})
+	}
+}
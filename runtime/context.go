@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"context"
+	"time"
+)
+
+// Invocation is the metadata of an invocation, that is got by InvocationNext.
+type Invocation struct {
+	// AWS request ID associated with the request.
+	AWSRequestID string
+
+	// X-Ray tracing header.
+	TraceID string
+
+	// Parsed X-Ray tracing header. It is nil if TraceID is empty or invalid.
+	TraceHeader *TraceHeader
+
+	// Information about the client application and device when invoked through the AWS Mobile SDK.
+	ClientContext string
+
+	// Information about the Amazon Cognito identity provider when invoked through the AWS Mobile SDK.
+	CognitoIdentity string
+
+	// Function execution deadline. It is zero if Lambda-Runtime-Deadline-Ms is missing or invalid.
+	Deadline time.Time
+
+	// The ARN requested. This can be different in each invoke that executes the same version.
+	InvokedFunctionArn string
+
+	// The tenant ID of the invocation. Filled only for the functions with tenant isolation mode.
+	TenantID string
+}
+
+type invocationContextKey struct{}
+
+// NewInvocationContext returns a copy of parent that carries the metadata of the invocation.
+// The deadline of the returned context is the function execution deadline
+// given by Lambda-Runtime-Deadline-Ms, if it is valid.
+// Canceling the returned context releases resources associated with it,
+// so the caller should call cancel as soon as the invocation completes.
+func NewInvocationContext(parent context.Context, next *NextOutput) (context.Context, context.CancelFunc) {
+	inv := newInvocation(next)
+	ctx := context.WithValue(parent, invocationContextKey{}, inv)
+
+	if inv.Deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithDeadline(ctx, inv.Deadline)
+}
+
+func newInvocation(next *NextOutput) *Invocation {
+	inv := &Invocation{}
+	if next == nil {
+		return inv
+	}
+
+	inv.AWSRequestID = next.AWSRequestID
+	inv.TraceID = next.TraceID
+	inv.ClientContext = next.ClientContext
+	inv.CognitoIdentity = next.CognitoIdentity
+	inv.InvokedFunctionArn = next.InvokedFunctionArn
+	inv.TenantID = next.TenantID
+
+	if th, err := ParseTraceHeader(next.TraceID); err == nil {
+		inv.TraceHeader = th
+	}
+
+	if d, err := next.Deadline(); err == nil {
+		inv.Deadline = d
+	}
+
+	return inv
+}
+
+// InvocationFromContext returns the metadata of the invocation stored in ctx by NewInvocationContext.
+func InvocationFromContext(ctx context.Context) (*Invocation, bool) {
+	inv, ok := ctx.Value(invocationContextKey{}).(*Invocation)
+	return inv, ok
+}
+
+// RequestIDFromContext returns the AWS request ID of the invocation stored in ctx by NewInvocationContext.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	inv, ok := InvocationFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return inv.AWSRequestID, true
+}
@@ -0,0 +1,103 @@
+package runtime_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewInvocationContext(t *testing.T) {
+	deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+
+	cases := []struct {
+		name           string
+		next           *runtime.NextOutput
+		expect         *runtime.Invocation
+		expectDeadline bool
+	}{
+		{
+			name: "ok",
+			next: &runtime.NextOutput{
+				AWSRequestID:       "test-request-id",
+				TraceID:            "test-trace-id",
+				ClientContext:      "test-client-context",
+				CognitoIdentity:    "test-cognito-identity",
+				DeadlineMs:         strconv.FormatInt(deadline.UnixMilli(), 10),
+				InvokedFunctionArn: "test-function-arn",
+			},
+			expect: &runtime.Invocation{
+				AWSRequestID:       "test-request-id",
+				TraceID:            "test-trace-id",
+				ClientContext:      "test-client-context",
+				CognitoIdentity:    "test-cognito-identity",
+				Deadline:           deadline,
+				InvokedFunctionArn: "test-function-arn",
+			},
+			expectDeadline: true,
+		},
+		{
+			name: "ok: invalid deadline",
+			next: &runtime.NextOutput{
+				AWSRequestID: "test-request-id",
+				DeadlineMs:   "invalid",
+			},
+			expect: &runtime.Invocation{
+				AWSRequestID: "test-request-id",
+			},
+			expectDeadline: false,
+		},
+		{
+			name:           "ok: NextOutput is nil",
+			next:           nil,
+			expect:         &runtime.Invocation{},
+			expectDeadline: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			ctx, cancel := runtime.NewInvocationContext(context.Background(), c.next)
+			defer cancel()
+
+			inv, ok := runtime.InvocationFromContext(ctx)
+			asst.True(ok)
+			asst.Equal(c.expect.AWSRequestID, inv.AWSRequestID)
+			asst.Equal(c.expect.TraceID, inv.TraceID)
+			asst.Equal(c.expect.ClientContext, inv.ClientContext)
+			asst.Equal(c.expect.CognitoIdentity, inv.CognitoIdentity)
+			asst.Equal(c.expect.InvokedFunctionArn, inv.InvokedFunctionArn)
+			asst.True(c.expect.Deadline.Equal(inv.Deadline))
+
+			id, ok := runtime.RequestIDFromContext(ctx)
+			asst.True(ok)
+			asst.Equal(c.expect.AWSRequestID, id)
+
+			d, ok := ctx.Deadline()
+			asst.Equal(c.expectDeadline, ok)
+			if c.expectDeadline {
+				asst.True(deadline.Equal(d))
+			}
+
+			cancel()
+			asst.Error(ctx.Err())
+		})
+	}
+}
+
+func Test_InvocationFromContext(t *testing.T) {
+	asst := assert.New(t)
+
+	inv, ok := runtime.InvocationFromContext(context.Background())
+	asst.False(ok)
+	asst.Nil(inv)
+
+	id, ok := runtime.RequestIDFromContext(context.Background())
+	asst.False(ok)
+	asst.Equal("", id)
+}
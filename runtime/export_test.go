@@ -1,6 +1,11 @@
 package runtime
 
 var (
-	Exported_generateNextOutput     = generateNextOutput
-	Exported_generateResponseOutput = generateResponseOutput
+	Exported_generateNextOutput            = generateNextOutput
+	Exported_generateNextStreamOutput      = generateNextStreamOutput
+	Exported_generateResponseOutput        = generateResponseOutput
+	Exported_generateInvocationErrorOutput = generateInvocationErrorOutput
+	Exported_generateInitErrorOutput       = generateInitErrorOutput
+	Exported_generateRestoreNextOutput     = generateRestoreNextOutput
+	Exported_generateRestoreErrorOutput    = generateRestoreErrorOutput
 )
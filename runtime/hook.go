@@ -0,0 +1,135 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultPostResponseTimeout time.Duration = 1 * time.Second
+
+// ErrNoPostResponseHooks is returned by RegisterPostResponseHook
+// when the context is not the one passed to the handler by Start.
+var ErrNoPostResponseHooks = errors.New("post-response hooks are not available in the context")
+
+// PostResponseHook is the function that runs after the response of the invocation
+// has been sent by InvocationResponse and before the next invocation is requested,
+// e.g. to flush metrics or close spans.
+// ctx carries the metadata of the invocation and is canceled when the time budget is exceeded.
+type PostResponseHook func(ctx context.Context, inv *Invocation)
+
+type postResponseHooks struct {
+	mu    sync.Mutex
+	hooks []PostResponseHook
+}
+
+type postResponseHooksContextKey struct{}
+
+// RegisterPostResponseHook registers the hook that runs after the response of the invocation
+// stored in ctx has been sent. ctx must be the context passed to the handler by Start,
+// otherwise it returns ErrNoPostResponseHooks.
+// The hooks run in the order of registration, after the ones set by WithPostResponseHooks.
+// They do not run if the handler or posting the response fails. A nil hook is ignored.
+func RegisterPostResponseHook(ctx context.Context, hook PostResponseHook) error {
+	h, ok := ctx.Value(postResponseHooksContextKey{}).(*postResponseHooks)
+	if !ok {
+		return ErrNoPostResponseHooks
+	}
+	if hook == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks = append(h.hooks, hook)
+	return nil
+}
+
+// WithPostResponseHooks sets the hooks that run after the response of every invocation has been sent.
+// nil hooks are ignored.
+func WithPostResponseHooks(hooks ...PostResponseHook) Option {
+	return func(o *options) {
+		for _, h := range hooks {
+			if h != nil {
+				o.postResponseHooks = append(o.postResponseHooks, h)
+			}
+		}
+	}
+}
+
+// WithPostResponseTimeout sets the time budget of the post-response hooks of each invocation.
+// When it is exceeded, the context passed to the hooks is canceled and the loop requests
+// the next invocation without waiting for the hooks to return.
+// If it is not set, 1 second is used.
+func WithPostResponseTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.postResponseTimeout = d
+	}
+}
+
+func withPostResponseHooks(ctx context.Context) (context.Context, *postResponseHooks) {
+	h := &postResponseHooks{}
+	return context.WithValue(ctx, postResponseHooksContextKey{}, h), h
+}
+
+// runPostResponseHooks runs the global hooks and the hooks registered on the invocation,
+// and waits for them to return within the time budget.
+// The context of the hooks is derived from ctx, that is the context of the loop,
+// since the context of the invocation may be already done.
+//
+// A panic in a hook is recovered and written to stderr with the stack trace, since the response
+// has already been sent, and the remaining hooks still run. Then, with PanicPolicyExit,
+// the first panic is returned as *PanicError to stop the loop.
+func (r *runner) runPostResponseHooks(ctx context.Context, inv *Invocation, h *postResponseHooks) error {
+	h.mu.Lock()
+	hooks := append(append([]PostResponseHook{}, r.postResponseHooks...), h.hooks...)
+	h.mu.Unlock()
+
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithValue(ctx, invocationContextKey{}, inv), r.postResponseTimeout)
+	defer cancel()
+
+	var perr *PanicError
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, hook := range hooks {
+			if ctx.Err() != nil {
+				return
+			}
+			if p := callPostResponseHook(ctx, hook, inv); p != nil {
+				writePostResponseHookPanic(os.Stderr, inv, p)
+				if perr == nil {
+					perr = p
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil
+	}
+
+	if perr != nil && r.panicPolicy != PanicPolicyContinue {
+		return perr
+	}
+	return nil
+}
+
+func writePostResponseHookPanic(w io.Writer, inv *Invocation, perr *PanicError) {
+	fe := perr.FunctionError()
+	fmt.Fprintf(w, "post-response hook of %s panicked: %s\n", inv.AWSRequestID, fe.ErrorMessage)
+	if len(fe.StackTrace) > 0 {
+		fmt.Fprintln(w, strings.Join(fe.StackTrace, "\n"))
+	}
+}
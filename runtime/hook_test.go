@@ -0,0 +1,213 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/michimani/aws-lambda-api-go/internal/runtimetest"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Start_postResponseHooks(t *testing.T) {
+	type hookCall struct {
+		name      string
+		requestID string
+		fromCtx   string
+		remaining int
+		results   int
+	}
+
+	asst := assert.New(t)
+	f := runtimetest.NewServer(t,
+		runtimetest.Event{RequestID: "req-1", Body: `{"name":"alice"}`},
+		runtimetest.Event{RequestID: "req-2", Body: `{}`},
+	)
+
+	mu := sync.Mutex{}
+	calls := []hookCall{}
+	hook := func(name string) runtime.PostResponseHook {
+		return func(ctx context.Context, inv *runtime.Invocation) {
+			id, _ := runtime.RequestIDFromContext(ctx)
+			remaining := f.RemainingEvents()
+			results := len(f.Results())
+
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, hookCall{name: name, requestID: inv.AWSRequestID, fromCtx: id, remaining: remaining, results: results})
+		}
+	}
+
+	err := runtime.Start(func(ctx context.Context, in testInput) (*testOutput, error) {
+		asst.NoError(runtime.RegisterPostResponseHook(ctx, hook("invocation-1")))
+		asst.NoError(runtime.RegisterPostResponseHook(ctx, hook("invocation-2")))
+		if in.Name == "" {
+			return nil, errors.New("name is empty")
+		}
+		return &testOutput{Message: "hello " + in.Name}, nil
+	}, runtime.WithClient(f.Client(t)), runtime.WithPostResponseHooks(hook("global")))
+
+	var rae *runtime.RuntimeAPIError
+	asst.ErrorAs(err, &rae)
+
+	// the hooks run after the response is sent and before the next invocation is requested,
+	// and do not run when the handler fails.
+	asst.Equal([]hookCall{
+		{name: "global", requestID: "req-1", fromCtx: "req-1", remaining: 1, results: 1},
+		{name: "invocation-1", requestID: "req-1", fromCtx: "req-1", remaining: 1, results: 1},
+		{name: "invocation-2", requestID: "req-1", fromCtx: "req-1", remaining: 1, results: 1},
+	}, calls)
+}
+
+func Test_Start_postResponseHooksStream(t *testing.T) {
+	asst := assert.New(t)
+	f := runtimetest.NewServer(t,
+		runtimetest.Event{RequestID: "req-1", Body: `"ok"`},
+		runtimetest.Event{RequestID: "req-2", Body: `"error"`},
+		runtimetest.Event{RequestID: "req-3", Body: `"panic"`},
+	)
+
+	calls := []string{}
+	err := runtime.StartHandler(func(ctx context.Context, event []byte) ([]byte, error) {
+		w, err := runtime.OpenResponseStream(ctx, "text/plain")
+		if err != nil {
+			return nil, err
+		}
+		w.Write([]byte("hello"))
+
+		switch string(event) {
+		case `"error"`:
+			return nil, errors.New("mid-stream error")
+		case `"panic"`:
+			panic("mid-stream panic")
+		}
+		return nil, nil
+	}, runtime.WithClient(f.Client(t)),
+		runtime.WithPanicPolicy(runtime.PanicPolicyContinue),
+		runtime.WithPostResponseHooks(func(ctx context.Context, inv *runtime.Invocation) {
+			calls = append(calls, inv.AWSRequestID)
+		}))
+
+	var rae *runtime.RuntimeAPIError
+	asst.ErrorAs(err, &rae)
+
+	// the hooks do not run when the error is reported by the trailers of the stream
+	asst.Equal([]string{"req-1"}, calls)
+	results := f.Results()
+	if asst.Len(results, 3) {
+		asst.Empty(results[0].Trailer.Get("Lambda-Runtime-Function-Error-Type"))
+		asst.Equal("errorString", results[1].Trailer.Get("Lambda-Runtime-Function-Error-Type"))
+		asst.Equal(runtime.ErrorTypePanic, results[2].Trailer.Get("Lambda-Runtime-Function-Error-Type"))
+	}
+}
+
+func Test_Start_postResponseTimeout(t *testing.T) {
+	asst := assert.New(t)
+	f := runtimetest.NewServer(t,
+		runtimetest.Event{RequestID: "req-1", Body: `{"name":"alice"}`},
+		runtimetest.Event{RequestID: "req-2", Body: `{"name":"bob"}`},
+	)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	ctxErrs := make(chan error, 2)
+	err := runtime.Start(func(ctx context.Context, in testInput) (*testOutput, error) {
+		return &testOutput{Message: "hello " + in.Name}, nil
+	}, runtime.WithClient(f.Client(t)),
+		runtime.WithPostResponseTimeout(10*time.Millisecond),
+		runtime.WithPostResponseHooks(func(ctx context.Context, inv *runtime.Invocation) {
+			<-ctx.Done()
+			ctxErrs <- ctx.Err()
+			// the hook that ignores the budget does not block the loop
+			<-release
+		}),
+	)
+
+	var rae *runtime.RuntimeAPIError
+	asst.ErrorAs(err, &rae)
+	asst.Equal("Test.NoMoreEvents", rae.Response.ErrorType)
+	asst.Len(f.Results(), 2)
+	asst.ErrorIs(<-ctxErrs, context.DeadlineExceeded)
+	asst.ErrorIs(<-ctxErrs, context.DeadlineExceeded)
+}
+
+func Test_Start_postResponseHookPanic(t *testing.T) {
+	cases := []struct {
+		name          string
+		policy        runtime.PanicPolicy
+		expectResults int
+		expectCalls   []string
+		wantPanic     bool
+	}{
+		{
+			name:          "ng: loop stops with PanicPolicyExit",
+			policy:        runtime.PanicPolicyExit,
+			expectResults: 1,
+			expectCalls:   []string{"req-1"},
+			wantPanic:     true,
+		},
+		{
+			name:          "ok: loop continues with PanicPolicyContinue",
+			policy:        runtime.PanicPolicyContinue,
+			expectResults: 2,
+			expectCalls:   []string{"req-1", "req-2"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			f := runtimetest.NewServer(tt,
+				runtimetest.Event{RequestID: "req-1", Body: `{"name":"alice"}`},
+				runtimetest.Event{RequestID: "req-2", Body: `{"name":"bob"}`},
+			)
+
+			calls := []string{}
+			err := runtime.Start(func(ctx context.Context, in testInput) (*testOutput, error) {
+				asst.NoError(runtime.RegisterPostResponseHook(ctx, nil))
+				return &testOutput{Message: "hello " + in.Name}, nil
+			}, runtime.WithClient(f.Client(tt)),
+				runtime.WithPanicPolicy(c.policy),
+				runtime.WithPostResponseHooks(
+					nil,
+					func(ctx context.Context, inv *runtime.Invocation) { panic("test-panic") },
+					// the hooks after the panicking one still run
+					func(ctx context.Context, inv *runtime.Invocation) { calls = append(calls, inv.AWSRequestID) },
+				),
+			)
+
+			if c.wantPanic {
+				var perr *runtime.PanicError
+				if asst.ErrorAs(err, &perr) {
+					asst.Equal("test-panic", perr.Value)
+					asst.NotEmpty(perr.Stack)
+				}
+			} else {
+				var rae *runtime.RuntimeAPIError
+				asst.ErrorAs(err, &rae)
+			}
+
+			results := f.Results()
+			if asst.Len(results, c.expectResults) {
+				for _, r := range results {
+					asst.Equal("response", r.Kind)
+				}
+			}
+			asst.Equal(c.expectCalls, calls)
+		})
+	}
+}
+
+func Test_RegisterPostResponseHook(t *testing.T) {
+	asst := assert.New(t)
+
+	ctx, cancel := runtime.NewInvocationContext(context.Background(), &runtime.NextOutput{AWSRequestID: "test-request-id"})
+	defer cancel()
+
+	err := runtime.RegisterPostResponseHook(ctx, func(ctx context.Context, inv *runtime.Invocation) {})
+	asst.ErrorIs(err, runtime.ErrNoPostResponseHooks)
+}
@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// The maximum size of the buffered response payload, that is stated by the error message
+	// of Function.ResponseSizeTooLarge. It is slightly more than 6 MiB.
+	MaxResponsePayloadSize int64 = 6291556
+
+	// The maximum size of the streaming response payload. (soft limit)
+	MaxStreamingResponsePayloadSize int64 = 20 * 1024 * 1024
+
+	// Error type reported when the response payload exceeds the limit
+	ErrorTypeResponseSizeTooLarge string = "Function.ResponseSizeTooLarge"
+)
+
+// ErrPayloadTooLarge is the error that the response payload exceeds the limit.
+// The error returned for the payload is *PayloadTooLargeError, that wraps ErrPayloadTooLarge.
+var ErrPayloadTooLarge = errors.New("response payload size exceeded maximum allowed payload size")
+
+// PayloadTooLargeError is the error that the response payload exceeds the limit.
+type PayloadTooLargeError struct {
+	// The size of the payload. For the payload whose size is unknown in advance,
+	// it is the size read until the limit is exceeded.
+	Size int64
+
+	// The maximum size of the payload.
+	Limit int64
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	return fmt.Sprintf("%v (%d bytes > %d bytes)", ErrPayloadTooLarge, e.Size, e.Limit)
+}
+
+func (e *PayloadTooLargeError) Unwrap() error {
+	return ErrPayloadTooLarge
+}
+
+// FunctionError returns the error document with the error type Function.ResponseSizeTooLarge.
+func (e *PayloadTooLargeError) FunctionError() *FunctionError {
+	return &FunctionError{
+		ErrorMessage: e.Error(),
+		ErrorType:    ErrorTypeResponseSizeTooLarge,
+	}
+}
+
+// limitPayload checks that the size of r does not exceed limit, and returns the reader
+// that reads the same content as r.
+// If the size of r is unknown in advance, r is read into the buffer up to limit.
+func limitPayload(r io.Reader, limit int64) (io.Reader, error) {
+	if l, ok := r.(interface{ Len() int }); ok {
+		if size := int64(l.Len()); size > limit {
+			return nil, &PayloadTooLargeError{Size: size, Limit: limit}
+		}
+		return r, nil
+	}
+
+	buf := new(bytes.Buffer)
+	n, err := io.Copy(buf, io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > limit {
+		return nil, &PayloadTooLargeError{Size: n, Limit: limit}
+	}
+
+	return buf, nil
+}
@@ -0,0 +1,36 @@
+package runtime
+
+// Middleware wraps Handler to add cross-cutting behavior, e.g. logging, tracing, metrics and validation.
+//
+// The returned Handler is called with the context of the invocation and the raw event.
+// The metadata of the invocation is available by InvocationFromContext, and the result
+// (the raw response and the error) is returned by the wrapped Handler. A middleware can
+// short-circuit by returning its own result without calling the wrapped Handler,
+// in which case the inner middlewares and the handler are not called.
+type Middleware func(Handler) Handler
+
+// Chain composes the middlewares into one. The first middleware is the outermost:
+// Chain(a, b, c)(h) is equivalent to a(b(c(h))), so for each invocation a sees the event first
+// and the result last. nil middlewares are ignored, and Chain() returns the handler as it is.
+func Chain(middlewares ...Middleware) Middleware {
+	return func(h Handler) Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			if middlewares[i] != nil {
+				h = middlewares[i](h)
+			}
+		}
+		return h
+	}
+}
+
+// WithMiddleware adds the middlewares around the handler of Start and StartHandler, in the same order as Chain.
+// When it is set more than once, the middlewares added later are inner.
+//
+// The middlewares run inside the loop: a panic in them is recovered and reported in the same way
+// as a panic in the handler, and a streaming response opened by OpenResponseStream is completed
+// after the outermost middleware returns.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
@@ -0,0 +1,205 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/internal/runtimetest"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder returns the middleware that records the event before and the result after the wrapped handler.
+func recorder(name string, calls *[]string) runtime.Middleware {
+	return func(next runtime.Handler) runtime.Handler {
+		return func(ctx context.Context, event []byte) ([]byte, error) {
+			*calls = append(*calls, fmt.Sprintf("%s:before:%s", name, event))
+			out, err := next(ctx, event)
+			*calls = append(*calls, fmt.Sprintf("%s:after:%s:%v", name, out, err))
+			return out, err
+		}
+	}
+}
+
+// shortCircuit returns the middleware that returns the result without calling the wrapped handler
+// when the event is "cached".
+func shortCircuit(calls *[]string) runtime.Middleware {
+	return func(next runtime.Handler) runtime.Handler {
+		return func(ctx context.Context, event []byte) ([]byte, error) {
+			if string(event) == "cached" {
+				*calls = append(*calls, "short-circuit")
+				return []byte("from-cache"), nil
+			}
+			return next(ctx, event)
+		}
+	}
+}
+
+func Test_Chain(t *testing.T) {
+	errTest := errors.New("test-error")
+
+	cases := []struct {
+		name        string
+		middlewares func(calls *[]string) []runtime.Middleware
+		event       string
+		handlerErr  error
+		expect      string
+		expectCalls []string
+		wantErr     bool
+	}{
+		{
+			name: "ok: first middleware is outermost",
+			middlewares: func(calls *[]string) []runtime.Middleware {
+				return []runtime.Middleware{recorder("a", calls), recorder("b", calls), recorder("c", calls)}
+			},
+			event:  "event",
+			expect: "response",
+			expectCalls: []string{
+				"a:before:event",
+				"b:before:event",
+				"c:before:event",
+				"handler",
+				"c:after:response:<nil>",
+				"b:after:response:<nil>",
+				"a:after:response:<nil>",
+			},
+		},
+		{
+			name: "ok: short-circuit skips inner middlewares and handler",
+			middlewares: func(calls *[]string) []runtime.Middleware {
+				return []runtime.Middleware{recorder("a", calls), shortCircuit(calls), recorder("c", calls)}
+			},
+			event:  "cached",
+			expect: "from-cache",
+			expectCalls: []string{
+				"a:before:cached",
+				"short-circuit",
+				"a:after:from-cache:<nil>",
+			},
+		},
+		{
+			name: "ok: nil middleware is ignored",
+			middlewares: func(calls *[]string) []runtime.Middleware {
+				return []runtime.Middleware{nil, recorder("a", calls), nil}
+			},
+			event:       "event",
+			expect:      "response",
+			expectCalls: []string{"a:before:event", "handler", "a:after:response:<nil>"},
+		},
+		{
+			name:        "ok: no middlewares",
+			middlewares: func(calls *[]string) []runtime.Middleware { return nil },
+			event:       "event",
+			expect:      "response",
+			expectCalls: []string{"handler"},
+		},
+		{
+			name: "ng: middlewares see the error of handler",
+			middlewares: func(calls *[]string) []runtime.Middleware {
+				return []runtime.Middleware{recorder("a", calls), recorder("b", calls)}
+			},
+			event:      "event",
+			handlerErr: errTest,
+			expectCalls: []string{
+				"a:before:event",
+				"b:before:event",
+				"handler",
+				"b:after::test-error",
+				"a:after::test-error",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			calls := []string{}
+			h := runtime.Chain(c.middlewares(&calls)...)(func(ctx context.Context, event []byte) ([]byte, error) {
+				calls = append(calls, "handler")
+				if c.handlerErr != nil {
+					return nil, c.handlerErr
+				}
+				return []byte("response"), nil
+			})
+
+			out, err := h(context.Background(), []byte(c.event))
+			asst.Equal(c.expectCalls, calls)
+			if c.wantErr {
+				asst.ErrorIs(err, c.handlerErr)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, string(out))
+		})
+	}
+}
+
+func Test_Start_withMiddleware(t *testing.T) {
+	asst := assert.New(t)
+	f := runtimetest.NewServer(t,
+		runtimetest.Event{RequestID: "req-1", Body: `{"name":"alice"}`},
+		runtimetest.Event{RequestID: "req-2", Body: `{"name":"bob"}`},
+		runtimetest.Event{RequestID: "req-3", Body: `{"name":"carol"}`},
+		runtimetest.Event{RequestID: "req-4", Body: `{"name":"dave"}`},
+	)
+
+	calls := []string{}
+	// sees the metadata of the invocation, the raw event and the result
+	logging := func(next runtime.Handler) runtime.Handler {
+		return func(ctx context.Context, event []byte) ([]byte, error) {
+			inv, _ := runtime.InvocationFromContext(ctx)
+			out, err := next(ctx, event)
+			calls = append(calls, fmt.Sprintf("%s %s -> %s %v", inv.AWSRequestID, event, out, err))
+			return out, err
+		}
+	}
+	// rejects the invocation without calling the handler
+	validation := func(next runtime.Handler) runtime.Handler {
+		return func(ctx context.Context, event []byte) ([]byte, error) {
+			if string(event) == `{"name":"bob"}` {
+				return nil, &runtime.FunctionError{ErrorMessage: "bob is not allowed", ErrorType: "Validation.Error"}
+			}
+			return next(ctx, event)
+		}
+	}
+	panicking := func(next runtime.Handler) runtime.Handler {
+		return func(ctx context.Context, event []byte) ([]byte, error) {
+			if string(event) == `{"name":"carol"}` {
+				panic("test-panic")
+			}
+			return next(ctx, event)
+		}
+	}
+
+	err := runtime.Start(func(ctx context.Context, in testInput) (*testOutput, error) {
+		return &testOutput{Message: "hello " + in.Name}, nil
+	}, runtime.WithClient(f.Client(t)),
+		runtime.WithMiddleware(logging, validation),
+		runtime.WithMiddleware(panicking),
+		runtime.WithPanicPolicy(runtime.PanicPolicyContinue))
+
+	var rae *runtime.RuntimeAPIError
+	asst.ErrorAs(err, &rae)
+
+	asst.Equal([]string{
+		`req-1 {"name":"alice"} -> {"message":"hello alice"} <nil>`,
+		`req-2 {"name":"bob"} ->  bob is not allowed`,
+		`req-4 {"name":"dave"} -> {"message":"hello dave"} <nil>`,
+	}, calls)
+
+	results := f.Results()
+	if asst.Len(results, 4) {
+		asst.Equal("response", results[0].Kind)
+		asst.Equal("error", results[1].Kind)
+		asst.JSONEq(`{"errorMessage":"bob is not allowed","errorType":"Validation.Error","stackTrace":null}`, results[1].Body)
+		asst.Equal("error", results[2].Kind)
+		asst.Contains(results[2].Body, "test-panic")
+		asst.Equal("response", results[3].Kind)
+	}
+}
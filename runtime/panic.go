@@ -0,0 +1,163 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	goruntime "runtime"
+	"strings"
+)
+
+const (
+	// Error type reported when the handler panics
+	ErrorTypePanic string = "Runtime.Panic"
+
+	maxPanicStackDepth int = 64
+)
+
+// PanicPolicy is the policy of the loop after the handler panics.
+type PanicPolicy int
+
+const (
+	// PanicPolicyExit stops the loop after reporting the panic, and Start returns *PanicError.
+	// This is the same behavior as the managed runtimes, whose process exits on panic.
+	PanicPolicyExit PanicPolicy = iota
+
+	// PanicPolicyContinue continues the loop with the next invocation after reporting the panic.
+	PanicPolicyContinue
+)
+
+// PanicError is the error that represents a panic in the handler.
+type PanicError struct {
+	// The value passed to panic.
+	Value any
+
+	// The stack frames from the point of the panic to the handler.
+	Stack []XRayErrorStackItem
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panicked: %v", e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// FunctionError returns the error document of the panic.
+// The stack trace has a line per frame from the point of the panic to the handler,
+// in the format of this package: `  File "<path>", line <line>, in <function>`
+// (e.g.   File "/var/task/main.go", line 12, in main.handler).
+// The structured frames are available as Stack and in XRayErrorCause.
+func (e *PanicError) FunctionError() *FunctionError {
+	st := make([]string, 0, len(e.Stack))
+	for _, s := range e.Stack {
+		st = append(st, fmt.Sprintf("  File \"%s\", line %d, in %s", s.Path, s.Line, s.Label))
+	}
+
+	return &FunctionError{
+		ErrorMessage: fmt.Sprint(e.Value),
+		ErrorType:    ErrorTypePanic,
+		StackTrace:   st,
+	}
+}
+
+// XRayErrorCause returns the X-Ray error cause of the panic.
+func (e *PanicError) XRayErrorCause() *XRayErrorCause {
+	wd, _ := os.Getwd()
+
+	paths := []string{}
+	seen := map[string]bool{}
+	for _, s := range e.Stack {
+		if !seen[s.Path] {
+			seen[s.Path] = true
+			paths = append(paths, s.Path)
+		}
+	}
+
+	return &XRayErrorCause{
+		WorkingDirectory: wd,
+		Exceptions: []XRayErrorException{
+			{
+				Type:    ErrorTypePanic,
+				Message: fmt.Sprint(e.Value),
+				Stack:   e.Stack,
+			},
+		},
+		Paths: paths,
+	}
+}
+
+// WithPanicPolicy sets the policy of the loop after the handler panics.
+// The panic is always recovered and reported by InvocationError with the error type Runtime.Panic.
+// The policy also applies to a panic in the post-response hooks, that is written to stderr
+// instead since the response has already been sent.
+// If it is not set, PanicPolicyExit is used.
+func WithPanicPolicy(p PanicPolicy) Option {
+	return func(o *options) {
+		o.panicPolicy = p
+	}
+}
+
+var (
+	callHandlerFuncName          = reflect.TypeOf(runner{}).PkgPath() + ".callHandler"
+	callPostResponseHookFuncName = reflect.TypeOf(runner{}).PkgPath() + ".callPostResponseHook"
+)
+
+// callHandler calls handler, and recovers the panic in it as *PanicError.
+func callHandler(ctx context.Context, handler Handler, event []byte) (res []byte, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			res, err = nil, &PanicError{Value: v, Stack: panicStack(callHandlerFuncName)}
+		}
+	}()
+
+	return handler(ctx, event)
+}
+
+// callPostResponseHook calls hook, and recovers the panic in it as *PanicError.
+func callPostResponseHook(ctx context.Context, hook PostResponseHook, inv *Invocation) (perr *PanicError) {
+	defer func() {
+		if v := recover(); v != nil {
+			perr = &PanicError{Value: v, Stack: panicStack(callPostResponseHookFuncName)}
+		}
+	}()
+
+	hook(ctx, inv)
+	return nil
+}
+
+// panicStack returns the stack frames from the point of the panic to the function named caller,
+// that recovers the panic. It must be called in the deferred function that recovers the panic.
+func panicStack(caller string) []XRayErrorStackItem {
+	pcs := make([]uintptr, maxPanicStackDepth)
+	n := goruntime.Callers(1, pcs)
+	frames := goruntime.CallersFrames(pcs[:n])
+
+	stack := []XRayErrorStackItem{}
+	panicked := false
+	for {
+		f, more := frames.Next()
+		switch {
+		case f.Function == "runtime.gopanic":
+			panicked = true
+		case f.Function == caller:
+			return stack
+		case panicked && !strings.HasPrefix(f.Function, "runtime."):
+			stack = append(stack, XRayErrorStackItem{
+				Path:  f.File,
+				Line:  f.Line,
+				Label: f.Function[strings.LastIndex(f.Function, "/")+1:],
+			})
+		}
+
+		if !more {
+			return stack
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package runtime_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/internal/runtimetest"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+type panicEvent struct {
+	Kind string `json:"kind"`
+}
+
+func panickingHandler(ctx context.Context, in panicEvent) (string, error) {
+	switch in.Kind {
+	case "string":
+		panic("test-panic")
+	case "nil":
+		var p *panicEvent
+		return p.Kind, nil
+	}
+	return "ok", nil
+}
+
+func Test_Start_panic(t *testing.T) {
+	cases := []struct {
+		name          string
+		policy        *runtime.PanicPolicy
+		events        []runtimetest.Event
+		expectKinds   []string
+		expectMessage string
+		wantPanicErr  bool
+	}{
+		{
+			name: "ok: exit by default",
+			events: []runtimetest.Event{
+				{RequestID: "req-1", Body: `{"kind":"string"}`},
+				{RequestID: "req-2", Body: `{}`},
+			},
+			expectKinds:   []string{"error"},
+			expectMessage: "test-panic",
+			wantPanicErr:  true,
+		},
+		{
+			name:   "ok: exit",
+			policy: func() *runtime.PanicPolicy { p := runtime.PanicPolicyExit; return &p }(),
+			events: []runtimetest.Event{
+				{RequestID: "req-1", Body: `{"kind":"nil"}`},
+				{RequestID: "req-2", Body: `{}`},
+			},
+			expectKinds:   []string{"error"},
+			expectMessage: "runtime error: invalid memory address or nil pointer dereference",
+			wantPanicErr:  true,
+		},
+		{
+			name:   "ok: continue",
+			policy: func() *runtime.PanicPolicy { p := runtime.PanicPolicyContinue; return &p }(),
+			events: []runtimetest.Event{
+				{RequestID: "req-1", Body: `{"kind":"string"}`},
+				{RequestID: "req-2", Body: `{}`},
+			},
+			expectKinds:   []string{"error", "response"},
+			expectMessage: "test-panic",
+			wantPanicErr:  false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			f := runtimetest.NewServer(tt, c.events...)
+
+			opts := []runtime.Option{runtime.WithClient(f.Client(tt))}
+			if c.policy != nil {
+				opts = append(opts, runtime.WithPanicPolicy(*c.policy))
+			}
+
+			err := runtime.Start(panickingHandler, opts...)
+
+			var perr *runtime.PanicError
+			if c.wantPanicErr {
+				asst.ErrorAs(err, &perr)
+			} else {
+				asst.False(errors.As(err, &perr))
+			}
+
+			results := f.Results()
+			kinds := []string{}
+			for _, r := range results {
+				kinds = append(kinds, r.Kind)
+			}
+			asst.Equal(c.expectKinds, kinds)
+
+			res := results[0]
+			asst.Equal("Runtime.Panic", res.Header.Get("Lambda-Runtime-Function-Error-Type"))
+
+			fe := runtime.FunctionError{}
+			asst.NoError(json.Unmarshal([]byte(res.Body), &fe))
+			asst.Equal(c.expectMessage, fe.ErrorMessage)
+			asst.Equal("Runtime.Panic", fe.ErrorType)
+			if asst.NotEmpty(fe.StackTrace) {
+				// the first frame is the point of the panic
+				asst.Contains(fe.StackTrace[0], "panic_test.go")
+				asst.Contains(fe.StackTrace[0], "in runtime_test.panickingHandler")
+				asst.True(strings.HasPrefix(fe.StackTrace[0], `  File "`))
+			}
+			for _, st := range fe.StackTrace {
+				asst.NotContains(st, "callHandler")
+				asst.NotContains(st, "gopanic")
+			}
+
+			cause := runtime.XRayErrorCause{}
+			asst.NoError(json.Unmarshal([]byte(res.Header.Get("Lambda-Runtime-Function-XRay-Error-Cause")), &cause))
+			if asst.Len(cause.Exceptions, 1) {
+				asst.Equal("Runtime.Panic", cause.Exceptions[0].Type)
+				asst.Equal(c.expectMessage, cause.Exceptions[0].Message)
+				asst.Equal("runtime_test.panickingHandler", cause.Exceptions[0].Stack[0].Label)
+			}
+			asst.NotEmpty(cause.Paths)
+		})
+	}
+}
+
+func Test_PanicError(t *testing.T) {
+	cause := errors.New("test-error")
+	stack := []runtime.XRayErrorStackItem{
+		{Path: "/var/task/main.go", Line: 12, Label: "main.handler"},
+		{Path: "/var/task/main.go", Line: 30, Label: "main.main"},
+		{Path: "/var/task/sub.go", Line: 5, Label: "main.sub"},
+	}
+
+	cases := []struct {
+		name         string
+		perr         *runtime.PanicError
+		expectMsg    string
+		expectUnwrap error
+	}{
+		{
+			name:         "ok: error value",
+			perr:         &runtime.PanicError{Value: cause, Stack: stack},
+			expectMsg:    "test-error",
+			expectUnwrap: cause,
+		},
+		{
+			name:         "ok: non error value",
+			perr:         &runtime.PanicError{Value: 100, Stack: stack},
+			expectMsg:    "100",
+			expectUnwrap: nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			asst.Equal("handler panicked: "+c.expectMsg, c.perr.Error())
+			asst.Equal(c.expectUnwrap, errors.Unwrap(c.perr))
+
+			fe := c.perr.FunctionError()
+			asst.Equal(&runtime.FunctionError{
+				ErrorMessage: c.expectMsg,
+				ErrorType:    "Runtime.Panic",
+				StackTrace: []string{
+					`  File "/var/task/main.go", line 12, in main.handler`,
+					`  File "/var/task/main.go", line 30, in main.main`,
+					`  File "/var/task/sub.go", line 5, in main.sub`,
+				},
+			}, fe)
+
+			xc := c.perr.XRayErrorCause()
+			asst.NotEmpty(xc.WorkingDirectory)
+			asst.Equal([]string{"/var/task/main.go", "/var/task/sub.go"}, xc.Paths)
+			asst.Equal([]runtime.XRayErrorException{
+				{Type: "Runtime.Panic", Message: c.expectMsg, Stack: stack},
+			}, xc.Exceptions)
+		})
+	}
+}
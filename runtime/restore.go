@@ -0,0 +1,120 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/michimani/aws-lambda-api-go/alago"
+)
+
+const (
+	initializationTypeEnvKey string = "AWS_LAMBDA_INITIALIZATION_TYPE"
+	initializationTypeSnap   string = "snap-start"
+
+	// Error types reported by the restore lifecycle
+	ErrorTypeBeforeSnapshotError string = "Runtime.BeforeSnapshotError"
+	ErrorTypeAfterRestoreError   string = "Runtime.AfterRestoreError"
+)
+
+// RestoreHook is the function that runs before the snapshot is taken or after it is restored.
+type RestoreHook func(ctx context.Context) error
+
+// RestoreHooks is the registry of the hooks for the restore lifecycle of Lambda SnapStart.
+// The zero value is ready to use.
+type RestoreHooks struct {
+	mu             sync.Mutex
+	beforeSnapshot []RestoreHook
+	afterRestore   []RestoreHook
+}
+
+var defaultRestoreHooks = &RestoreHooks{}
+
+// RegisterBeforeSnapshot registers fn to the default registry. See RestoreHooks.RegisterBeforeSnapshot.
+func RegisterBeforeSnapshot(fn RestoreHook) {
+	defaultRestoreHooks.RegisterBeforeSnapshot(fn)
+}
+
+// RegisterAfterRestore registers fn to the default registry. See RestoreHooks.RegisterAfterRestore.
+func RegisterAfterRestore(fn RestoreHook) {
+	defaultRestoreHooks.RegisterAfterRestore(fn)
+}
+
+// RegisterBeforeSnapshot registers fn that runs before the snapshot is taken,
+// e.g. to close network connections that will not be valid after restore.
+// The hooks run in the reverse order of registration.
+func (h *RestoreHooks) RegisterBeforeSnapshot(fn RestoreHook) {
+	if fn == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beforeSnapshot = append(h.beforeSnapshot, fn)
+}
+
+// RegisterAfterRestore registers fn that runs after the snapshot is restored,
+// e.g. to refresh unique values or reconnect to the dependencies.
+// The hooks run in the order of registration.
+func (h *RestoreHooks) RegisterAfterRestore(fn RestoreHook) {
+	if fn == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.afterRestore = append(h.afterRestore, fn)
+}
+
+func (h *RestoreHooks) hooks() ([]RestoreHook, []RestoreHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]RestoreHook{}, h.beforeSnapshot...), append([]RestoreHook{}, h.afterRestore...)
+}
+
+// Restore runs the restore lifecycle of Lambda SnapStart.
+// It runs the before-snapshot hooks, calls RestoreNext that blocks until the snapshot is restored,
+// and then runs the after-restore hooks. It must be called before the first InvocationNext.
+//
+// If a before-snapshot hook fails, the error is reported by InitError.
+// If an after-restore hook fails, the error is reported by RestoreError.
+// In both cases, Restore returns the error of the hook, and the runtime should exit.
+func (h *RestoreHooks) Restore(ctx context.Context, client alago.AlagoClient) error {
+	before, after := h.hooks()
+
+	for i := len(before) - 1; i >= 0; i-- {
+		if err := before[i](ctx); err != nil {
+			fe := &FunctionError{ErrorMessage: err.Error(), ErrorType: ErrorTypeBeforeSnapshotError}
+			if _, rerr := InitError(ctx, client, &InitErrorInput{Error: fe}); rerr != nil {
+				return fmt.Errorf("failed to report before-snapshot error: %v, hook error: %w", rerr, err)
+			}
+			return err
+		}
+	}
+
+	out, err := RestoreNext(ctx, client)
+	if err != nil {
+		return err
+	}
+	if out.Error != nil {
+		return &RuntimeAPIError{StatusCode: out.StatusCode, Response: out.Error}
+	}
+
+	for _, fn := range after {
+		if err := fn(ctx); err != nil {
+			fe := &FunctionError{ErrorMessage: err.Error(), ErrorType: ErrorTypeAfterRestoreError}
+			if _, rerr := RestoreError(ctx, client, &RestoreErrorInput{Error: fe}); rerr != nil {
+				return fmt.Errorf("failed to report after-restore error: %v, hook error: %w", rerr, err)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isSnapStart reports whether the function is initialized for Lambda SnapStart.
+func isSnapStart() bool {
+	return os.Getenv(initializationTypeEnvKey) == initializationTypeSnap
+}
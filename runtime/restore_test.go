@@ -0,0 +1,192 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/internal/runtimetest"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RestoreHooks_Restore(t *testing.T) {
+	cases := []struct {
+		name                  string
+		before                []string
+		after                 []string
+		failHook              string
+		restoreNextStatusCode int
+		expectCalls           []string
+		expectKinds           []string
+		expectErrorType       string
+		wantErr               bool
+	}{
+		{
+			name:        "ok",
+			before:      []string{"before-1", "before-2"},
+			after:       []string{"after-1", "after-2"},
+			expectCalls: []string{"before-2", "before-1", "after-1", "after-2"},
+			expectKinds: []string{"restore/next"},
+			wantErr:     false,
+		},
+		{
+			name:        "ok: no hooks",
+			expectCalls: []string{},
+			expectKinds: []string{"restore/next"},
+			wantErr:     false,
+		},
+		{
+			name:            "ng: before-snapshot hook fails",
+			before:          []string{"before-1", "before-2"},
+			after:           []string{"after-1"},
+			failHook:        "before-2",
+			expectCalls:     []string{"before-2"},
+			expectKinds:     []string{"init/error"},
+			expectErrorType: "Runtime.BeforeSnapshotError",
+			wantErr:         true,
+		},
+		{
+			name:            "ng: after-restore hook fails",
+			before:          []string{"before-1"},
+			after:           []string{"after-1", "after-2"},
+			failHook:        "after-1",
+			expectCalls:     []string{"before-1", "after-1"},
+			expectKinds:     []string{"restore/next", "restore/error"},
+			expectErrorType: "Runtime.AfterRestoreError",
+			wantErr:         true,
+		},
+		{
+			name:                  "ng: restore next returns error",
+			before:                []string{"before-1"},
+			after:                 []string{"after-1"},
+			restoreNextStatusCode: http.StatusInternalServerError,
+			expectCalls:           []string{"before-1"},
+			expectKinds:           []string{"restore/next"},
+			wantErr:               true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			f := runtimetest.NewServer(tt)
+			if c.restoreNextStatusCode != 0 {
+				f.RestoreNextStatusCode = c.restoreNextStatusCode
+			}
+
+			calls := []string{}
+			hook := func(name string) runtime.RestoreHook {
+				return func(ctx context.Context) error {
+					calls = append(calls, name)
+					if name == c.failHook {
+						return errors.New("hook failed")
+					}
+					return nil
+				}
+			}
+
+			h := &runtime.RestoreHooks{}
+			for _, name := range c.before {
+				h.RegisterBeforeSnapshot(hook(name))
+			}
+			for _, name := range c.after {
+				h.RegisterAfterRestore(hook(name))
+			}
+			h.RegisterBeforeSnapshot(nil)
+			h.RegisterAfterRestore(nil)
+
+			err := h.Restore(context.Background(), f.Client(tt))
+
+			asst.Equal(c.expectCalls, calls)
+			results := f.Results()
+			kinds := []string{}
+			for _, r := range results {
+				kinds = append(kinds, r.Kind)
+			}
+			asst.Equal(c.expectKinds, kinds)
+			if c.expectErrorType != "" {
+				asst.Equal(c.expectErrorType, results[len(results)-1].Header.Get("Lambda-Runtime-Function-Error-Type"))
+			}
+
+			if c.wantErr {
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+		})
+	}
+}
+
+func Test_Start_restore(t *testing.T) {
+	cases := []struct {
+		name               string
+		initializationType string
+		useDefault         bool
+		expectKinds        []string
+		expectCalls        []string
+	}{
+		{
+			name:               "ok: snap-start",
+			initializationType: "snap-start",
+			expectKinds:        []string{"restore/next", "response"},
+			expectCalls:        []string{"before", "after", "handler"},
+		},
+		{
+			name:               "ok: snap-start with default registry",
+			initializationType: "snap-start",
+			useDefault:         true,
+			expectKinds:        []string{"restore/next", "response"},
+			expectCalls:        []string{"before", "after", "handler"},
+		},
+		{
+			name:               "ok: on-demand",
+			initializationType: "on-demand",
+			expectKinds:        []string{"response"},
+			expectCalls:        []string{"handler"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			tt.Setenv("AWS_LAMBDA_INITIALIZATION_TYPE", c.initializationType)
+			f := runtimetest.NewServer(tt, runtimetest.Event{RequestID: "req-1", Body: `{}`})
+
+			calls := []string{}
+			before := func(ctx context.Context) error {
+				calls = append(calls, "before")
+				return nil
+			}
+			after := func(ctx context.Context) error {
+				calls = append(calls, "after")
+				return nil
+			}
+
+			opts := []runtime.Option{runtime.WithClient(f.Client(tt))}
+			if c.useDefault {
+				runtime.RegisterBeforeSnapshot(before)
+				runtime.RegisterAfterRestore(after)
+			} else {
+				h := &runtime.RestoreHooks{}
+				h.RegisterBeforeSnapshot(before)
+				h.RegisterAfterRestore(after)
+				opts = append(opts, runtime.WithRestoreHooks(h))
+			}
+
+			runtime.Start(func(ctx context.Context, in testInput) (string, error) {
+				calls = append(calls, "handler")
+				return "", nil
+			}, opts...)
+
+			kinds := []string{}
+			for _, r := range f.Results() {
+				kinds = append(kinds, r.Kind)
+			}
+			asst.Equal(c.expectKinds, kinds)
+			asst.Equal(c.expectCalls, calls)
+		})
+	}
+}
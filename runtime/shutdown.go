@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const defaultShutdownTimeout time.Duration = 500 * time.Millisecond
+
+// ShutdownFunc is the function that runs when the runtime receives SIGTERM,
+// e.g. to flush the buffered telemetry or close connections.
+// ctx is canceled when the time budget of shutdown is exceeded.
+type ShutdownFunc func(ctx context.Context)
+
+// WithShutdownFunc adds the function that runs when the runtime receives SIGTERM.
+// The functions run in the order they are added.
+func WithShutdownFunc(fn ShutdownFunc) Option {
+	return func(o *options) {
+		o.shutdownFuncs = append(o.shutdownFuncs, fn)
+	}
+}
+
+// WithShutdownTimeout sets the time budget of shutdown, that starts when SIGTERM is received.
+// The invocations in progress are waited for at most half of the budget to post their results,
+// and then the shutdown functions run within the rest. When the budget is exceeded,
+// the context passed to the shutdown functions and the posting of the results are canceled,
+// and Start returns without waiting for them to return.
+// If it is not set, 500 milliseconds is used, that is the time Lambda waits
+// for the runtime to exit after sending SIGTERM.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.shutdownTimeout = d
+	}
+}
+
+// startWithSignal runs the loops until they stop or SIGTERM is received.
+// On SIGTERM, only the blocking InvocationNext is canceled, so that the invocations in progress
+// can still post their results until the deadline of shutdown. The loops are waited for
+// at most half of the budget, so that a handler that does not return in time does not delay
+// the shutdown functions. Then the shutdown functions run within the rest, and it returns nil.
+func (r *runner) startWithSignal(ctx context.Context, o *options) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	pollCtx, cancelPoll := context.WithCancel(ctx)
+	defer cancelPoll()
+	postCtx, cancelPost := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelPost()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- r.start(pollCtx, postCtx)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-sigCh:
+	}
+
+	deadline := time.Now().Add(o.shutdownTimeout)
+	stop := time.AfterFunc(o.shutdownTimeout, cancelPost)
+	defer stop.Stop()
+
+	cancelPoll()
+	wait := time.NewTimer(o.shutdownTimeout / 2)
+	defer wait.Stop()
+	select {
+	case <-errCh:
+	case <-wait.C:
+	}
+
+	shutdown(ctx, o.shutdownFuncs, deadline)
+	return nil
+}
+
+// shutdown runs fns and waits for them to return until deadline.
+func shutdown(ctx context.Context, fns []ShutdownFunc, deadline time.Time) {
+	if len(fns) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithDeadline(context.WithoutCancel(ctx), deadline)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, fn := range fns {
+			if ctx.Err() != nil {
+				return
+			}
+			fn(ctx)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
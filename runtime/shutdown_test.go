@@ -0,0 +1,169 @@
+//go:build unix
+
+package runtime_test
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/michimani/aws-lambda-api-go/internal/runtimetest"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Start_sigterm(t *testing.T) {
+	cases := []struct {
+		name         string
+		fns          func(called chan<- string) []runtime.ShutdownFunc
+		timeout      time.Duration
+		expectCalled []string
+	}{
+		{
+			name: "ok",
+			fns: func(called chan<- string) []runtime.ShutdownFunc {
+				return []runtime.ShutdownFunc{
+					func(ctx context.Context) { called <- "first" },
+					func(ctx context.Context) { called <- "second" },
+				}
+			},
+			expectCalled: []string{"first", "second"},
+		},
+		{
+			name: "ok: no shutdown functions",
+			fns: func(called chan<- string) []runtime.ShutdownFunc {
+				return nil
+			},
+			expectCalled: []string{},
+		},
+		{
+			name: "ok: shutdown functions exceed the timeout",
+			fns: func(called chan<- string) []runtime.ShutdownFunc {
+				return []runtime.ShutdownFunc{
+					func(ctx context.Context) {
+						<-ctx.Done()
+						called <- ctx.Err().Error()
+					},
+					func(ctx context.Context) { called <- "not called" },
+				}
+			},
+			timeout:      10 * time.Millisecond,
+			expectCalled: []string{context.DeadlineExceeded.Error()},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			f := runtimetest.NewServer(tt, runtimetest.Event{RequestID: "req-1", Body: `{"name":"alice"}`})
+			f.BlockWhenEmpty = true
+
+			called := make(chan string, 2)
+			opts := []runtime.Option{runtime.WithClient(f.Client(tt)), runtime.WithShutdownTimeout(c.timeout)}
+			for _, fn := range c.fns(called) {
+				opts = append(opts, runtime.WithShutdownFunc(fn))
+			}
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- runtime.Start(func(ctx context.Context, in testInput) (string, error) {
+					return in.Name, nil
+				}, opts...)
+			}()
+
+			// wait until the loop blocks on the second InvocationNext
+			asst.Eventually(func() bool { return f.Polls() == 2 }, time.Second, time.Millisecond)
+			asst.NoError(syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+			select {
+			case err := <-errCh:
+				asst.NoError(err)
+			case <-time.After(time.Second):
+				tt.Fatal("Start did not return after SIGTERM")
+			}
+
+			// the shutdown function that exceeds the timeout may return after Start returns
+			got := []string{}
+			for range c.expectCalled {
+				select {
+				case s := <-called:
+					got = append(got, s)
+				case <-time.After(time.Second):
+				}
+			}
+			asst.Equal(c.expectCalled, got)
+			asst.Never(func() bool { return len(called) > 0 }, 20*time.Millisecond, time.Millisecond)
+			asst.Len(f.Results(), 1)
+		})
+	}
+}
+
+func Test_Start_sigtermInFlight(t *testing.T) {
+	cases := []struct {
+		name          string
+		handlerTime   time.Duration
+		expectResults []string
+	}{
+		{
+			name:          "ok: handler returns within the budget",
+			handlerTime:   20 * time.Millisecond,
+			expectResults: []string{"response"},
+		},
+		{
+			name:          "ok: handler does not return within the budget",
+			handlerTime:   time.Second,
+			expectResults: []string{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			f := runtimetest.NewServer(tt, runtimetest.Event{RequestID: "req-1", Body: `{"name":"alice"}`})
+			f.BlockWhenEmpty = true
+
+			started := make(chan struct{})
+			called := make(chan error, 1)
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- runtime.Start(func(ctx context.Context, in testInput) (string, error) {
+					// the handler that ignores the cancellation
+					close(started)
+					time.Sleep(c.handlerTime)
+					return in.Name, nil
+				}, runtime.WithClient(f.Client(tt)),
+					runtime.WithShutdownTimeout(200*time.Millisecond),
+					runtime.WithShutdownFunc(func(ctx context.Context) { called <- ctx.Err() }))
+			}()
+
+			<-started
+			sent := time.Now()
+			asst.NoError(syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+			select {
+			case err := <-errCh:
+				asst.NoError(err)
+				asst.Less(time.Since(sent), 500*time.Millisecond)
+			case <-time.After(time.Second):
+				tt.Fatal("Start did not return after SIGTERM")
+			}
+
+			// the shutdown function runs within the budget, after the response is posted
+			select {
+			case err := <-called:
+				asst.NoError(err)
+			default:
+				tt.Fatal("shutdown function was not called")
+			}
+
+			kinds := []string{}
+			for _, r := range f.Results() {
+				kinds = append(kinds, r.Kind)
+			}
+			asst.Equal(c.expectResults, kinds)
+			// the loop does not get the next invocation
+			asst.Equal(1, f.Polls())
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/michimani/aws-lambda-api-go/alago"
+)
+
+const (
+	maxConcurrencyEnvKey string = "AWS_LAMBDA_MAX_CONCURRENCY"
+)
+
+const (
+	// Error types reported by Start
+	ErrorTypeUnmarshalError string = "Runtime.UnmarshalError"
+	ErrorTypeMarshalError   string = "Runtime.MarshalError"
+)
+
+// Handler is the function that processes the raw event of an invocation,
+// and returns the raw response.
+type Handler func(ctx context.Context, event []byte) ([]byte, error)
+
+// NewHandler converts the typed handler into Handler.
+// The event is unmarshaled into TIn and the output is marshaled from TOut as JSON.
+// The errors of unmarshaling and marshaling are returned as *FunctionError
+// with the error type Runtime.UnmarshalError and Runtime.MarshalError.
+func NewHandler[TIn, TOut any](handler func(context.Context, TIn) (TOut, error)) Handler {
+	return func(ctx context.Context, event []byte) ([]byte, error) {
+		var in TIn
+		if err := json.Unmarshal(event, &in); err != nil {
+			return nil, &FunctionError{ErrorMessage: err.Error(), ErrorType: ErrorTypeUnmarshalError}
+		}
+
+		out, err := handler(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := json.Marshal(out)
+		if err != nil {
+			return nil, &FunctionError{ErrorMessage: err.Error(), ErrorType: ErrorTypeMarshalError}
+		}
+
+		return b, nil
+	}
+}
+
+// Option is the option for Start and StartHandler.
+type Option func(*options)
+
+type options struct {
+	ctx           context.Context
+	client        alago.AlagoClient
+	exportTraceID bool
+	restoreHooks  *RestoreHooks
+	concurrency   int
+	panicPolicy   PanicPolicy
+
+	postResponseHooks   []PostResponseHook
+	postResponseTimeout time.Duration
+
+	shutdownFuncs   []ShutdownFunc
+	shutdownTimeout time.Duration
+
+	middlewares []Middleware
+}
+
+// WithClient sets the client used to call Runtime API.
+// If it is not set, the client is created by alago.NewClient with the default http.Client.
+func WithClient(client alago.AlagoClient) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithContext sets the context of the loop.
+// When ctx is canceled, the blocking InvocationNext is canceled and the loop stops.
+// If it is not set, context.Background is used.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithTraceIDExport makes the loop set the X-Ray tracing header of each invocation
+// to the environment variable _X_AMZN_TRACE_ID by ExportTraceID, as the managed runtimes do.
+// Since the environment variable is shared by the process, it is not set when
+// the loop processes multiple invocations concurrently. Use TraceHeaderFromContext instead.
+func WithTraceIDExport() Option {
+	return func(o *options) {
+		o.exportTraceID = true
+	}
+}
+
+// WithRestoreHooks sets the registry of the hooks for the restore lifecycle of Lambda SnapStart.
+// If it is not set, the hooks registered by RegisterBeforeSnapshot and RegisterAfterRestore are used.
+func WithRestoreHooks(h *RestoreHooks) Option {
+	return func(o *options) {
+		o.restoreHooks = h
+	}
+}
+
+// WithMaxConcurrency sets the number of invocations processed concurrently
+// in one execution environment, e.g. with Lambda Managed Instances.
+// If it is not set, the value of AWS_LAMBDA_MAX_CONCURRENCY is used,
+// and if that is also not set or invalid, invocations are processed one at a time.
+func WithMaxConcurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = n
+	}
+}
+
+func newOptions(opts []Option) (*options, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.ctx == nil {
+		o.ctx = context.Background()
+	}
+
+	if o.restoreHooks == nil {
+		o.restoreHooks = defaultRestoreHooks
+	}
+
+	if o.concurrency < 1 {
+		o.concurrency = maxConcurrencyFromEnv()
+	}
+
+	if o.postResponseTimeout <= 0 {
+		o.postResponseTimeout = defaultPostResponseTimeout
+	}
+
+	if o.shutdownTimeout <= 0 {
+		o.shutdownTimeout = defaultShutdownTimeout
+	}
+
+	if o.client == nil {
+		c, err := alago.NewClient(&alago.NewClientInput{})
+		if err != nil {
+			return nil, err
+		}
+		o.client = c
+	}
+
+	return o, nil
+}
+
+func maxConcurrencyFromEnv() int {
+	n, err := strconv.Atoi(os.Getenv(maxConcurrencyEnvKey))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// RuntimeAPIError is the error returned by Start and StartHandler
+// when Runtime API returns an error from which the runtime cannot recover.
+// The runtime should exit promptly after receiving it.
+type RuntimeAPIError struct {
+	// http status code
+	StatusCode int
+
+	// The error response
+	Response *ErrorResponse
+}
+
+func (e *RuntimeAPIError) Error() string {
+	if e.Response == nil {
+		return fmt.Sprintf("runtime api error: statusCode:%d", e.StatusCode)
+	}
+	return fmt.Sprintf("runtime api error: statusCode:%d, errorType:%s, errorMessage:%s", e.StatusCode, e.Response.ErrorType, e.Response.ErrorMessage)
+}
+
+// Start runs the event loop of a custom runtime with the typed handler.
+// The loop gets an invocation by InvocationNext, unmarshals the event into TIn,
+// calls handler, and posts the marshaled TOut by InvocationResponse.
+// The context passed to handler is created by NewInvocationContext, so it carries
+// the metadata and the deadline of the invocation.
+// Errors of unmarshaling, handler and posting response are reported by InvocationError,
+// and the loop continues with the next invocation.
+// A panic in handler is recovered and reported by InvocationError with the stack trace,
+// and then the loop stops or continues according to the policy set by WithPanicPolicy.
+// The handler can send the response progressively by OpenResponseStream instead of returning it.
+// The middlewares set by WithMiddleware wrap handler, and see the raw event and the result.
+// After the response has been sent, the post-response hooks (see RegisterPostResponseHook)
+// run before the next invocation is requested.
+//
+// When the max concurrency is more than 1 (see WithMaxConcurrency), Start runs as many loops
+// as the max concurrency, so the handler must be safe for concurrent use.
+// When one of the loops stops with an error, the others stop getting invocations,
+// and Start returns the error after the invocations in progress have posted their results.
+//
+// When the function is initialized for Lambda SnapStart, Start runs the restore lifecycle
+// by RestoreHooks.Restore before getting the first invocation.
+//
+// Start blocks until Runtime API returns an unrecoverable error, the request to it fails,
+// or the context set by WithContext is done, and returns that error.
+// In the last case, the error wraps the error of the context, so that it can be checked
+// by errors.Is(err, context.Canceled). The caller should exit the process after Start returns.
+//
+// When the process receives SIGTERM, e.g. Lambda shuts down the execution environment
+// that has extensions, Start cancels the blocking InvocationNext, lets the invocations
+// in progress post their results within the budget set by WithShutdownTimeout,
+// runs the functions set by WithShutdownFunc, and returns nil,
+// so that the caller exits the process with status 0.
+func Start[TIn, TOut any](handler func(context.Context, TIn) (TOut, error), opts ...Option) error {
+	return StartHandler(NewHandler(handler), opts...)
+}
+
+// StartHandler runs the event loop of a custom runtime with Handler.
+// See Start for the details of the loop.
+func StartHandler(handler Handler, opts ...Option) error {
+	if handler == nil {
+		return fmt.Errorf("handler is nil")
+	}
+
+	o, err := newOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	r := &runner{
+		client:        o.client,
+		handler:       Chain(o.middlewares...)(handler),
+		exportTraceID: o.exportTraceID && o.concurrency == 1,
+		concurrency:   o.concurrency,
+		panicPolicy:   o.panicPolicy,
+
+		postResponseHooks:   o.postResponseHooks,
+		postResponseTimeout: o.postResponseTimeout,
+	}
+
+	if isSnapStart() {
+		if err := o.restoreHooks.Restore(o.ctx, o.client); err != nil {
+			return err
+		}
+	}
+
+	return r.startWithSignal(o.ctx, o)
+}
+
+type runner struct {
+	client        alago.AlagoClient
+	handler       Handler
+	exportTraceID bool
+	concurrency   int
+	panicPolicy   PanicPolicy
+
+	postResponseHooks   []PostResponseHook
+	postResponseTimeout time.Duration
+}
+
+// start runs the loops as many as the concurrency, that share the client.
+// pollCtx cancels the blocking InvocationNext, and postCtx is used for the invocations
+// that have been received, so that they can post their results after polling is stopped.
+// When one of the loops returns an error, the others stop polling, and the first error
+// is returned after the invocations in progress in them complete.
+func (r *runner) start(pollCtx, postCtx context.Context) error {
+	if r.concurrency <= 1 {
+		return r.run(pollCtx, postCtx)
+	}
+
+	pollCtx, cancel := context.WithCancel(pollCtx)
+	defer cancel()
+
+	errCh := make(chan error, r.concurrency)
+	wg := sync.WaitGroup{}
+	for i := 0; i < r.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errCh <- r.run(pollCtx, postCtx)
+			cancel()
+		}()
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	return <-errCh
+}
+
+func (r *runner) run(pollCtx, postCtx context.Context) error {
+	for {
+		if err := r.next(pollCtx, postCtx); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *runner) next(pollCtx, postCtx context.Context) error {
+	next, err := InvocationNext(pollCtx, r.client)
+	if err != nil {
+		return err
+	}
+	if next.Error != nil {
+		return &RuntimeAPIError{StatusCode: next.StatusCode, Response: next.Error}
+	}
+
+	return r.invoke(postCtx, next)
+}
+
+func (r *runner) invoke(ctx context.Context, next *NextOutput) error {
+	if r.exportTraceID {
+		if err := ExportTraceID(next); err != nil {
+			return err
+		}
+	}
+
+	invCtx, cancel := NewInvocationContext(ctx, next)
+	defer cancel()
+	invCtx, hooks := withPostResponseHooks(invCtx)
+	invCtx, stream := withResponseStream(invCtx, ctx, r.client, next.AWSRequestID)
+
+	res, err := callHandler(invCtx, r.handler, next.RawEventResponse)
+	if w := stream.writer(); w != nil {
+		if err := r.closeStream(w, err); err != nil {
+			return err
+		}
+		// the error has been reported by the trailers
+		if err != nil {
+			return nil
+		}
+		if out := w.Output(); out == nil || out.Error != nil {
+			return nil
+		}
+
+		inv, _ := InvocationFromContext(invCtx)
+		return r.runPostResponseHooks(ctx, inv, hooks)
+	}
+	if err != nil {
+		var perr *PanicError
+		if errors.As(err, &perr) {
+			return r.reportPanic(ctx, next.AWSRequestID, perr)
+		}
+		return r.reportError(ctx, next.AWSRequestID, NewFunctionError(err), nil)
+	}
+
+	out, err := InvocationResponse(ctx, r.client, &ResponseInput{
+		AWSRequestID: next.AWSRequestID,
+		Response:     bytes.NewReader(res),
+	})
+	if err != nil {
+		var perr *PayloadTooLargeError
+		if errors.As(err, &perr) {
+			return r.reportError(ctx, next.AWSRequestID, perr.FunctionError(), nil)
+		}
+		return err
+	}
+	if out.Error != nil {
+		if out.StatusCode == http.StatusInternalServerError {
+			return &RuntimeAPIError{StatusCode: out.StatusCode, Response: out.Error}
+		}
+
+		// e.g. the response is too large
+		return r.reportError(ctx, next.AWSRequestID, &FunctionError{
+			ErrorMessage: out.Error.ErrorMessage,
+			ErrorType:    out.Error.ErrorType,
+		}, nil)
+	}
+
+	inv, _ := InvocationFromContext(invCtx)
+	return r.runPostResponseHooks(ctx, inv, hooks)
+}
+
+// closeStream completes the streaming response opened by the handler by OpenResponseStream.
+// The error of the handler is reported by the trailers. If the handler has already closed
+// the stream, the result of that Close is used.
+func (r *runner) closeStream(w *ResponseStreamWriter, handlerErr error) error {
+	var err error
+	var perr *PanicError
+	switch {
+	case errors.As(handlerErr, &perr):
+		err = w.CloseWithError(perr.FunctionError())
+	case handlerErr != nil:
+		err = w.CloseWithError(NewFunctionError(handlerErr))
+	default:
+		err = w.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	if out := w.Output(); out.Error != nil && out.StatusCode == http.StatusInternalServerError {
+		return &RuntimeAPIError{StatusCode: out.StatusCode, Response: out.Error}
+	}
+
+	if perr != nil && r.panicPolicy != PanicPolicyContinue {
+		return perr
+	}
+
+	return nil
+}
+
+func (r *runner) reportPanic(ctx context.Context, awsRequestID string, perr *PanicError) error {
+	if err := r.reportError(ctx, awsRequestID, perr.FunctionError(), perr.XRayErrorCause()); err != nil {
+		return err
+	}
+
+	if r.panicPolicy == PanicPolicyContinue {
+		return nil
+	}
+	return perr
+}
+
+func (r *runner) reportError(ctx context.Context, awsRequestID string, fe *FunctionError, cause *XRayErrorCause) error {
+	out, err := InvocationError(ctx, r.client, &InvocationErrorInput{
+		AWSRequestID:   awsRequestID,
+		Error:          fe,
+		XRayErrorCause: cause,
+	})
+	if err != nil {
+		return err
+	}
+	if out.Error != nil && out.StatusCode == http.StatusInternalServerError {
+		return &RuntimeAPIError{StatusCode: out.StatusCode, Response: out.Error}
+	}
+
+	return nil
+}
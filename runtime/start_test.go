@@ -0,0 +1,507 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/michimani/aws-lambda-api-go/internal/runtimetest"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+type testInput struct {
+	Name string `json:"name"`
+}
+
+type testOutput struct {
+	Message string `json:"message"`
+}
+
+func Test_Start(t *testing.T) {
+	handler := func(ctx context.Context, in testInput) (*testOutput, error) {
+		if in.Name == "" {
+			return nil, errors.New("name is empty")
+		}
+		return &testOutput{Message: "hello " + in.Name}, nil
+	}
+
+	type expectResult struct {
+		requestID string
+		kind      string
+		body      string
+		errorType string
+	}
+
+	cases := []struct {
+		name               string
+		events             []runtimetest.Event
+		responseStatusCode int
+		expect             []expectResult
+	}{
+		{
+			name: "ok",
+			events: []runtimetest.Event{
+				{RequestID: "req-1", Body: `{"name":"alice"}`},
+				{RequestID: "req-2", Body: `{"name":"bob"}`},
+			},
+			expect: []expectResult{
+				{requestID: "req-1", kind: "response", body: `{"message":"hello alice"}`},
+				{requestID: "req-2", kind: "response", body: `{"message":"hello bob"}`},
+			},
+		},
+		{
+			name: "ok: handler returns error",
+			events: []runtimetest.Event{
+				{RequestID: "req-1", Body: `{}`},
+				{RequestID: "req-2", Body: `{"name":"bob"}`},
+			},
+			expect: []expectResult{
+				{requestID: "req-1", kind: "error", body: `{"errorMessage":"name is empty","errorType":"errorString","stackTrace":null}`, errorType: "errorString"},
+				{requestID: "req-2", kind: "response", body: `{"message":"hello bob"}`},
+			},
+		},
+		{
+			name: "ok: failed to unmarshal event",
+			events: []runtimetest.Event{
+				{RequestID: "req-1", Body: `///`},
+			},
+			expect: []expectResult{
+				{requestID: "req-1", kind: "error", errorType: "Runtime.UnmarshalError"},
+			},
+		},
+		{
+			name: "ok: failed to post response",
+			events: []runtimetest.Event{
+				{RequestID: "req-1", Body: `{"name":"alice"}`},
+			},
+			responseStatusCode: http.StatusRequestEntityTooLarge,
+			expect: []expectResult{
+				{requestID: "req-1", kind: "response", body: `{"message":"hello alice"}`},
+				{requestID: "req-1", kind: "error", body: `{"errorMessage":"test-error-message","errorType":"Test.Status413","stackTrace":null}`, errorType: "Test.Status413"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			f := runtimetest.NewServer(tt, c.events...)
+			if c.responseStatusCode != 0 {
+				f.ResponseStatusCode = c.responseStatusCode
+			}
+
+			err := runtime.Start(handler, runtime.WithClient(f.Client(tt)))
+
+			var rae *runtime.RuntimeAPIError
+			asst.ErrorAs(err, &rae)
+			asst.Equal(http.StatusInternalServerError, rae.StatusCode)
+			asst.Equal("Test.NoMoreEvents", rae.Response.ErrorType)
+
+			results := f.Results()
+			asst.Len(results, len(c.expect))
+			for i, e := range c.expect {
+				if i >= len(results) {
+					break
+				}
+				asst.Equal(e.requestID, results[i].RequestID)
+				asst.Equal(e.kind, results[i].Kind)
+				if e.body != "" {
+					asst.JSONEq(e.body, results[i].Body)
+				}
+				asst.Equal(e.errorType, results[i].Header.Get("Lambda-Runtime-Function-Error-Type"))
+			}
+		})
+	}
+}
+
+func Test_Start_fatalPostResponse(t *testing.T) {
+	asst := assert.New(t)
+	f := runtimetest.NewServer(t, runtimetest.Event{RequestID: "req-1", Body: `{"name":"alice"}`}, runtimetest.Event{RequestID: "req-2", Body: `{"name":"bob"}`})
+	f.ResponseStatusCode = http.StatusInternalServerError
+
+	err := runtime.Start(func(ctx context.Context, in testInput) (string, error) {
+		return in.Name, nil
+	}, runtime.WithClient(f.Client(t)))
+
+	var rae *runtime.RuntimeAPIError
+	asst.ErrorAs(err, &rae)
+	asst.Equal("Test.Status500", rae.Response.ErrorType)
+	asst.Len(f.Results(), 1)
+}
+
+func Test_Start_payloadTooLarge(t *testing.T) {
+	asst := assert.New(t)
+	f := runtimetest.NewServer(t,
+		runtimetest.Event{RequestID: "req-1", Body: `{"name":"alice"}`},
+		runtimetest.Event{RequestID: "req-2", Body: `{"name":"bob"}`},
+		runtimetest.Event{RequestID: "req-3", Body: `{"name":"carol"}`},
+	)
+
+	// the size of the marshaled output of alice is equal to the limit, and that of bob exceeds it by 1 byte
+	size := int(runtime.MaxResponsePayloadSize) - len(`{"message":""}`)
+	err := runtime.Start(func(ctx context.Context, in testInput) (*testOutput, error) {
+		switch in.Name {
+		case "alice":
+			return &testOutput{Message: strings.Repeat("a", size)}, nil
+		case "bob":
+			return &testOutput{Message: strings.Repeat("a", size+1)}, nil
+		}
+		return &testOutput{Message: "hello " + in.Name}, nil
+	}, runtime.WithClient(f.Client(t)))
+
+	var rae *runtime.RuntimeAPIError
+	asst.ErrorAs(err, &rae)
+	asst.Equal("Test.NoMoreEvents", rae.Response.ErrorType)
+
+	// the response that exceeds the limit is reported as an error without being posted
+	results := f.Results()
+	if asst.Len(results, 3) {
+		asst.Equal("req-1", results[0].RequestID)
+		asst.Equal("response", results[0].Kind)
+		asst.Len(results[0].Body, int(runtime.MaxResponsePayloadSize))
+		asst.Equal("req-2", results[1].RequestID)
+		asst.Equal("error", results[1].Kind)
+		asst.Equal("Function.ResponseSizeTooLarge", results[1].Header.Get("Lambda-Runtime-Function-Error-Type"))
+		asst.Equal("req-3", results[2].RequestID)
+		asst.Equal("response", results[2].Kind)
+	}
+}
+
+func Test_StartHandler(t *testing.T) {
+	cases := []struct {
+		name    string
+		handler runtime.Handler
+		host    string
+	}{
+		{
+			name:    "ng: handler is nil",
+			handler: nil,
+			host:    "test-host",
+		},
+		{
+			name: "ng: failed to create client",
+			handler: func(ctx context.Context, event []byte) ([]byte, error) {
+				return event, nil
+			},
+			host: "",
+		},
+		{
+			name: "ng: failed to call InvocationNext",
+			handler: func(ctx context.Context, event []byte) ([]byte, error) {
+				return event, nil
+			},
+			host: "\U00000001",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			tt.Setenv("AWS_LAMBDA_RUNTIME_API", c.host)
+
+			err := runtime.StartHandler(c.handler)
+			asst.Error(err)
+		})
+	}
+}
+
+func Test_NewHandler(t *testing.T) {
+	cases := []struct {
+		name            string
+		handler         runtime.Handler
+		event           []byte
+		expect          []byte
+		expectErrorType string
+		wantErr         bool
+	}{
+		{
+			name: "ok",
+			handler: runtime.NewHandler(func(ctx context.Context, in testInput) (testOutput, error) {
+				return testOutput{Message: in.Name}, nil
+			}),
+			event:  []byte(`{"name":"test"}`),
+			expect: []byte(`{"message":"test"}`),
+		},
+		{
+			name: "ng: failed to unmarshal",
+			handler: runtime.NewHandler(func(ctx context.Context, in testInput) (testOutput, error) {
+				return testOutput{Message: in.Name}, nil
+			}),
+			event:           []byte(`///`),
+			expectErrorType: "Runtime.UnmarshalError",
+			wantErr:         true,
+		},
+		{
+			name: "ng: failed to marshal",
+			handler: runtime.NewHandler(func(ctx context.Context, in testInput) (func(), error) {
+				return func() {}, nil
+			}),
+			event:           []byte(`{}`),
+			expectErrorType: "Runtime.MarshalError",
+			wantErr:         true,
+		},
+		{
+			name: "ng: handler returns error",
+			handler: runtime.NewHandler(func(ctx context.Context, in testInput) (testOutput, error) {
+				return testOutput{}, &runtime.FunctionError{ErrorType: "Test.Error"}
+			}),
+			event:           []byte(`{}`),
+			expectErrorType: "Test.Error",
+			wantErr:         true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := c.handler(context.Background(), c.event)
+			if c.wantErr {
+				var fe *runtime.FunctionError
+				asst.ErrorAs(err, &fe)
+				asst.Equal(c.expectErrorType, fe.ErrorType)
+				asst.Nil(out)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, out)
+		})
+	}
+}
+
+func Test_Start_invocationContext(t *testing.T) {
+	asst := assert.New(t)
+	deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	f := runtimetest.NewServer(t, runtimetest.Event{
+		RequestID: "req-1",
+		Body:      `{}`,
+		Header: map[string]string{
+			"Lambda-Runtime-Deadline-Ms":          strconv.FormatInt(deadline.UnixMilli(), 10),
+			"Lambda-Runtime-Invoked-Function-Arn": "test-function-arn",
+		},
+	})
+
+	var inv *runtime.Invocation
+	var ctxDeadline time.Time
+	runtime.Start(func(ctx context.Context, in testInput) (string, error) {
+		inv, _ = runtime.InvocationFromContext(ctx)
+		ctxDeadline, _ = ctx.Deadline()
+		return "", nil
+	}, runtime.WithClient(f.Client(t)))
+
+	asst.NotNil(inv)
+	asst.Equal("req-1", inv.AWSRequestID)
+	asst.Equal("test-function-arn", inv.InvokedFunctionArn)
+	asst.True(deadline.Equal(ctxDeadline))
+}
+
+func Test_Start_traceIDExport(t *testing.T) {
+	cases := []struct {
+		name   string
+		opts   []runtime.Option
+		expect []string
+	}{
+		{
+			name:   "ok: exported",
+			opts:   []runtime.Option{runtime.WithTraceIDExport()},
+			expect: []string{"Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1", ""},
+		},
+		{
+			name:   "ok: not exported",
+			opts:   nil,
+			expect: []string{"previous-trace-id", "previous-trace-id"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			tt.Setenv("_X_AMZN_TRACE_ID", "previous-trace-id")
+			f := runtimetest.NewServer(tt,
+				runtimetest.Event{RequestID: "req-1", Body: `{}`, Header: map[string]string{"Lambda-Runtime-Trace-Id": "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1"}},
+				runtimetest.Event{RequestID: "req-2", Body: `{}`},
+			)
+
+			got := []string{}
+			runtime.Start(func(ctx context.Context, in testInput) (string, error) {
+				got = append(got, os.Getenv("_X_AMZN_TRACE_ID"))
+				return "", nil
+			}, append(c.opts, runtime.WithClient(f.Client(tt)))...)
+
+			asst.Equal(c.expect, got)
+		})
+	}
+}
+
+func Test_Start_withContext(t *testing.T) {
+	asst := assert.New(t)
+
+	// the server blocks GET /runtime/invocation/next until the request is canceled
+	f := runtimetest.NewServer(t)
+	f.BlockWhenEmpty = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	err := runtime.Start(func(ctx context.Context, in testInput) (string, error) {
+		return "", nil
+	}, runtime.WithClient(f.Client(t)), runtime.WithContext(ctx))
+	asst.ErrorIs(err, context.Canceled)
+}
+
+func Test_Start_maxConcurrency(t *testing.T) {
+	cases := []struct {
+		name   string
+		env    string
+		opts   []runtime.Option
+		expect int32
+	}{
+		{
+			name:   "ok: from option",
+			env:    "",
+			opts:   []runtime.Option{runtime.WithMaxConcurrency(3)},
+			expect: 3,
+		},
+		{
+			name:   "ok: from environment variable",
+			env:    "4",
+			opts:   nil,
+			expect: 4,
+		},
+		{
+			name:   "ok: option takes precedence",
+			env:    "4",
+			opts:   []runtime.Option{runtime.WithMaxConcurrency(2)},
+			expect: 2,
+		},
+		{
+			name:   "ok: invalid environment variable",
+			env:    "invalid",
+			opts:   nil,
+			expect: 1,
+		},
+		{
+			name:   "ok: not set",
+			env:    "",
+			opts:   nil,
+			expect: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			tt.Setenv("AWS_LAMBDA_MAX_CONCURRENCY", c.env)
+
+			const eventCount = 8
+			events := []runtimetest.Event{}
+			for i := 0; i < eventCount; i++ {
+				events = append(events, runtimetest.Event{RequestID: fmt.Sprintf("req-%d", i), Body: `{}`})
+			}
+			f := runtimetest.NewServer(tt, events...)
+			f.BlockWhenEmpty = true
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var running, maxRunning int32
+			var mu sync.Mutex
+			seen := map[string]bool{}
+			handler := func(ctx context.Context, in testInput) (string, error) {
+				n := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+
+				id, _ := runtime.RequestIDFromContext(ctx)
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+
+				// wait for the other loops to get invocations
+				time.Sleep(20 * time.Millisecond)
+				return id, nil
+			}
+
+			done := make(chan error)
+			go func() {
+				done <- runtime.Start(handler, append(c.opts, runtime.WithClient(f.Client(tt)), runtime.WithContext(ctx))...)
+			}()
+
+			asst.Eventually(func() bool {
+				return len(f.Results()) == eventCount
+			}, 5*time.Second, 10*time.Millisecond)
+			cancel()
+
+			err := <-done
+			asst.ErrorIs(err, context.Canceled)
+			asst.Equal(c.expect, atomic.LoadInt32(&maxRunning))
+			asst.Len(seen, eventCount)
+			for _, r := range f.Results() {
+				asst.Equal("response", r.Kind)
+				asst.JSONEq(strconv.Quote(r.RequestID), r.Body)
+			}
+		})
+	}
+}
+
+func Test_Start_maxConcurrencyFatalError(t *testing.T) {
+	asst := assert.New(t)
+	f := runtimetest.NewServer(t, runtimetest.Event{RequestID: "req-1", Body: `{}`})
+
+	err := runtime.Start(func(ctx context.Context, in testInput) (string, error) {
+		return "", nil
+	}, runtime.WithClient(f.Client(t)), runtime.WithMaxConcurrency(4))
+
+	var rae *runtime.RuntimeAPIError
+	asst.ErrorAs(err, &rae)
+	asst.Equal("Test.NoMoreEvents", rae.Response.ErrorType)
+}
+
+func Test_Start_maxConcurrencyInFlight(t *testing.T) {
+	asst := assert.New(t)
+	f := runtimetest.NewServer(t,
+		runtimetest.Event{RequestID: "req-slow", Body: `{}`},
+		runtimetest.Event{RequestID: "req-panic", Body: `{}`},
+	)
+	f.BlockWhenEmpty = true
+
+	started := make(chan struct{})
+	err := runtime.Start(func(ctx context.Context, in testInput) (string, error) {
+		id, _ := runtime.RequestIDFromContext(ctx)
+		if id == "req-panic" {
+			<-started
+			panic("test-panic")
+		}
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		return id, ctx.Err()
+	}, runtime.WithClient(f.Client(t)), runtime.WithMaxConcurrency(2))
+
+	// the panic stops polling, but the invocation in progress in the other loop posts its response
+	var perr *runtime.PanicError
+	asst.ErrorAs(err, &perr)
+
+	kinds := map[string]string{}
+	for _, r := range f.Results() {
+		kinds[r.RequestID] = r.Kind
+	}
+	asst.Equal(map[string]string{"req-slow": "response", "req-panic": "error"}, kinds)
+}
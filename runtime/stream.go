@@ -0,0 +1,338 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/michimani/aws-lambda-api-go/alago"
+	"github.com/michimani/aws-lambda-api-go/internal"
+)
+
+const (
+	// Request Header Names for response streaming
+	requestHeaderLambdaRuntimeFunctionResponseMode string = "Lambda-Runtime-Function-Response-Mode"
+
+	// Trailer Names for response streaming
+	trailerLambdaRuntimeFunctionErrorType string = "Lambda-Runtime-Function-Error-Type"
+	trailerLambdaRuntimeFunctionErrorBody string = "Lambda-Runtime-Function-Error-Body"
+
+	responseModeStreaming    string = "streaming"
+	defaultStreamContentType string = "application/octet-stream"
+	responseStreamBufferSize int    = 4 * 1024
+)
+
+// InvocationResponseStream starts a streaming response for the invocation, and returns the writer for the response body.
+// The body is sent with chunked transfer encoding, and the data written to the writer is sent to Lambda
+// each time when the buffer is flushed.
+// The caller must call Close or CloseWithError of the returned writer to complete the response.
+//
+// document: https://docs.aws.amazon.com/lambda/latest/dg/runtimes-custom.html#runtimes-custom-response-streaming
+func InvocationResponseStream(ctx context.Context, client alago.AlagoClient, in *ResponseStreamInput) (*ResponseStreamWriter, error) {
+	if in == nil {
+		return nil, fmt.Errorf("ResponseStreamInput is nil")
+	}
+	if in.AWSRequestID == "" {
+		return nil, fmt.Errorf("ResponseStreamInput.AWSRequestID is empty")
+	}
+
+	pr, pw := io.Pipe()
+
+	url := fmt.Sprintf(invocationResponseEndpointFmt, client.Host(), in.AWSRequestID)
+	body := &streamBody{r: pr, started: make(chan struct{})}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := in.ContentType
+	if contentType == "" {
+		contentType = defaultStreamContentType
+	}
+
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(requestHeaderLambdaRuntimeFunctionResponseMode, responseModeStreaming)
+	req.Trailer = http.Header{
+		trailerLambdaRuntimeFunctionErrorType: nil,
+		trailerLambdaRuntimeFunctionErrorBody: nil,
+	}
+
+	w := &ResponseStreamWriter{
+		report:    in.ReportPayloadTooLarge,
+		pw:        pw,
+		bw:        bufio.NewWriterSize(pw, responseStreamBufferSize),
+		req:       req,
+		body:      body,
+		done:      make(chan struct{}),
+		closeDone: make(chan struct{}),
+	}
+
+	go w.do(client.HttpClient(), pr)
+
+	return w, nil
+}
+
+// ResponseStreamWriter is the writer for the body of streaming response.
+// It is returned by InvocationResponseStream.
+type ResponseStreamWriter struct {
+	mu     sync.Mutex
+	closed bool
+
+	written  int64
+	exceeded *PayloadTooLargeError
+	report   bool
+
+	pw   *io.PipeWriter
+	bw   *bufio.Writer
+	req  *http.Request
+	body *streamBody
+
+	done chan struct{}
+	out  *ResponseOutput
+	err  error
+
+	// closeDone is closed after closeErr is set by the first Close or CloseWithError.
+	closeDone chan struct{}
+	closeErr  error
+}
+
+func (w *ResponseStreamWriter) do(hc *http.Client, pr *io.PipeReader) {
+	defer close(w.done)
+
+	res, err := hc.Do(w.req)
+	if err != nil {
+		err = internal.ContextError(w.req.Context(), err)
+		pr.CloseWithError(err)
+		w.err = err
+		return
+	}
+	defer res.Body.Close()
+
+	// Lambda may respond before the whole body is sent (e.g. invalid request ID).
+	// Unblock the writer in that case.
+	pr.CloseWithError(errors.New("response stream has been completed"))
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		w.err = internal.ContextError(w.req.Context(), err)
+		return
+	}
+
+	w.out, w.err = generateResponseStreamOutput(res.StatusCode, b)
+}
+
+// Write writes p to the buffer of the response stream.
+// The buffered data is sent when the buffer is full or Flush is called.
+// If the total size exceeds MaxStreamingResponsePayloadSize, p is not written
+// and *PayloadTooLargeError is returned.
+func (w *ResponseStreamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, errors.New("ResponseStreamWriter is already closed")
+	}
+	if w.exceeded != nil {
+		return 0, w.exceeded
+	}
+
+	if size := w.written + int64(len(p)); size > MaxStreamingResponsePayloadSize {
+		w.exceeded = &PayloadTooLargeError{Size: size, Limit: MaxStreamingResponsePayloadSize}
+		return 0, w.exceeded
+	}
+
+	n, err := w.bw.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Flush sends the buffered data to Lambda.
+func (w *ResponseStreamWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errors.New("ResponseStreamWriter is already closed")
+	}
+
+	return w.bw.Flush()
+}
+
+// Close flushes the buffered data and completes the response.
+// It blocks until Lambda responds to the request, and returns the error of the request.
+// The result of the request is available via Output.
+// If the payload has exceeded the limit and ResponseStreamInput.ReportPayloadTooLarge is true,
+// the error is reported in the same way as CloseWithError.
+// Close and CloseWithError complete the response only once; the later calls do nothing
+// and return the same error as the first one.
+func (w *ResponseStreamWriter) Close() error {
+	return w.close(nil)
+}
+
+// CloseWithError reports fe as the error that occurred in the middle of the stream,
+// using Lambda-Runtime-Function-Error-Type and Lambda-Runtime-Function-Error-Body trailers,
+// and completes the response.
+func (w *ResponseStreamWriter) CloseWithError(fe *FunctionError) error {
+	return w.close(fe)
+}
+
+func (w *ResponseStreamWriter) close(fe *FunctionError) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		<-w.closeDone
+		return w.closeErr
+	}
+	w.closed = true
+
+	w.closeErr = w.complete(fe)
+	close(w.closeDone)
+	return w.closeErr
+}
+
+// complete sends the trailers if fe is not nil, and completes the request.
+// It must be called with w.mu locked, and unlocks it.
+func (w *ResponseStreamWriter) complete(fe *FunctionError) error {
+	if fe == nil && w.report && w.exceeded != nil {
+		fe = w.exceeded.FunctionError()
+	}
+
+	// The error of flushing is reported by the request itself.
+	_ = w.bw.Flush()
+
+	if fe != nil {
+		j, err := json.Marshal(fe)
+		if err != nil {
+			w.mu.Unlock()
+			w.pw.CloseWithError(err)
+			<-w.done
+			return err
+		}
+		// Trailers must not be updated until the request header has been written.
+		select {
+		case <-w.body.started:
+		case <-w.done:
+		}
+		w.req.Trailer.Set(trailerLambdaRuntimeFunctionErrorType, fe.ErrorType)
+		w.req.Trailer.Set(trailerLambdaRuntimeFunctionErrorBody, base64.StdEncoding.EncodeToString(j))
+	}
+
+	w.pw.Close()
+	w.mu.Unlock()
+
+	<-w.done
+	return w.err
+}
+
+// Output returns the result of the request. It is nil until the writer is closed.
+func (w *ResponseStreamWriter) Output() *ResponseOutput {
+	select {
+	case <-w.done:
+		return w.out
+	default:
+		return nil
+	}
+}
+
+// streamBody is the request body of streaming response.
+// started is closed when the body is read for the first time,
+// that is, after the request header has been written.
+type streamBody struct {
+	r       *io.PipeReader
+	once    sync.Once
+	started chan struct{}
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	b.once.Do(func() { close(b.started) })
+	return b.r.Read(p)
+}
+
+func (b *streamBody) Close() error {
+	return b.r.Close()
+}
+
+func generateResponseStreamOutput(sc int, body []byte) (*ResponseOutput, error) {
+	if sc == http.StatusAccepted && len(body) == 0 {
+		return &ResponseOutput{StatusCode: sc}, nil
+	}
+
+	return generateResponseOutput(sc, body)
+}
+
+// ErrNoResponseStream is returned by OpenResponseStream
+// when the context is not the one passed to the handler by Start.
+var ErrNoResponseStream = errors.New("response stream is not available in the context")
+
+type responseStreamContextKey struct{}
+
+// responseStream opens the streaming response of the invocation at most once.
+type responseStream struct {
+	ctx          context.Context
+	client       alago.AlagoClient
+	awsRequestID string
+
+	mu sync.Mutex
+	w  *ResponseStreamWriter
+}
+
+func withResponseStream(ctx, loopCtx context.Context, client alago.AlagoClient, awsRequestID string) (context.Context, *responseStream) {
+	s := &responseStream{ctx: loopCtx, client: client, awsRequestID: awsRequestID}
+	return context.WithValue(ctx, responseStreamContextKey{}, s), s
+}
+
+func (s *responseStream) writer() *ResponseStreamWriter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w
+}
+
+// OpenResponseStream starts the streaming response of the invocation stored in ctx,
+// so that the handler can send the response progressively.
+// ctx must be the context passed to the handler by Start, otherwise it returns ErrNoResponseStream.
+// The stream can be opened only once for the invocation.
+//
+// The loop completes the stream after the handler returns, so the handler does not have to close it.
+// The response returned by the handler is ignored, and the error returned by the handler
+// is reported by CloseWithError unless the handler has already closed the stream.
+// The payload exceeding MaxStreamingResponsePayloadSize is reported as well.
+func OpenResponseStream(ctx context.Context, contentType string) (*ResponseStreamWriter, error) {
+	s, ok := ctx.Value(responseStreamContextKey{}).(*responseStream)
+	if !ok {
+		return nil, ErrNoResponseStream
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.w != nil {
+		return nil, errors.New("response stream is already opened")
+	}
+
+	w, err := InvocationResponseStream(s.ctx, s.client, &ResponseStreamInput{
+		AWSRequestID:          s.awsRequestID,
+		ContentType:           contentType,
+		ReportPayloadTooLarge: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.w = w
+	return w, nil
+}
+
+// ResponseStreamOpened reports whether the streaming response of the invocation stored in ctx
+// has been opened by OpenResponseStream. It is useful for middlewares, since the response
+// returned by the handler is ignored in that case.
+func ResponseStreamOpened(ctx context.Context) bool {
+	s, ok := ctx.Value(responseStreamContextKey{}).(*responseStream)
+	return ok && s.writer() != nil
+}
@@ -0,0 +1,509 @@
+package runtime_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/alago"
+	"github.com/michimani/aws-lambda-api-go/internal/runtimetest"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+type streamRequest struct {
+	method  string
+	path    string
+	header  http.Header
+	body    string
+	trailer http.Header
+}
+
+func newStreamServer(t *testing.T, statusCode int, resBody string) (*httptest.Server, <-chan streamRequest) {
+	ch := make(chan streamRequest, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		ch <- streamRequest{
+			method:  r.Method,
+			path:    r.URL.Path,
+			header:  r.Header,
+			body:    string(b),
+			trailer: r.Trailer,
+		}
+		w.WriteHeader(statusCode)
+		w.Write([]byte(resBody))
+	}))
+	t.Cleanup(s.Close)
+	return s, ch
+}
+
+func Test_InvocationResponseStream(t *testing.T) {
+	cases := []struct {
+		name        string
+		statusCode  int
+		resBody     string
+		in          *runtime.ResponseStreamInput
+		chunks      []string
+		fe          *runtime.FunctionError
+		expectCT    string
+		expectBody  string
+		expectTrail map[string]string
+		expect      *runtime.ResponseOutput
+		wantErr     bool
+	}{
+		{
+			name:       "ok",
+			statusCode: 202,
+			resBody:    `{"status":"OK"}`,
+			in: &runtime.ResponseStreamInput{
+				AWSRequestID: "test-request-id",
+				ContentType:  "text/plain",
+			},
+			chunks:     []string{"hello, ", "world"},
+			expectCT:   "text/plain",
+			expectBody: "hello, world",
+			expectTrail: map[string]string{
+				"Lambda-Runtime-Function-Error-Type": "",
+				"Lambda-Runtime-Function-Error-Body": "",
+			},
+			expect: &runtime.ResponseOutput{
+				StatusCode: 202,
+				Status:     "OK",
+			},
+		},
+		{
+			name:       "ok: default content type and empty response body",
+			statusCode: 202,
+			resBody:    "",
+			in: &runtime.ResponseStreamInput{
+				AWSRequestID: "test-request-id",
+			},
+			chunks:     []string{"hello"},
+			expectCT:   "application/octet-stream",
+			expectBody: "hello",
+			expect: &runtime.ResponseOutput{
+				StatusCode: 202,
+			},
+		},
+		{
+			name:       "ok: mid-stream error",
+			statusCode: 202,
+			resBody:    `{"status":"OK"}`,
+			in: &runtime.ResponseStreamInput{
+				AWSRequestID: "test-request-id",
+			},
+			chunks: []string{"hello"},
+			fe: &runtime.FunctionError{
+				ErrorMessage: "test-error-message",
+				ErrorType:    "test-error-type",
+			},
+			expectCT:   "application/octet-stream",
+			expectBody: "hello",
+			expectTrail: map[string]string{
+				"Lambda-Runtime-Function-Error-Type": "test-error-type",
+				"Lambda-Runtime-Function-Error-Body": base64.StdEncoding.EncodeToString([]byte(`{"errorMessage":"test-error-message","errorType":"test-error-type","stackTrace":null}`)),
+			},
+			expect: &runtime.ResponseOutput{
+				StatusCode: 202,
+				Status:     "OK",
+			},
+		},
+		{
+			name:       "ok: not OK status code",
+			statusCode: 413,
+			resBody:    `{"errorMessage":"test-error-message","errorType":"Function.ResponseSizeTooLarge"}`,
+			in: &runtime.ResponseStreamInput{
+				AWSRequestID: "test-request-id",
+			},
+			chunks:     []string{"hello"},
+			expectCT:   "application/octet-stream",
+			expectBody: "hello",
+			expect: &runtime.ResponseOutput{
+				StatusCode: 413,
+				Error: &runtime.ErrorResponse{
+					ErrorMessage: "test-error-message",
+					ErrorType:    "Function.ResponseSizeTooLarge",
+				},
+			},
+		},
+		{
+			name:       "ng: failed to parse response",
+			statusCode: 500,
+			resBody:    `///`,
+			in: &runtime.ResponseStreamInput{
+				AWSRequestID: "test-request-id",
+			},
+			chunks:     []string{"hello"},
+			expectCT:   "application/octet-stream",
+			expectBody: "hello",
+			wantErr:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			s, reqCh := newStreamServer(tt, c.statusCode, c.resBody)
+			tt.Setenv("AWS_LAMBDA_RUNTIME_API", strings.TrimPrefix(s.URL, "http://"))
+
+			ac, err := alago.NewClient(&alago.NewClientInput{HttpClient: s.Client()})
+			asst.NoError(err)
+
+			w, err := runtime.InvocationResponseStream(context.Background(), ac, c.in)
+			asst.NoError(err)
+			asst.NotNil(w)
+
+			for _, chunk := range c.chunks {
+				_, err := w.Write([]byte(chunk))
+				asst.NoError(err)
+				asst.NoError(w.Flush())
+			}
+
+			if c.fe != nil {
+				err = w.CloseWithError(c.fe)
+			} else {
+				err = w.Close()
+			}
+
+			req := <-reqCh
+			asst.Equal(http.MethodPost, req.method)
+			asst.Equal("/2018-06-01/runtime/invocation/test-request-id/response", req.path)
+			asst.Equal(c.expectCT, req.header.Get("Content-Type"))
+			asst.Equal("streaming", req.header.Get("Lambda-Runtime-Function-Response-Mode"))
+			asst.Equal(c.expectBody, req.body)
+			for k, v := range c.expectTrail {
+				asst.Equal(v, req.trailer.Get(k))
+			}
+
+			if c.wantErr {
+				asst.Error(err)
+				asst.Nil(w.Output())
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, w.Output())
+
+			_, err = w.Write([]byte("after close"))
+			asst.Error(err)
+			asst.Error(w.Flush())
+			// closing again returns the result of the first close
+			asst.NoError(w.Close())
+			asst.NoError(w.CloseWithError(&runtime.FunctionError{ErrorType: "ignored"}))
+			asst.Equal(c.expect, w.Output())
+		})
+	}
+}
+
+func Test_InvocationResponseStream_flush(t *testing.T) {
+	asst := assert.New(t)
+
+	received := make(chan string)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 64)
+		n, _ := r.Body.Read(buf)
+		received <- string(buf[:n])
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer s.Close()
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", strings.TrimPrefix(s.URL, "http://"))
+
+	ac, err := alago.NewClient(&alago.NewClientInput{HttpClient: s.Client()})
+	asst.NoError(err)
+
+	w, err := runtime.InvocationResponseStream(context.Background(), ac, &runtime.ResponseStreamInput{AWSRequestID: "test-request-id"})
+	asst.NoError(err)
+
+	_, err = w.Write([]byte("first"))
+	asst.NoError(err)
+	asst.NoError(w.Flush())
+
+	// the first chunk arrives before the stream is closed
+	asst.Equal("first", <-received)
+
+	_, err = w.Write([]byte("second"))
+	asst.NoError(err)
+	asst.NoError(w.Close())
+}
+
+func Test_InvocationResponseStream_invalidInput(t *testing.T) {
+	cases := []struct {
+		name string
+		host string
+		in   *runtime.ResponseStreamInput
+	}{
+		{
+			name: "ng: ResponseStreamInput is nil",
+			host: "test-host",
+			in:   nil,
+		},
+		{
+			name: "ng: AWSRequestID is empty",
+			host: "test-host",
+			in:   &runtime.ResponseStreamInput{},
+		},
+		{
+			name: "ng: failed to create request",
+			host: "\U00000001",
+			in:   &runtime.ResponseStreamInput{AWSRequestID: "test-request-id"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			tt.Setenv("AWS_LAMBDA_RUNTIME_API", c.host)
+
+			ac, err := alago.NewClient(&alago.NewClientInput{})
+			asst.NoError(err)
+
+			w, err := runtime.InvocationResponseStream(context.Background(), ac, c.in)
+			asst.Error(err)
+			asst.Nil(w)
+		})
+	}
+}
+
+func Test_InvocationResponseStream_canceledContext(t *testing.T) {
+	asst := assert.New(t)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer s.Close()
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", strings.TrimPrefix(s.URL, "http://"))
+
+	ac, err := alago.NewClient(&alago.NewClientInput{HttpClient: s.Client()})
+	asst.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := runtime.InvocationResponseStream(ctx, ac, &runtime.ResponseStreamInput{AWSRequestID: "test-request-id"})
+	asst.NoError(err)
+
+	_, err = w.Write([]byte("hello"))
+	asst.NoError(err)
+	cancel()
+
+	err = w.Close()
+	asst.ErrorIs(err, context.Canceled)
+}
+
+func Test_InvocationResponseStream_payloadTooLarge(t *testing.T) {
+	cases := []struct {
+		name        string
+		report      bool
+		expectTrail map[string]string
+	}{
+		{
+			name: "ok: not reported",
+			expectTrail: map[string]string{
+				"Lambda-Runtime-Function-Error-Type": "",
+			},
+		},
+		{
+			name:   "ok: reported",
+			report: true,
+			expectTrail: map[string]string{
+				"Lambda-Runtime-Function-Error-Type": "Function.ResponseSizeTooLarge",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			s, reqCh := newStreamServer(tt, 202, `{"status":"OK"}`)
+			tt.Setenv("AWS_LAMBDA_RUNTIME_API", strings.TrimPrefix(s.URL, "http://"))
+
+			ac, err := alago.NewClient(&alago.NewClientInput{HttpClient: s.Client()})
+			asst.NoError(err)
+
+			w, err := runtime.InvocationResponseStream(context.Background(), ac, &runtime.ResponseStreamInput{
+				AWSRequestID:          "test-request-id",
+				ReportPayloadTooLarge: c.report,
+			})
+			asst.NoError(err)
+
+			_, err = w.Write([]byte("hello"))
+			asst.NoError(err)
+
+			// the chunk that exceeds the limit is not written
+			n, err := w.Write(make([]byte, runtime.MaxStreamingResponsePayloadSize))
+			asst.Equal(0, n)
+			asst.ErrorIs(err, runtime.ErrPayloadTooLarge)
+			var perr *runtime.PayloadTooLargeError
+			asst.ErrorAs(err, &perr)
+			asst.Equal(runtime.MaxStreamingResponsePayloadSize+5, perr.Size)
+
+			// the following writes fail as well
+			_, err = w.Write([]byte("world"))
+			asst.ErrorIs(err, runtime.ErrPayloadTooLarge)
+
+			asst.NoError(w.Close())
+
+			req := <-reqCh
+			asst.Equal("hello", req.body)
+			for k, v := range c.expectTrail {
+				asst.Equal(v, req.trailer.Get(k))
+			}
+		})
+	}
+}
+
+func Test_Start_responseStream(t *testing.T) {
+	type expectResult struct {
+		kind        string
+		contentType string
+		body        string
+		errorType   string
+	}
+
+	cases := []struct {
+		name    string
+		handler runtime.Handler
+		expect  []expectResult
+	}{
+		{
+			name: "ok",
+			handler: func(ctx context.Context, event []byte) ([]byte, error) {
+				w, err := runtime.OpenResponseStream(ctx, "text/plain")
+				if err != nil {
+					return nil, err
+				}
+				w.Write([]byte("hello, "))
+				w.Flush()
+				w.Write([]byte("world"))
+				return []byte("ignored"), nil
+			},
+			expect: []expectResult{
+				{kind: "response", contentType: "text/plain", body: "hello, world"},
+			},
+		},
+		{
+			name: "ok: handler returns error after opening stream",
+			handler: func(ctx context.Context, event []byte) ([]byte, error) {
+				w, err := runtime.OpenResponseStream(ctx, "")
+				if err != nil {
+					return nil, err
+				}
+				w.Write([]byte("hello"))
+				return nil, &runtime.FunctionError{ErrorMessage: "test-error-message", ErrorType: "Test.Error"}
+			},
+			expect: []expectResult{
+				{kind: "response", contentType: "application/octet-stream", body: "hello", errorType: "Test.Error"},
+			},
+		},
+		{
+			name: "ng: stream is opened twice",
+			handler: func(ctx context.Context, event []byte) ([]byte, error) {
+				if _, err := runtime.OpenResponseStream(ctx, ""); err != nil {
+					return nil, err
+				}
+				_, err := runtime.OpenResponseStream(ctx, "")
+				return nil, err
+			},
+			expect: []expectResult{
+				{kind: "response", contentType: "application/octet-stream", errorType: "errorString"},
+			},
+		},
+		{
+			name: "ok: not opened",
+			handler: func(ctx context.Context, event []byte) ([]byte, error) {
+				return []byte("hello"), nil
+			},
+			expect: []expectResult{
+				{kind: "response", contentType: "application/json;charset=UTF-8", body: "hello"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			f := runtimetest.NewServer(tt, runtimetest.Event{RequestID: "req-1", Body: `{}`})
+
+			err := runtime.StartHandler(c.handler, runtime.WithClient(f.Client(tt)))
+			var rae *runtime.RuntimeAPIError
+			asst.ErrorAs(err, &rae)
+			asst.Equal("Test.NoMoreEvents", rae.Response.ErrorType)
+
+			results := f.Results()
+			if !asst.Len(results, len(c.expect)) {
+				return
+			}
+			for i, e := range c.expect {
+				asst.Equal("req-1", results[i].RequestID)
+				asst.Equal(e.kind, results[i].Kind)
+				asst.Equal(e.contentType, results[i].Header.Get("Content-Type"))
+				asst.Equal(e.body, results[i].Body)
+				asst.Equal(e.errorType, results[i].Trailer.Get("Lambda-Runtime-Function-Error-Type"))
+			}
+		})
+	}
+}
+
+func Test_Start_responseStreamClosedByHandler(t *testing.T) {
+	asst := assert.New(t)
+	f := runtimetest.NewServer(t,
+		runtimetest.Event{RequestID: "req-1", Body: `"alice"`},
+		runtimetest.Event{RequestID: "req-2", Body: `"bob"`},
+	)
+
+	err := runtime.StartHandler(func(ctx context.Context, event []byte) ([]byte, error) {
+		w, err := runtime.OpenResponseStream(ctx, "text/plain")
+		if err != nil {
+			return nil, err
+		}
+		defer w.Close()
+
+		_, err = w.Write(event)
+		return nil, err
+	}, runtime.WithClient(f.Client(t)))
+
+	// the loop continues after the handler closes the stream
+	var rae *runtime.RuntimeAPIError
+	asst.ErrorAs(err, &rae)
+	asst.Equal("Test.NoMoreEvents", rae.Response.ErrorType)
+	asst.Equal(3, f.Polls())
+
+	results := f.Results()
+	if asst.Len(results, 2) {
+		asst.Equal("req-1", results[0].RequestID)
+		asst.Equal(`"alice"`, results[0].Body)
+		asst.Equal("req-2", results[1].RequestID)
+		asst.Equal(`"bob"`, results[1].Body)
+	}
+}
+
+func Test_ResponseStreamOpened(t *testing.T) {
+	asst := assert.New(t)
+	f := runtimetest.NewServer(t, runtimetest.Event{RequestID: "req-1", Body: `{}`})
+
+	opened := []bool{}
+	err := runtime.StartHandler(func(ctx context.Context, event []byte) ([]byte, error) {
+		opened = append(opened, runtime.ResponseStreamOpened(ctx))
+		if _, err := runtime.OpenResponseStream(ctx, ""); err != nil {
+			return nil, err
+		}
+		opened = append(opened, runtime.ResponseStreamOpened(ctx))
+		return nil, nil
+	}, runtime.WithClient(f.Client(t)))
+
+	var rae *runtime.RuntimeAPIError
+	asst.ErrorAs(err, &rae)
+	asst.Equal([]bool{false, true}, opened)
+	asst.False(runtime.ResponseStreamOpened(context.Background()))
+}
+
+func Test_OpenResponseStream(t *testing.T) {
+	asst := assert.New(t)
+
+	w, err := runtime.OpenResponseStream(context.Background(), "")
+	asst.ErrorIs(err, runtime.ErrNoResponseStream)
+	asst.Nil(w)
+}
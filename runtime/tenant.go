@@ -0,0 +1,122 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNoTenantID is returned when the invocation has no tenant ID.
+var ErrNoTenantID = errors.New("tenant ID is not found in the context")
+
+// TenantIDFromContext returns the tenant ID of the invocation stored in ctx by NewInvocationContext.
+// It returns false if there is no invocation or the invocation has no tenant ID.
+func TenantIDFromContext(ctx context.Context) (string, bool) {
+	inv, ok := InvocationFromContext(ctx)
+	if !ok || inv.TenantID == "" {
+		return "", false
+	}
+	return inv.TenantID, true
+}
+
+// TenantCache holds a value per tenant, e.g. a client or a cache for the tenant,
+// so that the state of the tenants is kept separate.
+// The value is created by the function given to NewTenantCache at the first time
+// it is required for the tenant. TenantCache is safe for concurrent use.
+type TenantCache[T any] struct {
+	newFn func(ctx context.Context, tenantID string) (T, error)
+
+	mu      sync.Mutex
+	entries map[string]*tenantEntry[T]
+}
+
+type tenantEntry[T any] struct {
+	once sync.Once
+	v    T
+	err  error
+}
+
+// NewTenantCache returns a new TenantCache that creates the value for a tenant by newFn.
+func NewTenantCache[T any](newFn func(ctx context.Context, tenantID string) (T, error)) *TenantCache[T] {
+	return &TenantCache[T]{
+		newFn:   newFn,
+		entries: map[string]*tenantEntry[T]{},
+	}
+}
+
+// Get returns the value for the tenant of the invocation stored in ctx.
+// If the invocation has no tenant ID, it returns ErrNoTenantID.
+func (c *TenantCache[T]) Get(ctx context.Context) (T, error) {
+	tenantID, ok := TenantIDFromContext(ctx)
+	if !ok {
+		var zero T
+		return zero, ErrNoTenantID
+	}
+	return c.GetByID(ctx, tenantID)
+}
+
+// GetByID returns the value for the tenant.
+// If newFn returns an error, the error is returned and the value is created again next time.
+// If newFn panics, the panic is propagated to the caller, the concurrent callers for the tenant
+// get an error, and the value is created again next time.
+func (c *TenantCache[T]) GetByID(ctx context.Context, tenantID string) (T, error) {
+	c.mu.Lock()
+	e, ok := c.entries[tenantID]
+	if !ok {
+		e = &tenantEntry[T]{}
+		c.entries[tenantID] = e
+	}
+	c.mu.Unlock()
+
+	var panicked any
+	e.once.Do(func() {
+		defer func() {
+			if panicked = recover(); panicked != nil {
+				e.err = fmt.Errorf("creating the value for tenant %s panicked: %v", tenantID, panicked)
+			}
+		}()
+		e.v, e.err = c.newFn(ctx, tenantID)
+	})
+
+	if e.err != nil {
+		c.mu.Lock()
+		if c.entries[tenantID] == e {
+			delete(c.entries, tenantID)
+		}
+		c.mu.Unlock()
+
+		if panicked != nil {
+			panic(panicked)
+		}
+
+		var zero T
+		return zero, e.err
+	}
+
+	return e.v, nil
+}
+
+// Delete removes the value for the tenant.
+func (c *TenantCache[T]) Delete(tenantID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, tenantID)
+}
+
+// PerTenant returns the handler that routes each invocation to the handler for its tenant.
+// The handler for a tenant is created by newHandler at the first invocation of the tenant
+// and reused for the following invocations of it.
+// If the invocation has no tenant ID, the returned handler returns ErrNoTenantID.
+func PerTenant[TIn, TOut any](newHandler func(ctx context.Context, tenantID string) (func(context.Context, TIn) (TOut, error), error)) func(context.Context, TIn) (TOut, error) {
+	handlers := NewTenantCache(newHandler)
+
+	return func(ctx context.Context, in TIn) (TOut, error) {
+		h, err := handlers.Get(ctx)
+		if err != nil {
+			var zero TOut
+			return zero, err
+		}
+		return h(ctx, in)
+	}
+}
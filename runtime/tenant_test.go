@@ -0,0 +1,182 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/internal/runtimetest"
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func tenantContext(tenantID string) (context.Context, context.CancelFunc) {
+	return runtime.NewInvocationContext(context.Background(), &runtime.NextOutput{AWSRequestID: "test-request-id", TenantID: tenantID})
+}
+
+func Test_TenantIDFromContext(t *testing.T) {
+	cases := []struct {
+		name     string
+		ctx      func() (context.Context, context.CancelFunc)
+		expect   string
+		expectOK bool
+	}{
+		{
+			name:     "ok",
+			ctx:      func() (context.Context, context.CancelFunc) { return tenantContext("tenant-a") },
+			expect:   "tenant-a",
+			expectOK: true,
+		},
+		{
+			name:     "ok: no tenant ID",
+			ctx:      func() (context.Context, context.CancelFunc) { return tenantContext("") },
+			expect:   "",
+			expectOK: false,
+		},
+		{
+			name:     "ok: no invocation",
+			ctx:      func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			expect:   "",
+			expectOK: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			ctx, cancel := c.ctx()
+			defer cancel()
+
+			id, ok := runtime.TenantIDFromContext(ctx)
+			asst.Equal(c.expectOK, ok)
+			asst.Equal(c.expect, id)
+		})
+	}
+}
+
+func Test_TenantCache(t *testing.T) {
+	asst := assert.New(t)
+
+	var created int32
+	failOnce := map[string]bool{"tenant-c": true}
+	var mu sync.Mutex
+	c := runtime.NewTenantCache(func(ctx context.Context, tenantID string) (*string, error) {
+		mu.Lock()
+		fail := failOnce[tenantID]
+		delete(failOnce, tenantID)
+		mu.Unlock()
+		if fail {
+			return nil, errors.New("failed to create")
+		}
+
+		atomic.AddInt32(&created, 1)
+		v := "value of " + tenantID
+		return &v, nil
+	})
+
+	// concurrent access for the same tenant creates the value only once
+	wg := sync.WaitGroup{}
+	values := make([]*string, 10)
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := tenantContext("tenant-a")
+			defer cancel()
+			v, err := c.Get(ctx)
+			asst.NoError(err)
+			values[i] = v
+		}(i)
+	}
+	wg.Wait()
+
+	asst.Equal(int32(1), atomic.LoadInt32(&created))
+	for _, v := range values {
+		asst.Same(values[0], v)
+	}
+
+	// another tenant has another value
+	b, err := c.GetByID(context.Background(), "tenant-b")
+	asst.NoError(err)
+	asst.Equal("value of tenant-b", *b)
+	asst.NotSame(values[0], b)
+
+	// failed creation is retried
+	_, err = c.GetByID(context.Background(), "tenant-c")
+	asst.Error(err)
+	v, err := c.GetByID(context.Background(), "tenant-c")
+	asst.NoError(err)
+	asst.Equal("value of tenant-c", *v)
+
+	// deleted value is created again
+	c.Delete("tenant-a")
+	a, err := c.GetByID(context.Background(), "tenant-a")
+	asst.NoError(err)
+	asst.NotSame(values[0], a)
+
+	// no tenant ID
+	ctx, cancel := tenantContext("")
+	defer cancel()
+	v, err = c.Get(ctx)
+	asst.ErrorIs(err, runtime.ErrNoTenantID)
+	asst.Nil(v)
+}
+
+func Test_TenantCache_panic(t *testing.T) {
+	asst := assert.New(t)
+
+	calls := 0
+	c := runtime.NewTenantCache(func(ctx context.Context, tenantID string) (*string, error) {
+		calls++
+		if calls == 1 {
+			panic("test-panic")
+		}
+		v := "value of " + tenantID
+		return &v, nil
+	})
+
+	asst.PanicsWithValue("test-panic", func() { c.GetByID(context.Background(), "tenant-a") })
+
+	// the value is created again after the panic
+	v, err := c.GetByID(context.Background(), "tenant-a")
+	asst.NoError(err)
+	asst.Equal("value of tenant-a", *v)
+	asst.Equal(2, calls)
+}
+
+func Test_PerTenant(t *testing.T) {
+	asst := assert.New(t)
+	f := runtimetest.NewServer(t,
+		runtimetest.Event{RequestID: "req-1", Body: `{"name":"alice"}`, Header: map[string]string{"Lambda-Runtime-Aws-Tenant-Id": "tenant-a"}},
+		runtimetest.Event{RequestID: "req-2", Body: `{"name":"bob"}`, Header: map[string]string{"Lambda-Runtime-Aws-Tenant-Id": "tenant-b"}},
+		runtimetest.Event{RequestID: "req-3", Body: `{"name":"carol"}`, Header: map[string]string{"Lambda-Runtime-Aws-Tenant-Id": "tenant-a"}},
+		runtimetest.Event{RequestID: "req-4", Body: `{"name":"dave"}`},
+	)
+
+	created := []string{}
+	handler := runtime.PerTenant(func(ctx context.Context, tenantID string) (func(context.Context, testInput) (string, error), error) {
+		created = append(created, tenantID)
+
+		// state per tenant
+		count := 0
+		return func(ctx context.Context, in testInput) (string, error) {
+			count++
+			return fmt.Sprintf("%s:%s:%d", tenantID, in.Name, count), nil
+		}, nil
+	})
+
+	runtime.Start(handler, runtime.WithClient(f.Client(t)))
+
+	asst.Equal([]string{"tenant-a", "tenant-b"}, created)
+
+	results := f.Results()
+	asst.Len(results, 4)
+	asst.JSONEq(`"tenant-a:alice:1"`, results[0].Body)
+	asst.JSONEq(`"tenant-b:bob:1"`, results[1].Body)
+	asst.JSONEq(`"tenant-a:carol:2"`, results[2].Body)
+	asst.Equal("error", results[3].Kind)
+	asst.Contains(results[3].Body, runtime.ErrNoTenantID.Error())
+}
@@ -0,0 +1,145 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// Environment variable name that the AWS SDKs read the X-Ray tracing header from.
+	traceIDEnvKey string = "_X_AMZN_TRACE_ID"
+
+	traceHeaderKeyRoot    string = "Root"
+	traceHeaderKeyParent  string = "Parent"
+	traceHeaderKeySampled string = "Sampled"
+	traceHeaderKeyLineage string = "Lineage"
+)
+
+// ErrInvalidTraceHeader is returned when the X-Ray tracing header cannot be parsed.
+var ErrInvalidTraceHeader = errors.New("invalid trace header")
+
+// TraceHeader is the parsed X-Ray tracing header.
+// (e.g. Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1)
+//
+// document: https://docs.aws.amazon.com/xray/latest/devguide/xray-concepts.html#xray-concepts-tracingheader
+type TraceHeader struct {
+	// The trace ID.
+	Root string
+
+	// The ID of the parent segment.
+	Parent string
+
+	// The sampling decision. "1" (sampled), "0" (not sampled) or "?" (to be decided).
+	Sampled string
+
+	// The lineage of the request.
+	Lineage string
+
+	// The other fields in the header, that are kept to be serialized again.
+	Others []TraceHeaderField
+}
+
+// TraceHeaderField is a key-value pair of the X-Ray tracing header.
+type TraceHeaderField struct {
+	Key   string
+	Value string
+}
+
+// ParseTraceHeader parses the X-Ray tracing header.
+// The returned error wraps ErrInvalidTraceHeader if s is empty, malformed, or has no Root.
+func ParseTraceHeader(s string) (*TraceHeader, error) {
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidTraceHeader)
+	}
+
+	h := &TraceHeader{}
+	for _, f := range strings.Split(s, ";") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q has no value", ErrInvalidTraceHeader, f)
+		}
+
+		switch k {
+		case traceHeaderKeyRoot:
+			h.Root = v
+		case traceHeaderKeyParent:
+			h.Parent = v
+		case traceHeaderKeySampled:
+			h.Sampled = v
+		case traceHeaderKeyLineage:
+			h.Lineage = v
+		default:
+			h.Others = append(h.Others, TraceHeaderField{Key: k, Value: v})
+		}
+	}
+
+	if h.Root == "" {
+		return nil, fmt.Errorf("%w: Root is empty", ErrInvalidTraceHeader)
+	}
+
+	return h, nil
+}
+
+// String serializes the header in the format of X-Ray tracing header.
+// Empty fields are omitted.
+func (h *TraceHeader) String() string {
+	if h == nil {
+		return ""
+	}
+
+	fs := []string{}
+	add := func(k, v string) {
+		if v != "" {
+			fs = append(fs, k+"="+v)
+		}
+	}
+
+	add(traceHeaderKeyRoot, h.Root)
+	add(traceHeaderKeyParent, h.Parent)
+	add(traceHeaderKeySampled, h.Sampled)
+	add(traceHeaderKeyLineage, h.Lineage)
+	for _, o := range h.Others {
+		add(o.Key, o.Value)
+	}
+
+	return strings.Join(fs, ";")
+}
+
+// IsSampled reports whether the request is sampled.
+func (h *TraceHeader) IsSampled() bool {
+	return h != nil && h.Sampled == "1"
+}
+
+// TraceHeaderFromContext returns the parsed X-Ray tracing header of the invocation
+// stored in ctx by NewInvocationContext.
+// It returns false if there is no invocation or the header of it is invalid.
+func TraceHeaderFromContext(ctx context.Context) (*TraceHeader, bool) {
+	inv, ok := InvocationFromContext(ctx)
+	if !ok || inv.TraceHeader == nil {
+		return nil, false
+	}
+	return inv.TraceHeader, true
+}
+
+// ExportTraceID sets TraceID of next to the environment variable _X_AMZN_TRACE_ID,
+// as the managed runtimes do, so that the AWS SDKs join the same trace.
+// If TraceID is empty, the environment variable is unset.
+func ExportTraceID(next *NextOutput) error {
+	if next == nil {
+		return fmt.Errorf("NextOutput is nil")
+	}
+
+	if next.TraceID == "" {
+		return os.Unsetenv(traceIDEnvKey)
+	}
+
+	return os.Setenv(traceIDEnvKey, next.TraceID)
+}
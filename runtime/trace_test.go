@@ -0,0 +1,232 @@
+package runtime_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/michimani/aws-lambda-api-go/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseTraceHeader(t *testing.T) {
+	cases := []struct {
+		name    string
+		s       string
+		expect  *runtime.TraceHeader
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			s:    "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+			expect: &runtime.TraceHeader{
+				Root:    "1-5759e988-bd862e3fe1be46a994272793",
+				Parent:  "53995c3f42cd8ad8",
+				Sampled: "1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "ok: with lineage",
+			s:    "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0;Lineage=a87bd80c:1|68fd508a:5",
+			expect: &runtime.TraceHeader{
+				Root:    "1-5759e988-bd862e3fe1be46a994272793",
+				Parent:  "53995c3f42cd8ad8",
+				Sampled: "0",
+				Lineage: "a87bd80c:1|68fd508a:5",
+			},
+			wantErr: false,
+		},
+		{
+			name: "ok: only root with unknown field and spaces",
+			s:    "Root=1-5759e988-bd862e3fe1be46a994272793; Self=1-5759e988-bd862e3fe1be46a994272794;",
+			expect: &runtime.TraceHeader{
+				Root: "1-5759e988-bd862e3fe1be46a994272793",
+				Others: []runtime.TraceHeaderField{
+					{Key: "Self", Value: "1-5759e988-bd862e3fe1be46a994272794"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "ng: empty",
+			s:       "",
+			wantErr: true,
+		},
+		{
+			name:    "ng: field has no value",
+			s:       "Root=1-5759e988-bd862e3fe1be46a994272793;Parent",
+			wantErr: true,
+		},
+		{
+			name:    "ng: no root",
+			s:       "Parent=53995c3f42cd8ad8;Sampled=1",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			h, err := runtime.ParseTraceHeader(c.s)
+			if c.wantErr {
+				asst.ErrorIs(err, runtime.ErrInvalidTraceHeader)
+				asst.Nil(h)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, h)
+		})
+	}
+}
+
+func Test_TraceHeader_String(t *testing.T) {
+	cases := []struct {
+		name   string
+		s      string
+		expect string
+	}{
+		{
+			name:   "ok",
+			s:      "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+			expect: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+		},
+		{
+			name:   "ok: with lineage",
+			s:      "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=?;Lineage=a87bd80c:1|68fd508a:5",
+			expect: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=?;Lineage=a87bd80c:1|68fd508a:5",
+		},
+		{
+			name:   "ok: others and spaces",
+			s:      "Root=1-5759e988-bd862e3fe1be46a994272793; Self=1-5759e988-bd862e3fe1be46a994272794",
+			expect: "Root=1-5759e988-bd862e3fe1be46a994272793;Self=1-5759e988-bd862e3fe1be46a994272794",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			h, err := runtime.ParseTraceHeader(c.s)
+			asst.NoError(err)
+			asst.Equal(c.expect, h.String())
+
+			rt, err := runtime.ParseTraceHeader(h.String())
+			asst.NoError(err)
+			asst.Equal(h, rt)
+		})
+	}
+
+	var nilHeader *runtime.TraceHeader
+	assert.Equal(t, "", nilHeader.String())
+}
+
+func Test_TraceHeader_IsSampled(t *testing.T) {
+	cases := []struct {
+		name   string
+		h      *runtime.TraceHeader
+		expect bool
+	}{
+		{name: "ok: sampled", h: &runtime.TraceHeader{Sampled: "1"}, expect: true},
+		{name: "ok: not sampled", h: &runtime.TraceHeader{Sampled: "0"}, expect: false},
+		{name: "ok: not decided", h: &runtime.TraceHeader{Sampled: "?"}, expect: false},
+		{name: "ok: nil", h: nil, expect: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			assert.Equal(tt, c.expect, c.h.IsSampled())
+		})
+	}
+}
+
+func Test_TraceHeaderFromContext(t *testing.T) {
+	cases := []struct {
+		name     string
+		ctx      func() (context.Context, context.CancelFunc)
+		expect   *runtime.TraceHeader
+		expectOK bool
+	}{
+		{
+			name: "ok",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return runtime.NewInvocationContext(context.Background(), &runtime.NextOutput{TraceID: "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1"})
+			},
+			expect:   &runtime.TraceHeader{Root: "1-5759e988-bd862e3fe1be46a994272793", Sampled: "1"},
+			expectOK: true,
+		},
+		{
+			name: "ok: invalid trace id",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return runtime.NewInvocationContext(context.Background(), &runtime.NextOutput{TraceID: "lambda-runtime-trace-id"})
+			},
+			expectOK: false,
+		},
+		{
+			name: "ok: no invocation",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+			expectOK: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			ctx, cancel := c.ctx()
+			defer cancel()
+
+			h, ok := runtime.TraceHeaderFromContext(ctx)
+			asst.Equal(c.expectOK, ok)
+			asst.Equal(c.expect, h)
+		})
+	}
+}
+
+func Test_ExportTraceID(t *testing.T) {
+	cases := []struct {
+		name      string
+		next      *runtime.NextOutput
+		expect    string
+		expectSet bool
+		wantErr   bool
+	}{
+		{
+			name:      "ok",
+			next:      &runtime.NextOutput{TraceID: "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1"},
+			expect:    "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1",
+			expectSet: true,
+		},
+		{
+			name:      "ok: empty trace id",
+			next:      &runtime.NextOutput{},
+			expectSet: false,
+		},
+		{
+			name:    "ng: NextOutput is nil",
+			next:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			tt.Setenv("_X_AMZN_TRACE_ID", "previous-trace-id")
+
+			err := runtime.ExportTraceID(c.next)
+			if c.wantErr {
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			v, ok := os.LookupEnv("_X_AMZN_TRACE_ID")
+			asst.Equal(c.expectSet, ok)
+			asst.Equal(c.expect, v)
+		})
+	}
+}
@@ -3,7 +3,11 @@ package runtime
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"reflect"
+	"strconv"
+	"time"
 )
 
 type NextOutput struct {
@@ -28,6 +32,9 @@ type NextOutput struct {
 	// The ARN requested. This can be different in each invoke that	executes the same version.
 	InvokedFunctionArn string
 
+	// The tenant ID of the invocation. Filled only for the functions with tenant isolation mode.
+	TenantID string
+
 	// The bytes of EventResponse.
 	RawEventResponse []byte
 
@@ -49,6 +56,121 @@ func (o *NextOutput) UnmarshalEventResponse(target any) error {
 	return nil
 }
 
+// NextStreamOutput is the output of InvocationNextStream.
+// The headers are the same as NextOutput, but RawEventResponse is always nil
+// and the event is read from Body instead.
+// NextOutput can be passed to NewInvocationContext as it is.
+type NextStreamOutput struct {
+	NextOutput
+
+	// The body of EventResponse. It is nil if Error is not nil.
+	Body io.ReadCloser
+}
+
+// Decode decodes the EventResponse from Body into the structure received as an argument
+// with a streaming JSON decoder, and drains and closes Body so that the connection is reused.
+func (o *NextStreamOutput) Decode(target any) error {
+	if o == nil {
+		return errors.New("Receiver is nil.")
+	}
+	if o.Body == nil {
+		return errors.New("Body is nil.")
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, o.Body)
+		o.Body.Close()
+	}()
+
+	if err := json.NewDecoder(o.Body).Decode(target); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Deadline returns the function execution deadline parsed from DeadlineMs.
+func (o *NextOutput) Deadline() (time.Time, error) {
+	if o == nil {
+		return time.Time{}, errors.New("Receiver is nil.")
+	}
+
+	ms, err := strconv.ParseInt(o.DeadlineMs, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid DeadlineMs: %w", err)
+	}
+
+	return time.UnixMilli(ms), nil
+}
+
+var (
+	// ErrInvalidClientContext is returned when Lambda-Runtime-Client-Context header is not a valid JSON.
+	ErrInvalidClientContext = errors.New("invalid client context")
+
+	// ErrInvalidCognitoIdentity is returned when Lambda-Runtime-Cognito-Identity header is not a valid JSON.
+	ErrInvalidCognitoIdentity = errors.New("invalid cognito identity")
+)
+
+// ClientContext is the information about the client application and device
+// when the function is invoked through the AWS Mobile SDK.
+type ClientContext struct {
+	Client ClientApplication `json:"client"`
+	Custom map[string]string `json:"custom"`
+	Env    map[string]string `json:"env"`
+}
+
+// ClientApplication is the client application in ClientContext.
+type ClientApplication struct {
+	InstallationID string `json:"installation_id"`
+	AppTitle       string `json:"app_title"`
+	AppVersionCode string `json:"app_version_code"`
+	AppPackageName string `json:"app_package_name"`
+}
+
+// CognitoIdentity is the information about the Amazon Cognito identity provider
+// when the function is invoked through the AWS Mobile SDK.
+type CognitoIdentity struct {
+	CognitoIdentityID     string `json:"cognitoIdentityId"`
+	CognitoIdentityPoolID string `json:"cognitoIdentityPoolId"`
+}
+
+// DecodeClientContext decodes ClientContext.
+// If ClientContext is empty, it returns nil without error.
+// If ClientContext is not a valid JSON, the returned error wraps ErrInvalidClientContext.
+func (o *NextOutput) DecodeClientContext() (*ClientContext, error) {
+	if o == nil {
+		return nil, errors.New("Receiver is nil.")
+	}
+	if o.ClientContext == "" {
+		return nil, nil
+	}
+
+	var cc ClientContext
+	if err := json.Unmarshal([]byte(o.ClientContext), &cc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClientContext, err)
+	}
+
+	return &cc, nil
+}
+
+// DecodeCognitoIdentity decodes CognitoIdentity.
+// If CognitoIdentity is empty, it returns nil without error.
+// If CognitoIdentity is not a valid JSON, the returned error wraps ErrInvalidCognitoIdentity.
+func (o *NextOutput) DecodeCognitoIdentity() (*CognitoIdentity, error) {
+	if o == nil {
+		return nil, errors.New("Receiver is nil.")
+	}
+	if o.CognitoIdentity == "" {
+		return nil, nil
+	}
+
+	var ci CognitoIdentity
+	if err := json.Unmarshal([]byte(o.CognitoIdentity), &ci); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCognitoIdentity, err)
+	}
+
+	return &ci, nil
+}
+
 // ResponseInput is the struct for parameter of
 // POST /runtime/invocation/{AwsRequestId}/response API.
 type ResponseInput struct {
@@ -57,6 +179,9 @@ type ResponseInput struct {
 
 	// Any data that will be returned after the function has run to completion.
 	Response io.Reader
+
+	// If true, the response payload that exceeds the limit is reported by InvocationError.
+	ReportPayloadTooLarge bool
 }
 
 // ResponseOutput is the struct for response of
@@ -72,6 +197,172 @@ type ResponseOutput struct {
 	Error *ErrorResponse `json:"-"`
 }
 
+// ResponseStreamInput is the struct for parameter of
+// POST /runtime/invocation/{AwsRequestId}/response API with streaming response mode.
+type ResponseStreamInput struct {
+	// AWS request ID associated with the request.
+	AWSRequestID string
+
+	// Content-Type of the response. If empty, application/octet-stream is used.
+	ContentType string
+
+	// If true, the response payload that exceeds the limit is reported
+	// by the error trailers when the stream is closed.
+	ReportPayloadTooLarge bool
+}
+
+// FunctionError is the error document that the runtime reports to Lambda
+// when the function fails.
+type FunctionError struct {
+	// Description of the error.
+	ErrorMessage string `json:"errorMessage"`
+
+	// Type of the error. (e.g. Runtime.UnknownReason)
+	// It is also sent as the value of Lambda-Runtime-Function-Error-Type header.
+	ErrorType string `json:"errorType"`
+
+	// Stack trace of the error.
+	StackTrace []string `json:"stackTrace"`
+}
+
+// NewFunctionError converts err into FunctionError.
+// If err is (or wraps) *FunctionError, it is returned as it is.
+// If err is (or wraps) an error that has FunctionError method, such as *PayloadTooLargeError and *PanicError,
+// the result of the method is returned.
+// Otherwise the type name of err is used as ErrorType. (e.g. *errors.errorString -> errorString)
+func NewFunctionError(err error) *FunctionError {
+	if err == nil {
+		return nil
+	}
+
+	var fe *FunctionError
+	if errors.As(err, &fe) {
+		return fe
+	}
+
+	var fc interface{ FunctionError() *FunctionError }
+	if errors.As(err, &fc) {
+		return fc.FunctionError()
+	}
+
+	return &FunctionError{
+		ErrorMessage: err.Error(),
+		ErrorType:    errorTypeName(err),
+	}
+}
+
+func errorTypeName(v any) string {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		return t.Elem().Name()
+	}
+	return t.Name()
+}
+
+// Error returns ErrorMessage, so that FunctionError can be returned as an error.
+func (e *FunctionError) Error() string {
+	if e == nil {
+		return ""
+	}
+	return e.ErrorMessage
+}
+
+// XRayErrorCause is the error cause that will be recorded to the X-Ray segment
+// via Lambda-Runtime-Function-XRay-Error-Cause header.
+type XRayErrorCause struct {
+	WorkingDirectory string               `json:"working_directory"`
+	Exceptions       []XRayErrorException `json:"exceptions"`
+	Paths            []string             `json:"paths"`
+}
+
+type XRayErrorException struct {
+	Type    string               `json:"type"`
+	Message string               `json:"message"`
+	Stack   []XRayErrorStackItem `json:"stack"`
+}
+
+type XRayErrorStackItem struct {
+	Path  string `json:"path"`
+	Line  int    `json:"line"`
+	Label string `json:"label"`
+}
+
+// InvocationErrorInput is the struct for parameter of
+// POST /runtime/invocation/{AwsRequestId}/error API.
+type InvocationErrorInput struct {
+	// AWS request ID associated with the request.
+	AWSRequestID string
+
+	// The error that occurred in the function.
+	Error *FunctionError
+
+	// The error cause for X-Ray. (Optional)
+	XRayErrorCause *XRayErrorCause
+}
+
+// InvocationErrorOutput is the struct for response of
+// POST /runtime/invocation/{AwsRequestId}/error API.
+type InvocationErrorOutput struct {
+	// http status code
+	StatusCode int `json:"-"`
+
+	// status
+	Status string `json:"status"`
+
+	// The error response
+	Error *ErrorResponse `json:"-"`
+}
+
+// InitErrorInput is the struct for parameter of
+// POST /runtime/init/error API.
+type InitErrorInput struct {
+	// The error that occurred while initializing the function.
+	Error *FunctionError
+}
+
+// InitErrorOutput is the struct for response of
+// POST /runtime/init/error API.
+type InitErrorOutput struct {
+	// http status code
+	StatusCode int `json:"-"`
+
+	// status
+	Status string `json:"status"`
+
+	// The error response
+	Error *ErrorResponse `json:"-"`
+}
+
+// RestoreNextOutput is the struct for response of
+// GET /runtime/restore/next API.
+type RestoreNextOutput struct {
+	// http status code
+	StatusCode int
+
+	// The error response
+	Error *ErrorResponse
+}
+
+// RestoreErrorInput is the struct for parameter of
+// POST /runtime/restore/error API.
+type RestoreErrorInput struct {
+	// The error that occurred while running the after-restore hooks.
+	Error *FunctionError
+}
+
+// RestoreErrorOutput is the struct for response of
+// POST /runtime/restore/error API.
+type RestoreErrorOutput struct {
+	// http status code
+	StatusCode int `json:"-"`
+
+	// status
+	Status string `json:"status"`
+
+	// The error response
+	Error *ErrorResponse `json:"-"`
+}
+
 type ErrorResponse struct {
 	ErrorMessage string `json:"errorMessage"`
 	ErrorType    string `json:"errorType"`
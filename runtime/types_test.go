@@ -1,7 +1,11 @@
 package runtime_test
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/michimani/aws-lambda-api-go/runtime"
 	"github.com/stretchr/testify/assert"
@@ -75,3 +79,300 @@ func Test_UnmarshalEventResponse(t *testing.T) {
 		})
 	}
 }
+
+type testCustomError struct{}
+
+func (e testCustomError) Error() string { return "test-custom-error" }
+
+// trackingBody records whether it is closed after read to the end.
+type trackingBody struct {
+	*strings.Reader
+	drained bool
+}
+
+func (b *trackingBody) Close() error {
+	b.drained = b.Len() == 0
+	return nil
+}
+
+func Test_NextStreamOutput_Decode(t *testing.T) {
+	type event struct {
+		Name string `json:"name"`
+	}
+
+	cases := []struct {
+		name    string
+		out     *runtime.NextStreamOutput
+		expect  event
+		wantErr bool
+	}{
+		{
+			name:    "ok",
+			out:     &runtime.NextStreamOutput{Body: &trackingBody{Reader: strings.NewReader(`{"name":"test-name"}`)}},
+			expect:  event{Name: "test-name"},
+			wantErr: false,
+		},
+		{
+			name:    "ok: trailing data is drained",
+			out:     &runtime.NextStreamOutput{Body: &trackingBody{Reader: strings.NewReader("{\"name\":\"test-name\"}\n  \n")}},
+			expect:  event{Name: "test-name"},
+			wantErr: false,
+		},
+		{
+			name:    "ng: invalid JSON",
+			out:     &runtime.NextStreamOutput{Body: &trackingBody{Reader: strings.NewReader(`///`)}},
+			wantErr: true,
+		},
+		{
+			name:    "ng: Body is nil",
+			out:     &runtime.NextStreamOutput{},
+			wantErr: true,
+		},
+		{
+			name:    "ng: receiver is nil",
+			out:     nil,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			e := event{}
+			err := c.out.Decode(&e)
+			if c.out != nil && c.out.Body != nil {
+				asst.True(c.out.Body.(*trackingBody).drained)
+			}
+			if c.wantErr {
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, e)
+		})
+	}
+}
+
+func Test_NewFunctionError(t *testing.T) {
+	fe := &runtime.FunctionError{ErrorMessage: "test-message", ErrorType: "Test.Type"}
+
+	cases := []struct {
+		name   string
+		err    error
+		expect *runtime.FunctionError
+	}{
+		{
+			name:   "ok: pointer error",
+			err:    errors.New("test-message"),
+			expect: &runtime.FunctionError{ErrorMessage: "test-message", ErrorType: "errorString"},
+		},
+		{
+			name:   "ok: value error",
+			err:    testCustomError{},
+			expect: &runtime.FunctionError{ErrorMessage: "test-custom-error", ErrorType: "testCustomError"},
+		},
+		{
+			name:   "ok: FunctionError",
+			err:    fe,
+			expect: fe,
+		},
+		{
+			name:   "ok: wrapped FunctionError",
+			err:    fmt.Errorf("wrapped: %w", fe),
+			expect: fe,
+		},
+		{
+			name: "ok: error that has FunctionError method",
+			err:  fmt.Errorf("wrapped: %w", &runtime.PayloadTooLargeError{Size: 10, Limit: 5}),
+			expect: &runtime.FunctionError{
+				ErrorMessage: "response payload size exceeded maximum allowed payload size (10 bytes > 5 bytes)",
+				ErrorType:    "Function.ResponseSizeTooLarge",
+			},
+		},
+		{
+			name:   "ok: nil",
+			err:    nil,
+			expect: nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out := runtime.NewFunctionError(c.err)
+			asst.Equal(c.expect, out)
+		})
+	}
+}
+
+func Test_Deadline(t *testing.T) {
+	cases := []struct {
+		name    string
+		o       *runtime.NextOutput
+		expect  time.Time
+		wantErr bool
+	}{
+		{
+			name:    "ok",
+			o:       &runtime.NextOutput{DeadlineMs: "1700000000123"},
+			expect:  time.UnixMilli(1700000000123),
+			wantErr: false,
+		},
+		{
+			name:    "ng: invalid value",
+			o:       &runtime.NextOutput{DeadlineMs: "lambda-runtime-deadline-ms"},
+			wantErr: true,
+		},
+		{
+			name:    "ng: empty",
+			o:       &runtime.NextOutput{},
+			wantErr: true,
+		},
+		{
+			name:    "ng: receiver is nil",
+			o:       nil,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			d, err := c.o.Deadline()
+			if c.wantErr {
+				asst.Error(err)
+				asst.True(d.IsZero())
+				return
+			}
+
+			asst.NoError(err)
+			asst.True(c.expect.Equal(d))
+		})
+	}
+}
+
+func Test_DecodeClientContext(t *testing.T) {
+	cases := []struct {
+		name      string
+		o         *runtime.NextOutput
+		expect    *runtime.ClientContext
+		expectErr error
+		wantErr   bool
+	}{
+		{
+			name: "ok",
+			o: &runtime.NextOutput{
+				ClientContext: `{"client":{"installation_id":"test-installation-id","app_title":"test-app","app_version_code":"1.0.0","app_package_name":"com.example.test"},"custom":{"key":"value"},"env":{"platform":"Android","locale":"ja_JP"}}`,
+			},
+			expect: &runtime.ClientContext{
+				Client: runtime.ClientApplication{
+					InstallationID: "test-installation-id",
+					AppTitle:       "test-app",
+					AppVersionCode: "1.0.0",
+					AppPackageName: "com.example.test",
+				},
+				Custom: map[string]string{"key": "value"},
+				Env:    map[string]string{"platform": "Android", "locale": "ja_JP"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "ok: empty",
+			o:       &runtime.NextOutput{},
+			expect:  nil,
+			wantErr: false,
+		},
+		{
+			name:      "ng: malformed JSON",
+			o:         &runtime.NextOutput{ClientContext: "lambda-runtime-client-context"},
+			expectErr: runtime.ErrInvalidClientContext,
+			wantErr:   true,
+		},
+		{
+			name:    "ng: receiver is nil",
+			o:       nil,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			cc, err := c.o.DecodeClientContext()
+			if c.wantErr {
+				asst.Error(err)
+				if c.expectErr != nil {
+					asst.ErrorIs(err, c.expectErr)
+				}
+				asst.Nil(cc)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, cc)
+		})
+	}
+}
+
+func Test_DecodeCognitoIdentity(t *testing.T) {
+	cases := []struct {
+		name      string
+		o         *runtime.NextOutput
+		expect    *runtime.CognitoIdentity
+		expectErr error
+		wantErr   bool
+	}{
+		{
+			name: "ok",
+			o: &runtime.NextOutput{
+				CognitoIdentity: `{"cognitoIdentityId":"test-identity-id","cognitoIdentityPoolId":"test-pool-id"}`,
+			},
+			expect: &runtime.CognitoIdentity{
+				CognitoIdentityID:     "test-identity-id",
+				CognitoIdentityPoolID: "test-pool-id",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "ok: empty",
+			o:       &runtime.NextOutput{},
+			expect:  nil,
+			wantErr: false,
+		},
+		{
+			name:      "ng: malformed JSON",
+			o:         &runtime.NextOutput{CognitoIdentity: `{"cognitoIdentityId":`},
+			expectErr: runtime.ErrInvalidCognitoIdentity,
+			wantErr:   true,
+		},
+		{
+			name:    "ng: receiver is nil",
+			o:       nil,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			ci, err := c.o.DecodeCognitoIdentity()
+			if c.wantErr {
+				asst.Error(err)
+				if c.expectErr != nil {
+					asst.ErrorIs(err, c.expectErr)
+				}
+				asst.Nil(ci)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, ci)
+		})
+	}
+}
@@ -33,7 +33,7 @@ func Subscribe(ctx context.Context, client alago.AlagoClient, in *SubscribeInput
 	}
 
 	url := fmt.Sprintf(subscribeEndpointFmt, client.Host())
-	sc, h, b, err := internal.CallAPI(context.Background(), client, http.MethodPut, url, reqBody, hs...)
+	sc, h, b, err := internal.CallAPI(ctx, client, http.MethodPut, url, reqBody, hs...)
 	if err != nil {
 		return nil, err
 	}
@@ -173,3 +173,22 @@ func Test_generateEventSubscribeOutput(t *testing.T) {
 		})
 	}
 }
+
+func Test_Subscribe_canceledContext(t *testing.T) {
+	asst := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", "127.0.0.1:1")
+	ac, err := alago.NewClient(&alago.NewClientInput{HttpClient: &http.Client{}})
+	asst.NoError(err)
+
+	out, err := telemetry.Subscribe(ctx, ac, &telemetry.SubscribeInput{
+		LambdaExtensionIdentifier: "test",
+		DestinationProtocol:       telemetry.DestinationProtocolHTTP,
+		DestinationURI:            "http://sandbox.localdomain:8080",
+		TelemetryTypes:            []telemetry.TelemetryType{telemetry.TelemetryTypeFunction},
+	})
+	asst.ErrorIs(err, context.Canceled)
+	asst.Nil(out)
+}